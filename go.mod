@@ -3,7 +3,7 @@ module github.com/dwchenxj/taosql
 go 1.18
 
 require (
-	github.com/gogf/gf/v2 v2.0.0-rc
+	github.com/gogf/gf/v2 v2.0.6
 	github.com/taosdata/driver-go/v2 v2.0.1
 )
 
@@ -26,8 +26,3 @@ require (
 	golang.org/x/text v0.3.8-0.20211105212822-18b340fc7af2 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
-
-replace (
-	github.com/gogf/gf/v2 => D:/software/iems.go/src/github.com/gogf/gf/
-	github.com/taosdata/driver-go/v2 => D:/software/iems.go/src/github.com/taosdata/driver-go
-)
@@ -4,21 +4,36 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/container/gvar"
 	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/errors/gcode"
 	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
 	"github.com/gogf/gf/v2/text/gregex"
 	"github.com/gogf/gf/v2/text/gstr"
 	_ "github.com/taosdata/driver-go/v2/taosSql"
+	"reflect"
+	"time"
 )
 
 // Driver is the driver for taossql database.
 type Driver struct {
 	*gdb.Core
+	option Option
 }
 
+const (
+	// fieldKeyPrimary is the gdb.TableField.Key of the primary timestamp column, the same as mysql.
+	fieldKeyPrimary = "PRI"
+	// fieldExtraTag is the gdb.TableField.Extra of the tag columns.
+	fieldExtraTag = "TAG"
+	// fieldTypeJSON is the gdb.TableField.Type of the JSON tag.
+	fieldTypeJSON = "JSON"
+)
+
 var (
 	// tableFieldsMap caches the table information retrieved from database.
 	tableFieldsMap = gmap.New(true)
@@ -31,34 +46,55 @@ func init() {
 }
 
 // New create and returns a driver that implements gdb.Driver, which supports operations for taossql.
-func New() gdb.Driver {
-	return &Driver{}
+// The optional parameter `option` specifies the driver specific configuration, see Option.
+func New(option ...Option) gdb.Driver {
+	d := &Driver{}
+	if len(option) > 0 {
+		d.option = option[0]
+	}
+	return d
 }
 
 // New creates and returns a database object for postgresql.
 // It implements the interface of gdb.Driver for extra database driver installation.
+// The option of the database is the option set by SetGroupOption for its configuration group if any,
+// or else the option of the driver.
 func (d *Driver) New(core *gdb.Core, node *gdb.ConfigNode) (gdb.DB, error) {
+	option := d.option
+	if v := groupOptions.Get(core.GetGroup()); v != nil {
+		option = v.(Option)
+	}
 	return &Driver{
-		Core: core,
+		Core:   core,
+		option: option,
 	}, nil
 }
 
-// Open creates and returns an underlying sql.DB object for taossql.
+// cacheKey returns the key of `names` of cache `kind` in tableFieldsMap, which is scoped by the configuration
+// group, so that the groups connecting to different clusters never share the cached information.
+func (d *Driver) cacheKey(kind string, names ...string) string {
+	return fmt.Sprintf(`taossql_%s_%s@group:%s`, kind, gstr.Join(names, "_"), d.GetGroup())
+}
+
+// now returns the current time by Option.Clock.
+func (d *Driver) now() time.Time {
+	if d.option.Clock != nil {
+		return d.option.Clock()
+	}
+	return time.Now()
+}
+
+// Open creates and returns an underlying sql.DB object for taossql,
+// by the underlying driver of the connector of Option.Connector.
 func (d *Driver) Open(config *gdb.ConfigNode) (db *sql.DB, err error) {
 	var (
 		source               string
-		underlyingDriverName = "taosSql"
+		underlyingDriverName = d.option.Connector.driverName()
 	)
 	if config.Link != "" {
 		source = config.Link
 	} else {
-		source = fmt.Sprintf(
-			"%s:%s/tcp(%s:%s)/%s",
-			config.User, config.Pass, config.Host, config.Port, config.Name,
-		)
-		if config.Timezone != "" {
-			source = fmt.Sprintf("%s timezone=%s", source, config.Timezone)
-		}
+		source = d.buildSource(config, config.Pass)
 	}
 
 	if db, err = sql.Open(underlyingDriverName, source); err != nil {
@@ -68,75 +104,148 @@ func (d *Driver) Open(config *gdb.ConfigNode) (db *sql.DB, err error) {
 		)
 		return nil, err
 	}
+	if d.option.ConnectRetryCount > 0 {
+		if err = d.pingWithRetry(db); err != nil {
+			_ = db.Close()
+			err = gerror.WrapCodef(
+				gcode.CodeDbOperationError, err,
+				`connect failed for driver "%s" with max %d retries`, underlyingDriverName, d.option.ConnectRetryCount,
+			)
+			return nil, err
+		}
+	}
 	return
 }
 
 // FilteredLink retrieves and returns filtered `linkInfo` that can be using for
-// logging or tracing purpose.
+// logging or tracing purpose, in which the password is masked as `xxx`.
+// The link is built from the discrete fields of the configuration if it's not configured.
 func (d *Driver) FilteredLink() string {
-	linkInfo := d.GetConfig().Link
-	if linkInfo == "" {
-		return ""
+	config := d.GetConfig()
+	if config.Link == "" {
+		if config.Host == "" {
+			return ""
+		}
+		return d.buildSource(config, "xxx")
 	}
 	s, _ := gregex.ReplaceString(
 		`(.+?)\s*password=(.+)\s*host=(.+)`,
 		`$1 password=xxx host=$3`,
-		linkInfo,
+		config.Link,
 	)
+	// The `user:pass@protocol(address)/dbname` data source name.
+	s, _ = gregex.ReplaceString(`^([^:@/]*):.*([@/]\w+\()`, `$1:xxx$2`, s)
 	return s
 }
 
 // GetChars returns the security char for this type of database.
 func (d *Driver) GetChars() (charLeft string, charRight string) {
-	return "\"", "\""
+	return "`", "`"
 }
 
 // DoFilter deals with the sql string before commits it to underlying sql driver.
 func (d *Driver) DoFilter(ctx context.Context, link gdb.Link, sql string, args []interface{}) (newSql string, newArgs []interface{}, err error) {
 	defer func() {
-		newSql, newArgs, err = d.Core.DoFilter(ctx, link, newSql, newArgs)
+		if err == nil {
+			newSql, newArgs, err = d.Core.DoFilter(ctx, link, newSql, newArgs)
+		}
 	}()
-	var index int
-	// Convert placeholder char '?' to string "$x".
-	sql, _ = gregex.ReplaceStringFunc(`\?`, sql, func(s string) string {
-		index++
-		return fmt.Sprintf(`$%d`, index)
-	})
-	sql, _ = gregex.ReplaceStringFuncMatch(`(::jsonb([^\w\d]*)\$\d)`, sql, func(match []string) string {
-		return fmt.Sprintf(`::jsonb%s?`, match[2])
-	})
+	// Splice the TDengine specific clauses bound to the context.
+	if clause := ClauseFromCtx(ctx); clause != nil {
+		if clause.eventStart != "" {
+			if err = d.checkServerVersion(ctx, minVersionForEventWindow, "EVENT_WINDOW"); err != nil {
+				return "", nil, err
+			}
+		}
+		if sql, err = clause.splice(sql); err != nil {
+			return "", nil, err
+		}
+	}
+	// Qualify the table names with current schema.
+	if d.option.QualifyTableNames {
+		sql = d.qualifyTableNames(sql)
+	}
+	// Render the time arguments at the database precision.
+	if args, err = d.convertTimeArgs(ctx, sql, args); err != nil {
+		return "", nil, err
+	}
+	// The pseudo columns quoted by gdb.Model, like `tbname` of Fields("tbname"), would be taken as
+	// the columns of the same names by TDengine.
+	sql = unquotePseudoColumns(sql)
+	// The placeholder char '?' is kept as it is, which is the native placeholder of taosSql.
 	newSql, _ = gregex.ReplaceString(` LIMIT (\d+),\s*(\d+)`, ` LIMIT $2 OFFSET $1`, sql)
 	return newSql, args, nil
 }
 
-// Tables retrieves and returns the tables of current schema.
+// DoCommit commits current sql and arguments to underlying sql driver.
+// It logs the statement if it takes longer than Option.SlowQueryThreshold,
+// and skips the statement if it is in dry run mode, see WithDryRun.
+// The returned error retains the original TDengine error code, see TaosCode, and it returns promptly
+// with error "query timed out" once the deadline of `ctx` is exceeded.
+// The window pseudo columns _wstart and _wend of the query records that are read as epoch integers are
+// converted to time values at the database precision, and the pseudo column tbname is read as string.
+// The values of the other columns are converted to the Go types of the columns if Option.ConvertLocalTypes
+// is enabled, see convertLocalTypes.
+func (d *Driver) DoCommit(ctx context.Context, in gdb.DoCommitInput) (out gdb.DoCommitOutput, err error) {
+	if err = dryRunUnavailable(ctx, in); err != nil {
+		return
+	}
+	if d.dryRun(ctx, in) {
+		if in.Type == gdb.SqlTypeExecContext || in.Type == gdb.SqlTypeStmtExecContext {
+			out.Result = new(gdb.SqlResult)
+		}
+		return
+	}
+	start := d.now()
+	out, err = d.commitWithDeadline(ctx, in)
+	d.logSlowQuery(ctx, in, d.now().Sub(start))
+	if err = wrapTaosError(err); err == nil && len(out.Records) > 0 {
+		metadataCtx := withoutDryRun(withoutClause(ctx))
+		if err = d.convertWindowColumns(metadataCtx, out.Records); err == nil && d.option.ConvertLocalTypes {
+			err = d.convertLocalTypes(metadataCtx, in.Sql, out.Records)
+		}
+	}
+	return
+}
+
+// Tables retrieves and returns the tables of current schema, including the super tables,
+// by `SHOW STABLES` and `SHOW TABLES`, in which the child tables are included.
 // It's mainly used in cli tool chain for automatically generating the models.
+//
+// The optional second parameter is the LIKE pattern of the table names, which filters the tables on the server,
+// like: Tables(ctx, "power", "sensor_%") for `SHOW power.TABLES LIKE 'sensor_%'`.
 func (d *Driver) Tables(ctx context.Context, schema ...string) (tables []string, err error) {
 	var result gdb.Result
 	link, err := d.SlaveLink(schema...)
 	if err != nil {
 		return nil, err
 	}
-	query := ""
+	prefix, like := "", ""
 	if len(schema) > 0 && schema[0] != "" {
-		query = fmt.Sprintf(
-			"desc %s",
-			schema[0],
-		)
+		prefix = d.QuoteWord(schema[0]) + "."
 	}
-	result, err = d.DoSelect(ctx, link, query)
-	if err != nil {
-		return
+	if len(schema) > 1 && schema[1] != "" {
+		like = ` LIKE ` + quoteString(schema[1])
 	}
-	for _, m := range result {
-		for _, v := range m {
-			tables = append(tables, v.String())
+	ctx = withoutDryRun(withoutClause(ctx))
+	for _, item := range []struct{ query, column string }{
+		{fmt.Sprintf(`SHOW %sSTABLES%s`, prefix, like), "stable_name"},
+		{fmt.Sprintf(`SHOW %sTABLES%s`, prefix, like), "table_name"},
+	} {
+		if result, err = d.DoSelect(ctx, link, item.query); err != nil {
+			return nil, err
+		}
+		for _, m := range result {
+			tables = append(tables, m[item.column].String())
 		}
 	}
 	return
 }
 
 // TableFields retrieves and returns the fields' information of specified table of current schema.
+// The Type of the variable-width types contains the declared length, like: NCHAR(64),
+// the Key of the primary timestamp is "PRI", and the Extra of the tags of super tables is "TAG".
+// The JSON tag is of Type "JSON", which is checked by IsJSONTag.
 //
 // Also see DriverMysql.TableFields.
 func (d *Driver) TableFields(ctx context.Context, table string, schema ...string) (fields map[string]*gdb.TableField, err error) {
@@ -148,13 +257,13 @@ func (d *Driver) TableFields(ctx context.Context, table string, schema ...string
 			"function TableFields supports only single table operations",
 		)
 	}
-	table, _ = gregex.ReplaceString("\"", "", table)
+	table, _ = gregex.ReplaceString("`", "", table)
 	useSchema := d.GetSchema()
 	if len(schema) > 0 && schema[0] != "" {
 		useSchema = schema[0]
 	}
 	v := tableFieldsMap.GetOrSetFuncLock(
-		fmt.Sprintf(`taossql_table_fields_%s_%s@group:%s`, table, useSchema, d.GetGroup()),
+		d.tableFieldsKey(table, useSchema),
 		func() interface{} {
 			var (
 				result       gdb.Result
@@ -165,17 +274,36 @@ func (d *Driver) TableFields(ctx context.Context, table string, schema ...string
 				return nil
 			}
 			structureSql, _ = gregex.ReplaceString(`[\n\r\s]+`, " ", gstr.Trim(structureSql))
-			result, err = d.DoSelect(ctx, link, structureSql)
+			result, err = d.DoSelect(withoutDryRun(withoutClause(ctx)), link, structureSql)
 			if err != nil {
 				return nil
 			}
 			fields = make(map[string]*gdb.TableField)
 			for i, m := range result {
-				fields[m["field"].String()] = &gdb.TableField{
+				field := &gdb.TableField{
 					Index: i,
-					Name:  m["field"].String(),
-					Type:  m["type"].String(),
+					Name:  recordValue(m, "field").String(),
+					Type:  recordValue(m, "type").String(),
+				}
+				if gstr.Equal(field.Type, fieldTypeJSON) {
+					field.Type = fieldTypeJSON
+				}
+				// The variable-width types are declared with length, like: NCHAR(64).
+				switch columnTypeName(field.Type) {
+				case "binary", "varchar", "nchar":
+					if length := recordValue(m, "length").Int(); length > 0 && !gstr.Contains(field.Type, "(") {
+						field.Type = fmt.Sprintf(`%s(%d)`, field.Type, length)
+					}
 				}
+				// The first column is always the primary timestamp.
+				if i == 0 {
+					field.Key = fieldKeyPrimary
+				}
+				// The tags of super tables are noted as TAG.
+				if gstr.Equal(recordValue(m, "note").String(), fieldExtraTag) {
+					field.Extra = fieldExtraTag
+				}
+				fields[field.Name] = field
 			}
 			return fields
 		},
@@ -186,7 +314,59 @@ func (d *Driver) TableFields(ctx context.Context, table string, schema ...string
 	return
 }
 
-// DoInsert is not supported in taossql.
+// tableFieldsKey returns the key of the fields of `table` of `schema` in tableFieldsMap,
+// in which `schema` is the current schema if it's empty.
+func (d *Driver) tableFieldsKey(table string, schema string) string {
+	return d.tableKey("table_fields", table, schema)
+}
+
+// tableKey returns the key of the information `kind` of `table` of `schema` in tableFieldsMap, like the fields
+// and the type of the table, in which the quote chars of `table` are removed, so that the quoted and unquoted
+// names share the same key, and `schema` is the current schema if it's empty.
+func (d *Driver) tableKey(kind string, table string, schema string) string {
+	charL, charR := d.GetChars()
+	table, _ = gregex.ReplaceString("`", "", gstr.Trim(table, charL+charR))
+	if schema == "" {
+		schema = d.GetSchema()
+	}
+	return d.cacheKey(kind, table, schema)
+}
+
+// ClearTableFields removes the cached fields and type of `table` of current schema, or of the optional `schema`,
+// which are retrieved again by the next TableFields and TableType, like after the table is altered.
+func (d *Driver) ClearTableFields(table string, schema ...string) {
+	useSchema := d.GetSchema()
+	if len(schema) > 0 && schema[0] != "" {
+		useSchema = schema[0]
+	}
+	tableFieldsMap.Remove(d.tableFieldsKey(table, useSchema))
+	tableFieldsMap.Remove(d.tableKey("table_type", table, useSchema))
+}
+
+// ClearAllTableFields removes all the cached information of all configuration groups, like the table fields,
+// the table types, the database precisions and the server versions, which are retrieved again on demand.
+func (d *Driver) ClearAllTableFields() {
+	tableFieldsMap.Clear()
+}
+
+// recordValue returns the value of column `name` of `record` case-insensitively, as the columns of `desc` are
+// capitalized by the servers of version 2.x, like: Field, Type, and are lower case by the later servers.
+func recordValue(record gdb.Record, name string) *gvar.Var {
+	if v, ok := record[name]; ok {
+		return v
+	}
+	for k, v := range record {
+		if gstr.Equal(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// DoInsert inserts data for given table, in which Save and Replace operations are not supported in taossql.
+// The record keys are validated against the table fields if Option.ValidateSchema is enabled, see Insert.
+// It does nothing and returns a result of zero affected rows if `list` is empty. The RowsAffected of the returned
+// result is the total rows of all batches, and its LastInsertId returns an error of code gcode.CodeNotSupported.
 func (d *Driver) DoInsert(ctx context.Context, link gdb.Link, table string, list gdb.List, option gdb.DoInsertOption) (result sql.Result, err error) {
 	switch option.InsertOption {
 	case gdb.InsertOptionSave:
@@ -202,23 +382,103 @@ func (d *Driver) DoInsert(ctx context.Context, link gdb.Link, table string, list
 		)
 
 	default:
-		return d.Core.DoInsert(ctx, link, table, list, option)
+		if len(list) == 0 {
+			return &insertResult{Result: new(gdb.SqlResult)}, nil
+		}
+		if d.option.ValidateSchema {
+			if err = d.checkRecordColumns(ctx, table, list); err != nil {
+				return nil, err
+			}
+		}
+		if result, err = d.Core.DoInsert(ctx, link, table, list, option); err != nil {
+			return result, err
+		}
+		return &insertResult{Result: result}, nil
 	}
 }
 
 // ConvertDataForRecord converting for any data that will be inserted into table/collection as a record.
+// The attributes of embedded structs are mapped to columns by their own tagged names, without nested prefix.
+// The time values are converted to epoch integers at the precision of current schema, or to RFC3339 strings
+// with nanoseconds if the precision is unknown, and the zero time values are converted to NULL.
+// The maps and slices, except bytes, are converted to JSON strings, which are the values of JSON tags.
+// It returns nil if any value fails to convert, see convertDataForRecord for the error.
 func (d *Driver) ConvertDataForRecord(ctx context.Context, value interface{}) map[string]interface{} {
+	data, _ := d.convertDataForRecord(ctx, value)
+	return data
+}
+
+// convertDataForRecord converts `value` to record like ConvertDataForRecord, but returns an error of code
+// gcode.CodeInvalidParameter if any value fails to convert, like a driver.Valuer returning an error.
+func (d *Driver) convertDataForRecord(ctx context.Context, value interface{}) (map[string]interface{}, error) {
 	data := gdb.DataToMapDeep(value)
-	var err error
+	flattenEmbedded(data, value)
+	var (
+		err       error
+		precision *string
+	)
 	for k, v := range data {
 		if valuer, ok := v.(driver.Valuer); ok {
-			data[k], err = valuer.Value()
-			if err != nil {
-				return nil
+			// The value is assigned only if it succeeds, as the map of `value` may be converted in place.
+			var value driver.Value
+			if value, err = valuer.Value(); err != nil {
+				return nil, gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid value of column "%s"`, k)
+			}
+			data[k] = value
+		} else if isJSONValue(v) {
+			// The maps and slices are the values of JSON tags, which are inserted as JSON string literals.
+			var content []byte
+			if content, err = json.Marshal(v); err != nil {
+				return nil, gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid JSON value of column "%s"`, k)
 			}
+			data[k] = string(content)
 		} else {
 			data[k] = d.Core.ConvertDataForRecordValue(ctx, v)
 		}
+		if t, ok := recordTime(data[k]); ok {
+			if t.IsZero() {
+				data[k] = nil
+				continue
+			}
+			if precision == nil {
+				precision = new(string)
+				if schema := d.GetSchema(); schema != "" {
+					*precision, _ = d.precision(withoutDryRun(withoutClause(ctx)), schema)
+				}
+			}
+			data[k] = timeValue(t, *precision)
+		}
+	}
+	return data, nil
+}
+
+// isJSONValue checks whether record value `value` is a map or slice except bytes, which is encoded as JSON.
+func isJSONValue(value interface{}) bool {
+	if _, ok := value.([]byte); ok {
+		return false
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordTime returns the time of record value `value` if it's a time value,
+// in which the nil pointers are returned as zero time.
+func recordTime(value interface{}) (t time.Time, ok bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			t = *v
+		}
+		return t, true
+	case gtime.Time:
+		return v.Time, true
+	default:
+		return t, false
 	}
-	return data
 }
@@ -0,0 +1,50 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gregex"
+)
+
+// DropDatabase drops database `name` along with all its data. It requires Option.AdminMode.
+func (d *Driver) DropDatabase(ctx context.Context, name string) (sql.Result, error) {
+	if err := d.checkAdmin(`DROP DATABASE`, name); err != nil {
+		return nil, err
+	}
+	return d.Exec(withoutClause(ctx), fmt.Sprintf(`DROP DATABASE %s`, d.QuoteWord(name)))
+}
+
+// DropStable drops super table `name` along with all its child tables. It requires Option.AdminMode.
+func (d *Driver) DropStable(ctx context.Context, name string) (sql.Result, error) {
+	if err := d.checkAdmin(`DROP STABLE`, name); err != nil {
+		return nil, err
+	}
+	return d.Exec(withoutClause(ctx), fmt.Sprintf(`DROP STABLE %s`, d.QuotePrefixTableName(name)))
+}
+
+// DropTable drops normal or child table `name`. It requires Option.AdminMode.
+func (d *Driver) DropTable(ctx context.Context, name string) (sql.Result, error) {
+	if err := d.checkAdmin(`DROP TABLE`, name); err != nil {
+		return nil, err
+	}
+	return d.Exec(withoutClause(ctx), fmt.Sprintf(`DROP TABLE %s`, d.QuotePrefixTableName(name)))
+}
+
+// checkAdmin checks whether the admin mode is enabled for destructive `operation` on object `name`,
+// and whether `name` is a valid object name, which is optionally prefixed with database name, like: db.meters.
+func (d *Driver) checkAdmin(operation string, name string) error {
+	if !d.option.AdminMode {
+		return gerror.NewCodef(
+			gcode.CodeNotAuthorized,
+			`%s is not allowed as admin mode is disabled for taossql driver`, operation,
+		)
+	}
+	if !gregex.IsMatchString(`^\w+(\.\w+)?$`, name) {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid name "%s" for %s`, name, operation)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+func TestAdminMode(t *testing.T) {
+	operations := []struct {
+		name string
+		call func(d *Driver, name string) (sql.Result, error)
+		arg  string
+		want string
+	}{
+		{
+			name: "drop database",
+			call: func(d *Driver, name string) (sql.Result, error) { return d.DropDatabase(context.Background(), name) },
+			arg:  "power",
+			want: "DROP DATABASE `power`",
+		},
+		{
+			name: "drop stable",
+			call: func(d *Driver, name string) (sql.Result, error) { return d.DropStable(context.Background(), name) },
+			arg:  "meters",
+			want: "DROP STABLE `meters`",
+		},
+		{
+			name: "drop table",
+			call: func(d *Driver, name string) (sql.Result, error) { return d.DropTable(context.Background(), name) },
+			arg:  "power.d1001",
+			want: "DROP TABLE `power`.`d1001`",
+		},
+	}
+	cases := []struct {
+		name      string
+		adminMode bool
+		arg       string
+		code      gcode.Code
+	}{
+		{name: "disabled", adminMode: false, code: gcode.CodeNotAuthorized},
+		{name: "enabled", adminMode: true},
+		{name: "invalid name", adminMode: true, arg: "meters; DROP DATABASE power", code: gcode.CodeInvalidParameter},
+	}
+	for _, operation := range operations {
+		for _, c := range cases {
+			t.Run(operation.name+"/"+c.name, func(t *testing.T) {
+				d, server := newMockDriver(t, Option{AdminMode: c.adminMode}, nil)
+				arg := c.arg
+				if arg == "" {
+					arg = operation.arg
+				}
+				_, err := operation.call(d, arg)
+				checkCode(t, err, c.code)
+				sqls := server.Sqls()
+				if c.code != nil {
+					if len(sqls) > 0 {
+						t.Fatalf("unexpected statements %q", sqls)
+					}
+					return
+				}
+				if len(sqls) != 1 || sqls[0] != operation.want {
+					t.Fatalf("got statements %q, want %q", sqls, operation.want)
+				}
+			})
+		}
+	}
+}
+
+func TestSetKeepAdminMode(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, nil)
+	checkCode(t, d.SetKeep(context.Background(), "power", "365d"), gcode.CodeNotAuthorized)
+	if sqls := server.Sqls(); len(sqls) > 0 {
+		t.Fatalf("unexpected statements %q", sqls)
+	}
+}
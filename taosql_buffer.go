@@ -0,0 +1,141 @@
+package taosql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+const (
+	defaultInsertBufferInterval = time.Second
+)
+
+// InsertBufferOptions is the options for function NewInsertBuffer.
+type InsertBufferOptions struct {
+	Size     int             // (Optional) Max buffered rows that triggers a flush, which is Option.MaxRowsPerBatch in default.
+	Interval time.Duration   // (Optional) Interval of the periodical flushes, which is 1 second in default.
+	OnError  func(err error) // (Optional) Handler of the errors of the periodical flushes, which are logged in default.
+}
+
+// InsertBuffer accumulates the rows per subtable and inserts them by BatchInsert when the buffered rows reach
+// the size, or periodically by the interval, which gives a high-throughput write path for the high-frequency
+// single-row inserts. It is safe for concurrent use, and should be closed by Close, which drains the buffer.
+//
+// Note that the rows of a failed flush are dropped rather than retried, and the error is returned by the Add or
+// Flush that triggers it, or passed to InsertBufferOptions.OnError for the periodical flushes.
+type InsertBuffer struct {
+	driver  *Driver
+	ctx     context.Context
+	opts    InsertBufferOptions
+	mu      sync.Mutex // mu protects the buffered rows.
+	flushMu sync.Mutex // flushMu serializes the flushes, which keeps the order of the rows.
+	groups  map[string]gdb.List
+	count   int
+	closed  bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewInsertBuffer creates and returns an insert buffer, which inserts the buffered rows with context `ctx`,
+// and flushes periodically in background until it is closed.
+func (d *Driver) NewInsertBuffer(ctx context.Context, opts InsertBufferOptions) *InsertBuffer {
+	if opts.Size <= 0 {
+		if opts.Size = d.option.MaxRowsPerBatch; opts.Size <= 0 {
+			opts.Size = defaultMaxRowsPerBatch
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInsertBufferInterval
+	}
+	b := &InsertBuffer{
+		driver: d,
+		ctx:    ctx,
+		opts:   opts,
+		groups: make(map[string]gdb.List),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// Add buffers the rows of `data` of subtable `subtable`, which can be a map, struct, or slice of them,
+// and flushes the buffer if the buffered rows reach the size.
+func (b *InsertBuffer) Add(subtable string, data interface{}) error {
+	list, err := b.driver.toRecordList(b.ctx, data)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return gerror.NewCodef(gcode.CodeMissingParameter, `no data for subtable "%s"`, subtable)
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return gerror.NewCode(gcode.CodeInvalidOperation, `insert buffer is closed`)
+	}
+	b.groups[subtable] = append(b.groups[subtable], list...)
+	b.count += len(list)
+	full := b.count >= b.opts.Size
+	b.mu.Unlock()
+	if full {
+		return b.Flush()
+	}
+	return nil
+}
+
+// Flush inserts all the buffered rows immediately. It does nothing if there's no buffered row.
+func (b *InsertBuffer) Flush() error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	b.mu.Lock()
+	groups := b.groups
+	if len(groups) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	b.groups, b.count = make(map[string]gdb.List), 0
+	b.mu.Unlock()
+	_, err := b.driver.BatchInsert(b.ctx, groups)
+	return err
+}
+
+// Close stops the periodical flushes, and drains the buffer by a final flush.
+// The Add after Close returns an error of code gcode.CodeInvalidOperation.
+func (b *InsertBuffer) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+	close(b.stop)
+	<-b.done
+	return b.Flush()
+}
+
+// loop flushes the buffer periodically until the buffer is closed.
+func (b *InsertBuffer) loop() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			if err := b.Flush(); err != nil {
+				if b.opts.OnError != nil {
+					b.opts.OnError(err)
+				} else {
+					b.driver.GetLogger().Errorf(b.ctx, `[taossql] insert buffer flush failed: %+v`, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package taosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// bufferedInserts returns the number of rows of each INSERT statement received by `server`,
+// in which each row is of columns current and ts.
+func bufferedInserts(server *mockServer) []int {
+	var inserts []int
+	for _, sql := range server.Sqls() {
+		if gstr.HasPrefix(sql, "INSERT") {
+			inserts = append(inserts, gstr.Count(sql, "(?,?)"))
+		}
+	}
+	return inserts
+}
+
+// bufferRow returns a row of subtable buffered by InsertBuffer at offset `i`.
+func bufferRow(i int) map[string]interface{} {
+	return map[string]interface{}{"ts": int64(1672531200000 + i), "current": 10.5}
+}
+
+func TestInsertBufferSize(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	b := d.NewInsertBuffer(context.Background(), InsertBufferOptions{Size: 3, Interval: time.Hour})
+	defer b.Close()
+	steps := []struct {
+		subtable string
+		want     []int
+	}{
+		{subtable: "d1001", want: nil},
+		{subtable: "d1002", want: nil},
+		{subtable: "d1001", want: []int{3}},
+		{subtable: "d1001", want: []int{3}},
+	}
+	for i, step := range steps {
+		if err := b.Add(step.subtable, bufferRow(i)); err != nil {
+			t.Fatal(err)
+		}
+		if got := bufferedInserts(server); !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("got inserts %v after row %d, want %v", got, i, step.want)
+		}
+	}
+	// The manual flush inserts the rest rows under the size.
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bufferedInserts(server), []int{3, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got inserts %v after flush, want %v", got, want)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(bufferedInserts(server)); got != 2 {
+		t.Fatalf("got %d inserts after flushing the empty buffer, want 2", got)
+	}
+}
+
+func TestInsertBufferInterval(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	b := d.NewInsertBuffer(context.Background(), InsertBufferOptions{Size: 100, Interval: 10 * time.Millisecond})
+	defer b.Close()
+	for i := 0; i < 2; i++ {
+		if err := b.Add("d1001", bufferRow(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for deadline := time.Now().Add(time.Second); len(bufferedInserts(server)) == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("got no insert, want the periodical flush")
+		}
+	}
+	if got, want := bufferedInserts(server), []int{2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got inserts %v, want %v", got, want)
+	}
+}
+
+func TestInsertBufferClose(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	b := d.NewInsertBuffer(context.Background(), InsertBufferOptions{Size: 100, Interval: time.Hour})
+	for i := 0; i < 3; i++ {
+		if err := b.Add(fmt.Sprintf("d%d", 1001+i), bufferRow(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bufferedInserts(server), []int{3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got inserts %v, want the buffer drained by close", got)
+	}
+	checkCode(t, b.Add("d1001", bufferRow(0)), gcode.CodeInvalidOperation)
+	if err := b.Close(); err != nil {
+		t.Fatalf("got error %v of closing twice", err)
+	}
+	if got := len(bufferedInserts(server)); got != 1 {
+		t.Fatalf("got %d inserts, want no more after close", got)
+	}
+}
+
+func TestInsertBufferErrors(t *testing.T) {
+	errInsert := errors.New("insert failed")
+	d, _ := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+		return mockResponse{Err: errInsert}
+	}))
+	checkCode(t, d.NewInsertBuffer(context.Background(), InsertBufferOptions{}).Add("d1001", nil), gcode.CodeMissingParameter)
+
+	// The error of the flush triggered by the size is returned by Add.
+	b := d.NewInsertBuffer(context.Background(), InsertBufferOptions{Size: 1, Interval: time.Hour})
+	if err := b.Add("d1001", bufferRow(0)); err == nil || !gstr.Contains(err.Error(), errInsert.Error()) {
+		t.Fatalf("got error %v, want %v", err, errInsert)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("got error %v, want nil as the failed rows are dropped", err)
+	}
+
+	// The error of the periodical flush is passed to OnError.
+	errs := make(chan error, 1)
+	b = d.NewInsertBuffer(context.Background(), InsertBufferOptions{
+		Size:     100,
+		Interval: 10 * time.Millisecond,
+		OnError:  func(err error) { errs <- err },
+	})
+	defer b.Close()
+	if err := b.Add("d1001", bufferRow(0)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if err == nil || !gstr.Contains(err.Error(), errInsert.Error()) {
+			t.Fatalf("got error %v, want %v", err, errInsert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("got no error of the periodical flush")
+	}
+}
@@ -0,0 +1,73 @@
+package taosql
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/encoding/gcharset"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+const (
+	charsetUTF8 = "UTF-8"
+)
+
+// DecodeStrings decodes the values of the BINARY(VARCHAR) and NCHAR columns of `result` to strings in place,
+// by the column types of `table` retrieved from TableFields. The columns that are not of `table`, like
+// expressions or aliases, are left unchanged.
+//
+// The BINARY columns are returned as raw bytes by gdb, which are decoded from `charset` to UTF-8 strings.
+// The optional parameter `charset` overrides Option.BinaryCharset, which is UTF-8 in default.
+// The NCHAR columns are always stored as unicode, which are converted to strings as they are.
+func (d *Driver) DecodeStrings(ctx context.Context, table string, result gdb.Result, charset ...string) error {
+	if len(result) == 0 {
+		return nil
+	}
+	useCharset := d.option.BinaryCharset
+	if len(charset) > 0 && charset[0] != "" {
+		useCharset = charset[0]
+	}
+	if useCharset == "" {
+		useCharset = charsetUTF8
+	}
+	if !gstr.Equal(useCharset, charsetUTF8) && !gcharset.Supported(useCharset) {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `unsupported charset "%s"`, useCharset)
+	}
+	fields, err := d.TableFields(withoutClause(ctx), table)
+	if err != nil {
+		return err
+	}
+	for _, record := range result {
+		for name, value := range record {
+			field, ok := fields[name]
+			if !ok || value.IsNil() {
+				continue
+			}
+			switch columnTypeName(field.Type) {
+			case "binary", "varchar":
+				s := value.String()
+				if !gstr.Equal(useCharset, charsetUTF8) {
+					if s, err = gcharset.ToUTF8(useCharset, s); err != nil {
+						return gerror.WrapCodef(gcode.CodeInvalidParameter, err,
+							`decode column "%s" from charset "%s" failed`, name, useCharset)
+					}
+				}
+				record[name] = gvar.New(s)
+			case "nchar":
+				record[name] = gvar.New(value.String())
+			}
+		}
+	}
+	return nil
+}
+
+// columnTypeName returns the lower case type name of column type `t` without its length, like: binary.
+func columnTypeName(t string) string {
+	if pos := gstr.Pos(t, "("); pos >= 0 {
+		t = t[:pos]
+	}
+	return gstr.ToLower(gstr.Trim(t))
+}
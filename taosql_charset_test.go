@@ -0,0 +1,64 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/encoding/gcharset"
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+// stringsDesc is the response of `desc` of a table of BINARY column `name` and NCHAR column `title`.
+var stringsDesc = mockRecords(
+	[]string{"field", "type", "length", "note"},
+	[]interface{}{"ts", "TIMESTAMP", int64(8), ""},
+	[]interface{}{"name", "BINARY", int64(32), ""},
+	[]interface{}{"title", "NCHAR", int64(32), ""},
+)
+
+func TestDecodeStrings(t *testing.T) {
+	gbk, err := gcharset.UTF8To("GBK", "北京")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name      string
+		option    Option
+		charset   []string
+		binary    interface{}
+		wantName  string
+		wantTitle string
+		code      gcode.Code
+	}{
+		{name: "utf-8", binary: []byte("北京"), wantName: "北京", wantTitle: "上海"},
+		{name: "option charset", option: Option{BinaryCharset: "GBK"}, binary: []byte(gbk), wantName: "北京", wantTitle: "上海"},
+		{name: "charset parameter", charset: []string{"GBK"}, binary: []byte(gbk), wantName: "北京", wantTitle: "上海"},
+		{name: "unsupported charset", charset: []string{"NO-SUCH-CHARSET"}, binary: []byte("x"), code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, c.option, func(mockQuery) mockResponse { return stringsDesc })
+			result := gdb.Result{{
+				"name":     gvar.New(c.binary),
+				"title":    gvar.New([]byte("上海")),
+				"nickname": gvar.New([]byte("alias")),
+			}}
+			err := d.DecodeStrings(context.Background(), "users", result, c.charset...)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				return
+			}
+			if got, ok := result[0]["name"].Val().(string); !ok || got != c.wantName {
+				t.Fatalf("got BINARY value %#v, want %q", result[0]["name"].Val(), c.wantName)
+			}
+			if got, ok := result[0]["title"].Val().(string); !ok || got != c.wantTitle {
+				t.Fatalf("got NCHAR value %#v, want %q", result[0]["title"].Val(), c.wantTitle)
+			}
+			if _, ok := result[0]["nickname"].Val().([]byte); !ok {
+				t.Fatalf("got value %#v of unknown column, want it unchanged", result[0]["nickname"].Val())
+			}
+		})
+	}
+}
@@ -0,0 +1,584 @@
+package taosql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// FillMode is the mode of FILL clause, which fills the missing data of windows or interpolation points.
+type FillMode string
+
+const (
+	FillNone   FillMode = "NONE"   // Do not fill.
+	FillNull   FillMode = "NULL"   // Fill with NULL.
+	FillValue  FillMode = "VALUE"  // Fill with given constant values.
+	FillPrev   FillMode = "PREV"   // Fill with the previous non-NULL value.
+	FillNext   FillMode = "NEXT"   // Fill with the next non-NULL value.
+	FillLinear FillMode = "LINEAR" // Fill with the linear interpolation of the previous and next non-NULL values.
+)
+
+const (
+	ctxKeyForClause gctx.StrKey = `TaossqlClause`
+)
+
+var (
+	// clauseInsertKeywords are the SELECT keywords that the TDengine specific clauses are spliced before.
+	clauseInsertKeywords = []string{" GROUP BY ", " HAVING ", " ORDER BY ", " SLIMIT ", " LIMIT ", " OFFSET "}
+
+	// pseudoColumns are the pseudo columns of TDengine, which are never quoted as identifiers.
+	pseudoColumns = map[string]struct{}{
+		"tbname": {}, "_wstart": {}, "_wend": {}, "_wduration": {}, "_irowts": {},
+		"_qstart": {}, "_qend": {}, "_qduration": {}, "_rowts": {}, "_c0": {},
+	}
+
+	// aggregateFuncPattern matches the calls of TDengine aggregate and selection functions.
+	aggregateFuncPattern = `(?i)\b(COUNT|SUM|AVG|MIN|MAX|SPREAD|STDDEV|FIRST|LAST|LAST_ROW|MODE|ELAPSED|TWA|IRATE|` +
+		`LEASTSQUARES|HYPERLOGLOG|PERCENTILE|APERCENTILE|HISTOGRAM|TOP|BOTTOM|SAMPLE|UNIQUE)\s*\(`
+)
+
+// Clause is the builder for TDengine specific clauses of SELECT statement, which gdb.Model cannot express,
+// like PARTITION BY, RANGE/EVERY, INTERVAL/SLIDING, EVENT_WINDOW and FILL. It is bound to the statements with WithClause,
+// and DoFilter splices it into the SELECT statement right after the WHERE condition, eg:
+//
+// ctx = taosql.WithClause(ctx, taosql.NewClause().Range(start, end).Every("1s").Fill(taosql.FillPrev))
+// db.Model("meters").Ctx(ctx).Fields("_irowts", "INTERP(current)").All()
+//
+// The chaining functions do not return error, the first error of them is returned by function Build,
+// or by the statement execution.
+type Clause struct {
+	partitionBy    []string
+	rangeStart     time.Time
+	rangeEnd       time.Time
+	every          string
+	interval       string
+	intervalOffset time.Duration
+	sliding        string
+	eventStart     string
+	eventEnd       string
+	tzOffset       *time.Duration
+	fill           string
+	having         string
+	err            error
+}
+
+// NewClause creates and returns an empty clause builder.
+func NewClause() *Clause {
+	return &Clause{}
+}
+
+// WithClause creates and returns a new context from `ctx` with `clause` bound, which is spliced into
+// all SELECT statements that are executed with the returned context.
+func WithClause(ctx context.Context, clause *Clause) context.Context {
+	return context.WithValue(ctx, ctxKeyForClause, clause)
+}
+
+// ClauseFromCtx retrieves and returns the clause bound to `ctx`, or nil if there's no clause bound.
+func ClauseFromCtx(ctx context.Context) *Clause {
+	if ctx == nil {
+		return nil
+	}
+	if clause, ok := ctx.Value(ctxKeyForClause).(*Clause); ok {
+		return clause
+	}
+	return nil
+}
+
+// withoutClause returns a new context from `ctx` without clause bound,
+// which is used for internal statements that should not be affected by the user clause.
+func withoutClause(ctx context.Context) context.Context {
+	if ClauseFromCtx(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKeyForClause, (*Clause)(nil))
+}
+
+// PartitionBy sets the `PARTITION BY exprs...` clause, which splits the data into partitions by given
+// columns, tags or expressions of them, and the aggregation and window clauses are computed per partition.
+// The expression partitions coarsely by a function of a tag, like: PartitionBy("SUBSTR(location, 1, 3)").
+// The plain column and tag names are quoted as identifiers, but the pseudo columns like `tbname` are not,
+// like: PartitionBy("tbname", "location") for PARTITION BY tbname, `location`.
+func (c *Clause) PartitionBy(exprs ...string) *Clause {
+	if len(exprs) == 0 {
+		c.setErr(gerror.NewCode(gcode.CodeInvalidParameter, `at least one expression is required for PARTITION BY`))
+		return c
+	}
+	for _, expr := range exprs {
+		if err := checkExpr(expr); err != nil {
+			c.setErr(gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid expression for PARTITION BY`))
+			return c
+		}
+	}
+	for _, expr := range exprs {
+		c.partitionBy = append(c.partitionBy, quoteIdentifier(gstr.Trim(expr)))
+	}
+	return c
+}
+
+// Range sets the `RANGE(start, end)` clause for INTERP queries, which specifies the time range
+// of the interpolation points, both inclusive.
+func (c *Clause) Range(start, end time.Time) *Clause {
+	if end.Before(start) {
+		c.setErr(gerror.NewCodef(gcode.CodeInvalidParameter, `range end "%s" is before start "%s"`, end, start))
+	}
+	c.rangeStart, c.rangeEnd = start, end
+	return c
+}
+
+// Every sets the `EVERY(interval)` clause for INTERP queries, which specifies the interval between
+// interpolation points, like: 1s, 5m.
+func (c *Clause) Every(interval Interval) *Clause {
+	c.setErr(checkInterval(interval.String()))
+	c.every = interval.String()
+	return c
+}
+
+// Interval sets the `INTERVAL(interval[, offset])` window clause, which aggregates the data in time windows
+// of `interval`, like: 10s, 1h, 1d. The optional parameter `offset` shifts the window boundaries, which should
+// be smaller than `interval`, like: Interval("1d", "9h") for the daily windows starting at 9am.
+func (c *Clause) Interval(interval Interval, offset ...Interval) *Clause {
+	c.setErr(checkInterval(interval.String()))
+	c.interval, c.intervalOffset = interval.String(), 0
+	if len(offset) == 0 || c.err != nil {
+		return c
+	}
+	intervalOffset, err := parseDuration(offset[0].String())
+	if err != nil {
+		c.setErr(gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid interval offset "%s"`, offset[0]))
+		return c
+	}
+	if intervalOffset >= minDuration(interval.String()) {
+		c.setErr(gerror.NewCodef(
+			gcode.CodeInvalidParameter, `interval offset "%s" should be smaller than interval "%s"`, offset[0], interval,
+		))
+		return c
+	}
+	c.intervalOffset = intervalOffset
+	return c
+}
+
+// Sliding sets the `SLIDING(sliding)` clause of INTERVAL windows, which specifies the forward step of the windows,
+// like: Interval("10s").Sliding("5s") for the overlapping windows of 10 seconds every 5 seconds.
+// The `sliding` should be a fixed length duration that is not greater than the interval, which is validated
+// by function Build, as the sliding can be set before the interval.
+func (c *Clause) Sliding(sliding Interval) *Clause {
+	if err := checkInterval(sliding.String()); err != nil {
+		c.setErr(err)
+		return c
+	}
+	if _, err := parseDuration(sliding.String()); err != nil {
+		c.setErr(gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid sliding "%s"`, sliding))
+		return c
+	}
+	c.sliding = sliding.String()
+	return c
+}
+
+// EventWindow sets the `EVENT_WINDOW START WITH start END WITH end` window clause, which aggregates the data
+// in the windows from the row meeting condition `start` to the row meeting condition `end`, like the windows
+// from door-open to door-close: EventWindow("status = 'open'", "status = 'closed'").
+//
+// It cannot be combined with the INTERVAL windows, and requires TDengine server of version 3.0.4.0 or later,
+// which is checked before the statement is sent, see Driver.ServerVersion.
+func (c *Clause) EventWindow(start, end string) *Clause {
+	for _, condition := range []string{start, end} {
+		if err := checkExpr(condition); err != nil {
+			c.setErr(gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid condition for EVENT_WINDOW`))
+			return c
+		}
+	}
+	c.eventStart, c.eventEnd = start, end
+	return c
+}
+
+// TimezoneOffset aligns the INTERVAL windows to the timezone of UTC offset `offset`, like 8 * time.Hour for
+// UTC+8, as TDengine aligns the windows to UTC in default, which misgroups the daily windows for non-UTC zones.
+// It is rendered as the window offset of INTERVAL, like: INTERVAL(1d, 16h) for daily windows of UTC+8,
+// which is combined with the offset of Interval that is in the local time then.
+// The offset of a location is retrieved by: _, offset := time.Now().In(loc).Zone().
+//
+// It is only supported for the fixed length intervals, but not for the natural month(n) and year(y) intervals.
+func (c *Clause) TimezoneOffset(offset time.Duration) *Clause {
+	c.tzOffset = &offset
+	return c
+}
+
+// Having sets the `HAVING condition` clause, which filters the partitions of PARTITION BY or the windows by
+// `condition` on their aggregates, like: PartitionBy("tbname").Having("SUM(current) > 100").
+// The `condition` should reference at least one aggregate function, and the HAVING of the clause cannot
+// be combined with the GROUP BY or HAVING of the statement, for which gdb.Model.Having is used instead.
+func (c *Clause) Having(condition string) *Clause {
+	if err := checkExpr(condition); err != nil {
+		c.setErr(gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid condition for HAVING`))
+		return c
+	}
+	if !gregex.IsMatchString(aggregateFuncPattern, maskStringLiterals(condition)) {
+		c.setErr(gerror.NewCodef(
+			gcode.CodeInvalidParameter, `condition "%s" for HAVING does not reference any aggregate`, condition,
+		))
+		return c
+	}
+	c.having = condition
+	return c
+}
+
+// Fill sets the `FILL(mode[, values...])` clause, which fills the missing data of windows or interpolation points.
+// The parameter `values` is required for and only for FillValue mode. It requires the INTERVAL windows,
+// or the EVERY interpolation points of INTERP queries, which is validated by function Build.
+func (c *Clause) Fill(mode FillMode, values ...interface{}) *Clause {
+	switch mode {
+	case FillValue:
+		if len(values) == 0 {
+			c.setErr(gerror.NewCode(gcode.CodeInvalidParameter, `fill mode VALUE requires at least one value`))
+			return c
+		}
+		array := make([]string, len(values))
+		for i, v := range values {
+			array[i] = formatFillValue(v)
+		}
+		c.fill = fmt.Sprintf(`FILL(%s, %s)`, mode, gstr.Join(array, ", "))
+	case FillNone, FillNull, FillPrev, FillNext, FillLinear:
+		if len(values) > 0 {
+			c.setErr(gerror.NewCodef(gcode.CodeInvalidParameter, `fill mode %s does not accept values`, mode))
+			return c
+		}
+		c.fill = fmt.Sprintf(`FILL(%s)`, mode)
+	default:
+		c.setErr(gerror.NewCodef(gcode.CodeInvalidParameter, `invalid fill mode "%s"`, mode))
+	}
+	return c
+}
+
+// Build validates and renders the clauses in the order that TDengine requires, like:
+// PARTITION BY ... RANGE(...) EVERY(...) INTERVAL(...) SLIDING(...) FILL(...) HAVING ....
+// The EVENT_WINDOW is rendered at the position of INTERVAL, as they cannot be combined.
+func (c *Clause) Build() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	var array []string
+	if len(c.partitionBy) > 0 {
+		array = append(array, `PARTITION BY `+gstr.Join(c.partitionBy, ", "))
+	}
+	if !c.rangeStart.IsZero() || !c.rangeEnd.IsZero() {
+		array = append(array, fmt.Sprintf(
+			`RANGE(%s, %s)`, formatTimeLiteral(c.rangeStart), formatTimeLiteral(c.rangeEnd),
+		))
+	}
+	if c.every != "" {
+		array = append(array, fmt.Sprintf(`EVERY(%s)`, c.every))
+	}
+	if c.interval != "" {
+		window, err := c.buildInterval()
+		if err != nil {
+			return "", err
+		}
+		array = append(array, window)
+	}
+	if c.eventStart != "" {
+		if c.interval != "" {
+			return "", gerror.NewCode(gcode.CodeInvalidParameter, `EVENT_WINDOW cannot be combined with INTERVAL`)
+		}
+		array = append(array, fmt.Sprintf(`EVENT_WINDOW START WITH %s END WITH %s`, c.eventStart, c.eventEnd))
+	}
+	if c.sliding != "" {
+		if c.interval == "" {
+			return "", gerror.NewCode(gcode.CodeInvalidParameter, `SLIDING requires INTERVAL`)
+		}
+		sliding, _ := parseDuration(c.sliding)
+		if sliding > minDuration(c.interval) {
+			return "", gerror.NewCodef(
+				gcode.CodeInvalidParameter, `sliding "%s" should not be greater than interval "%s"`, c.sliding, c.interval,
+			)
+		}
+		array = append(array, fmt.Sprintf(`SLIDING(%s)`, c.sliding))
+	}
+	if c.fill != "" {
+		if c.interval == "" && c.every == "" {
+			return "", gerror.NewCode(gcode.CodeInvalidParameter, `FILL requires INTERVAL, or EVERY for INTERP queries`)
+		}
+		array = append(array, c.fill)
+	}
+	if c.having != "" {
+		array = append(array, `HAVING `+c.having)
+	}
+	return gstr.Join(array, " "), nil
+}
+
+// buildInterval renders the INTERVAL window clause, with the window offset of the interval offset,
+// and of the timezone offset if set.
+func (c *Clause) buildInterval() (string, error) {
+	offset := c.intervalOffset
+	if c.tzOffset != nil {
+		interval, err := parseDuration(c.interval)
+		if err != nil {
+			return "", gerror.WrapCodef(
+				gcode.CodeInvalidParameter, err, `timezone offset is not supported for interval "%s"`, c.interval,
+			)
+		}
+		// The windows are aligned to the multiples of interval since UTC epoch, which are shifted back by the
+		// timezone offset, so that they are aligned to the local time.
+		if offset = (offset - *c.tzOffset) % interval; offset < 0 {
+			offset += interval
+		}
+	}
+	if offset == 0 {
+		return fmt.Sprintf(`INTERVAL(%s)`, c.interval), nil
+	}
+	return fmt.Sprintf(`INTERVAL(%s, %s)`, c.interval, formatDuration(offset)), nil
+}
+
+// splice renders and splices the clauses into SELECT statement `sql` right after its WHERE condition,
+// that is before its first top-level GROUP BY/HAVING/ORDER BY/SLIMIT/LIMIT/OFFSET keyword.
+// It does nothing if `sql` is not a SELECT statement.
+func (c *Clause) splice(sql string) (string, error) {
+	if !gregex.IsMatchString(`^(?i)\s*SELECT\s`, sql) {
+		return sql, nil
+	}
+	clause, err := c.Build()
+	if err != nil || clause == "" {
+		return sql, err
+	}
+	if c.having != "" && topLevelIndex(sql, " GROUP BY ", " HAVING ") >= 0 {
+		return "", gerror.NewCode(
+			gcode.CodeInvalidOperation, `HAVING of clause cannot be combined with GROUP BY or HAVING of the statement`,
+		)
+	}
+	pos := topLevelIndex(sql, clauseInsertKeywords...)
+	if pos < 0 {
+		return sql + " " + clause, nil
+	}
+	return sql[:pos] + " " + clause + sql[pos:], nil
+}
+
+// quoteIdentifier quotes `expr` with the identifier chars if it's a plain column name,
+// and returns the expressions, quoted identifiers and pseudo columns unchanged.
+func quoteIdentifier(expr string) string {
+	if !gregex.IsMatchString(`^[A-Za-z_]\w*$`, expr) || isPseudoColumn(expr) {
+		return expr
+	}
+	return "`" + expr + "`"
+}
+
+// isPseudoColumn checks whether `name` is a pseudo column of TDengine, like `tbname`, case-insensitively.
+func isPseudoColumn(name string) bool {
+	_, ok := pseudoColumns[gstr.ToLower(name)]
+	return ok
+}
+
+// unquotePseudoColumns removes the identifier quotes of the pseudo columns of `sql`, like `tbname`,
+// except within the string literals.
+func unquotePseudoColumns(sql string) string {
+	if !strings.Contains(sql, "`") {
+		return sql
+	}
+	var (
+		buffer  strings.Builder
+		quote   byte
+		escaped bool
+	)
+	for i := 0; i < len(sql); i++ {
+		switch ch := sql[i]; {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if ch == '\\' {
+				escaped = true
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '`':
+			if end := strings.IndexByte(sql[i+1:], '`'); end >= 0 {
+				if name := sql[i+1 : i+1+end]; isPseudoColumn(name) {
+					buffer.WriteString(name)
+				} else {
+					buffer.WriteString(sql[i : i+2+end])
+				}
+				i += end + 1
+				continue
+			}
+		}
+		buffer.WriteByte(sql[i])
+	}
+	return buffer.String()
+}
+
+// setErr records `err` as the error of the clause builder if there's no error recorded yet.
+func (c *Clause) setErr(err error) {
+	if c.err == nil && err != nil {
+		c.err = err
+	}
+}
+
+// topLevelIndex returns the index of the first occurrence of any keyword of `keywords` in `sql`
+// case-insensitively, which is neither quoted nor within parentheses. The backslash escaped chars
+// in quotes are skipped. It returns -1 if not found.
+func topLevelIndex(sql string, keywords ...string) int {
+	var (
+		depth   int
+		quote   byte
+		escaped bool
+		upper   = strings.ToUpper(sql)
+	)
+	for i := 0; i < len(sql); i++ {
+		switch ch := sql[i]; {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if ch == '\\' {
+				escaped = true
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case depth == 0:
+			for _, keyword := range keywords {
+				if strings.HasPrefix(upper[i:], keyword) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// maskStringLiterals returns `sql` with the contents of its string literals replaced by spaces, so that the
+// keywords and names are searched in the returned string without matching the literals, at the same positions
+// as in `sql`. The quote chars are kept, and the backslash escaped chars in quotes are masked as well.
+func maskStringLiterals(sql string) string {
+	var (
+		masked  []byte
+		quote   byte
+		escaped bool
+	)
+	for i := 0; i < len(sql); i++ {
+		switch ch := sql[i]; {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if ch == '\\' {
+				escaped = true
+			} else if ch == quote {
+				quote = 0
+				continue
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			continue
+		default:
+			continue
+		}
+		if masked == nil {
+			masked = []byte(sql)
+		}
+		masked[i] = ' '
+	}
+	if masked == nil {
+		return sql
+	}
+	return string(masked)
+}
+
+// splitTopLevel splits `sql` by the occurrences of `separator` that are neither quoted nor within parentheses,
+// case-insensitively, see topLevelIndex.
+func splitTopLevel(sql, separator string) []string {
+	var parts []string
+	for {
+		pos := topLevelIndex(sql, separator)
+		if pos < 0 {
+			return append(parts, gstr.Trim(sql))
+		}
+		parts = append(parts, gstr.Trim(sql[:pos]))
+		sql = sql[pos+len(separator):]
+	}
+}
+
+// trimParentheses removes the enclosing parentheses of `expr`, like: ((a > 1)) to a > 1.
+func trimParentheses(expr string) string {
+	expr = gstr.Trim(expr)
+	for len(expr) > 1 && expr[0] == '(' && expr[len(expr)-1] == ')' && checkExpr(expr[1:len(expr)-1]) == nil {
+		expr = gstr.Trim(expr[1 : len(expr)-1])
+	}
+	return expr
+}
+
+// checkDuration checks whether `s` is a valid TDengine duration literal, like: 10a, 1s, 5m, 1d.
+func checkDuration(s string) error {
+	if !gregex.IsMatchString(`^\d+[abusmhdwny]$`, s) {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid duration "%s"`, s)
+	}
+	return nil
+}
+
+// checkInterval checks whether `s` is a valid duration literal of the INTERVAL, SLIDING and EVERY clauses,
+// which should be positive, like: 10s, 1d.
+func checkInterval(s string) error {
+	if err := checkDuration(s); err != nil {
+		return err
+	}
+	if gconv.Int64(s[:len(s)-1]) == 0 {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid interval "%s", it should be positive`, s)
+	}
+	return nil
+}
+
+// parseDuration parses fixed length TDengine duration literal `s` to time.Duration, like: 10a, 1s, 1d, 1w.
+// The natural month(n) and year(y) durations are not supported, as they have no fixed length.
+func parseDuration(s string) (time.Duration, error) {
+	match, _ := gregex.MatchString(`^(\d+)([buasmhdw])$`, s)
+	if len(match) == 0 {
+		return 0, gerror.NewCodef(gcode.CodeInvalidParameter, `invalid fixed length duration "%s"`, s)
+	}
+	units := map[string]time.Duration{
+		"b": time.Nanosecond,
+		"u": time.Microsecond,
+		"a": time.Millisecond,
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	return time.Duration(gconv.Int64(match[1])) * units[match[2]], nil
+}
+
+// minDuration returns the minimum length of TDengine duration literal `s`, which is the length of the fixed
+// length durations, and the length of the shortest month or year for the natural month(n) and year(y) durations.
+func minDuration(s string) time.Duration {
+	if match, _ := gregex.MatchString(`^(\d+)([ny])$`, s); len(match) > 0 {
+		days := 28
+		if match[2] == "y" {
+			days = 365
+		}
+		return time.Duration(gconv.Int64(match[1])*int64(days)) * 24 * time.Hour
+	}
+	d, _ := parseDuration(s)
+	return d
+}
+
+// formatFillValue formats `v` as constant value of FILL clause.
+func formatFillValue(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return quoteString(value)
+	case nil:
+		return "NULL"
+	default:
+		return gconv.String(value)
+	}
+}
@@ -0,0 +1,532 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/text/gregex"
+)
+
+// clauseCase is a test case of the clause builder.
+type clauseCase struct {
+	name   string
+	clause *Clause
+	want   string
+	code   gcode.Code // Expected error code, or nil for success.
+}
+
+// runClauseCases runs the test cases of the clause builder by Build.
+func runClauseCases(t *testing.T, cases []clauseCase) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.clause.Build()
+			checkCode(t, err, c.code)
+			if c.code == nil && got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestClausePartitionBy(t *testing.T) {
+	runClauseCases(t, []clauseCase{
+		{
+			name:   "tag",
+			clause: NewClause().PartitionBy("location"),
+			want:   "PARTITION BY `location`",
+		},
+		{
+			name:   "tbname",
+			clause: NewClause().PartitionBy("tbname"),
+			want:   "PARTITION BY tbname",
+		},
+		{
+			name:   "upper case tbname",
+			clause: NewClause().PartitionBy(" TBNAME "),
+			want:   "PARTITION BY TBNAME",
+		},
+		{
+			name:   "tbname and tag with window",
+			clause: NewClause().PartitionBy("tbname", "location").Interval("10m"),
+			want:   "PARTITION BY tbname, `location` INTERVAL(10m)",
+		},
+		{
+			name:   "quoted tag",
+			clause: NewClause().PartitionBy(" `location` "),
+			want:   "PARTITION BY `location`",
+		},
+		{
+			name:   "expression",
+			clause: NewClause().PartitionBy("SUBSTR(location, 1, 3)"),
+			want:   "PARTITION BY SUBSTR(location, 1, 3)",
+		},
+		{
+			name:   "expression with window",
+			clause: NewClause().PartitionBy("SUBSTR(location, 1, 3)", "groupid").Interval("1h"),
+			want:   "PARTITION BY SUBSTR(location, 1, 3), `groupid` INTERVAL(1h)",
+		},
+		{
+			name:   "no expression",
+			clause: NewClause().PartitionBy(),
+			code:   gcode.CodeInvalidParameter,
+		},
+		{
+			name:   "unbalanced expression",
+			clause: NewClause().PartitionBy("SUBSTR(location, 1, 3"),
+			code:   gcode.CodeInvalidParameter,
+		},
+		{
+			name:   "statement separator",
+			clause: NewClause().PartitionBy("location; DROP TABLE meters"),
+			code:   gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestClausePartitionBySplice(t *testing.T) {
+	cases := []struct {
+		name   string
+		clause *Clause
+		sql    string
+		want   string
+	}{
+		{
+			name:   "tbname",
+			clause: NewClause().PartitionBy("tbname").Interval("1m"),
+			sql:    "SELECT tbname, _wstart, AVG(current) FROM meters WHERE ts > NOW - 1h",
+			want:   "SELECT tbname, _wstart, AVG(current) FROM meters WHERE ts > NOW - 1h PARTITION BY tbname INTERVAL(1m)",
+		},
+		{
+			name:   "tag before order",
+			clause: NewClause().PartitionBy("location").Interval("1h"),
+			sql:    "SELECT location, _wstart, MAX(voltage) FROM meters ORDER BY _wstart",
+			want:   "SELECT location, _wstart, MAX(voltage) FROM meters PARTITION BY `location` INTERVAL(1h) ORDER BY _wstart",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.clause.splice(c.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{expr: "location", want: "`location`"},
+		{expr: "_col1", want: "`_col1`"},
+		{expr: "tbname", want: "tbname"},
+		{expr: "_wstart", want: "_wstart"},
+		{expr: "`location`", want: "`location`"},
+		{expr: "1abc", want: "1abc"},
+		{expr: "SUBSTR(location, 1, 3)", want: "SUBSTR(location, 1, 3)"},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			if got := quoteIdentifier(c.expr); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestUnquotePseudoColumns(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{name: "no quotes", sql: "SELECT tbname FROM meters", want: "SELECT tbname FROM meters"},
+		{name: "tbname", sql: "SELECT `tbname`,`current` FROM `meters`", want: "SELECT tbname,`current` FROM `meters`"},
+		{name: "window columns", sql: "SELECT `_wstart`,`_WEND` FROM `meters`", want: "SELECT _wstart,_WEND FROM `meters`"},
+		{name: "qualified", sql: "SELECT `meters`.`tbname` FROM `meters`", want: "SELECT `meters`.tbname FROM `meters`"},
+		{name: "string literal", sql: "SELECT `tbname` FROM meters WHERE location='`tbname`'", want: "SELECT tbname FROM meters WHERE location='`tbname`'"},
+		{name: "escaped quote", sql: "SELECT `tbname` FROM meters WHERE location='a\\'`tbname`'", want: "SELECT tbname FROM meters WHERE location='a\\'`tbname`'"},
+		{name: "unterminated", sql: "SELECT `tbname", want: "SELECT `tbname"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := unquotePseudoColumns(c.sql); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFieldsTbname(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(func(mockQuery) mockResponse {
+		return mockRecords(
+			[]string{"tbname", "current"},
+			[]interface{}{[]byte("d1001"), 10.3},
+			[]interface{}{[]byte("d1002"), 12.6},
+		)
+	}))
+	var rows []struct {
+		Tbname  string  `orm:"tbname"`
+		Current float64 `orm:"current"`
+	}
+	if err := d.Model("meters").Ctx(context.Background()).Fields("tbname,current").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	sqls := server.Sqls()
+	if want := "SELECT tbname,`current` FROM `meters`"; sqls[len(sqls)-1] != want {
+		t.Fatalf("got sql %q, want %q", sqls[len(sqls)-1], want)
+	}
+	if len(rows) != 2 || rows[0].Tbname != "d1001" || rows[1].Tbname != "d1002" || rows[1].Current != 12.6 {
+		t.Fatalf("got rows %+v", rows)
+	}
+}
+
+func TestClauseTimezoneOffset(t *testing.T) {
+	runClauseCases(t, []clauseCase{
+		{
+			name:   "utc",
+			clause: NewClause().Interval("1d"),
+			want:   "INTERVAL(1d)",
+		},
+		{
+			// The daily windows of UTC+8 start at 16:00 UTC of the previous day.
+			name:   "east offset",
+			clause: NewClause().Interval("1d").TimezoneOffset(8 * time.Hour),
+			want:   "INTERVAL(1d, 16h)",
+		},
+		{
+			name:   "west offset",
+			clause: NewClause().Interval("1d").TimezoneOffset(-5 * time.Hour),
+			want:   "INTERVAL(1d, 5h)",
+		},
+		{
+			name:   "offset with interval offset",
+			clause: NewClause().Interval("1d", "9h").TimezoneOffset(8 * time.Hour),
+			want:   "INTERVAL(1d, 1h)",
+		},
+		{
+			name:   "offset of multiple intervals",
+			clause: NewClause().Interval("1h").TimezoneOffset(8 * time.Hour),
+			want:   "INTERVAL(1h)",
+		},
+		{
+			name:   "half hour offset",
+			clause: NewClause().Interval("1d").TimezoneOffset(5*time.Hour + 30*time.Minute),
+			want:   "INTERVAL(1d, 1110m)",
+		},
+		{
+			name:   "natural month",
+			clause: NewClause().Interval("1n").TimezoneOffset(8 * time.Hour),
+			code:   gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestClauseTimezoneOffsetAlignment(t *testing.T) {
+	var (
+		loc    = time.FixedZone("UTC+8", 8*3600)
+		clause = NewClause().Interval("1d").TimezoneOffset(8 * time.Hour)
+	)
+	window, err := clause.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, _ := gregex.MatchString(`^INTERVAL\(1d, (\w+)\)$`, window)
+	if len(match) == 0 {
+		t.Fatalf("got %q, want daily windows with offset", window)
+	}
+	offset, err := parseDuration(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The windows start at the multiples of 1d since UTC epoch shifted by the offset, which should be midnight
+	// of UTC+8.
+	windowStart := time.Unix(0, 0).Add(19000 * 24 * time.Hour).Add(offset).In(loc)
+	if windowStart.Hour() != 0 || windowStart.Minute() != 0 {
+		t.Fatalf("got window start %s, want midnight of UTC+8", windowStart)
+	}
+}
+
+func TestClauseIntervalOffset(t *testing.T) {
+	runClauseCases(t, []clauseCase{
+		{
+			name:   "business day",
+			clause: NewClause().Interval("1d", "9h"),
+			want:   "INTERVAL(1d, 9h)",
+		},
+		{
+			name:   "minutes offset",
+			clause: NewClause().Interval("1h", "15m"),
+			want:   "INTERVAL(1h, 15m)",
+		},
+		{
+			name:   "zero offset",
+			clause: NewClause().Interval("1d", "0s"),
+			want:   "INTERVAL(1d)",
+		},
+		{
+			name:   "with sliding",
+			clause: NewClause().Interval("1h", "15m").Sliding("30m"),
+			want:   "INTERVAL(1h, 15m) SLIDING(30m)",
+		},
+		{
+			name:   "offset equal to interval",
+			clause: NewClause().Interval("1d", "24h"),
+			code:   gcode.CodeInvalidParameter,
+		},
+		{
+			name:   "offset greater than interval",
+			clause: NewClause().Interval("1h", "2h"),
+			code:   gcode.CodeInvalidParameter,
+		},
+		{
+			// The natural months are at least 28 days.
+			name:   "natural month",
+			clause: NewClause().Interval("1n", "29d"),
+			code:   gcode.CodeInvalidParameter,
+		},
+		{
+			name:   "invalid offset",
+			clause: NewClause().Interval("1d", "9x"),
+			code:   gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestClauseIntervalOffsetAlignment(t *testing.T) {
+	window, err := NewClause().Interval("1d", "9h").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, _ := gregex.MatchString(`^INTERVAL\(1d, (\w+)\)$`, window)
+	if len(match) == 0 {
+		t.Fatalf("got %q, want daily windows with offset", window)
+	}
+	offset, err := parseDuration(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The windows start at the multiples of 1d since UTC epoch shifted by the offset, which should be 9am UTC.
+	windowStart := time.Unix(0, 0).UTC().Add(19000 * 24 * time.Hour).Add(offset)
+	if windowStart.Hour() != 9 || windowStart.Minute() != 0 {
+		t.Fatalf("got window start %s, want 9am", windowStart)
+	}
+}
+
+func TestClauseHaving(t *testing.T) {
+	runClauseCases(t, []clauseCase{
+		{
+			name:   "partitioned sum",
+			clause: NewClause().PartitionBy("tbname").Having("SUM(current) > 100"),
+			want:   "PARTITION BY tbname HAVING SUM(current) > 100",
+		},
+		{
+			name:   "window count",
+			clause: NewClause().PartitionBy("tbname").Interval("1h").Having("count(*) >= 10 AND AVG(voltage) < 220"),
+			want:   "PARTITION BY tbname INTERVAL(1h) HAVING count(*) >= 10 AND AVG(voltage) < 220",
+		},
+		{
+			name:   "after fill",
+			clause: NewClause().Interval("1h").Fill(FillNull).Having("MAX(current) > 10"),
+			want:   "INTERVAL(1h) FILL(NULL) HAVING MAX(current) > 10",
+		},
+		{
+			name:   "non-aggregate",
+			clause: NewClause().PartitionBy("tbname").Having("current > 100"),
+			code:   gcode.CodeInvalidParameter,
+		},
+		{
+			name:   "aggregate in literal",
+			clause: NewClause().PartitionBy("tbname").Having("location = 'SUM(x)'"),
+			code:   gcode.CodeInvalidParameter,
+		},
+		{
+			name:   "statement separator",
+			clause: NewClause().Having("SUM(current) > 1; DROP TABLE meters"),
+			code:   gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestClauseHavingSplice(t *testing.T) {
+	clause := NewClause().PartitionBy("tbname").Having("SUM(current) > 100")
+	cases := []struct {
+		name string
+		sql  string
+		want string
+		code gcode.Code
+	}{
+		{
+			name: "no trailing clauses",
+			sql:  "SELECT tbname, SUM(current) FROM meters WHERE ts > 0",
+			want: "SELECT tbname, SUM(current) FROM meters WHERE ts > 0 PARTITION BY tbname HAVING SUM(current) > 100",
+		},
+		{
+			name: "before order and limit",
+			sql:  "SELECT tbname, SUM(current) AS total FROM meters ORDER BY total DESC LIMIT 10",
+			want: "SELECT tbname, SUM(current) AS total FROM meters PARTITION BY tbname HAVING SUM(current) > 100 ORDER BY total DESC LIMIT 10",
+		},
+		{
+			name: "statement group by",
+			sql:  "SELECT location, SUM(current) FROM meters GROUP BY location",
+			code: gcode.CodeInvalidOperation,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := clause.splice(c.sql)
+			checkCode(t, err, c.code)
+			if c.code == nil && got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestClauseSliding(t *testing.T) {
+	runClauseCases(t, []clauseCase{
+		{name: "interval only", clause: NewClause().Interval("10s"), want: "INTERVAL(10s)"},
+		{name: "interval and sliding", clause: NewClause().Interval("10s").Sliding("5s"), want: "INTERVAL(10s) SLIDING(5s)"},
+		{name: "sliding before interval", clause: NewClause().Sliding("5s").Interval("10s"), want: "INTERVAL(10s) SLIDING(5s)"},
+		{name: "sliding of interval", clause: NewClause().Interval("1m").Sliding("60s"), want: "INTERVAL(1m) SLIDING(60s)"},
+		{
+			name:   "sliding and fill",
+			clause: NewClause().Interval("10s").Sliding("5s").Fill(FillPrev),
+			want:   "INTERVAL(10s) SLIDING(5s) FILL(PREV)",
+		},
+		{name: "sliding greater than interval", clause: NewClause().Interval("5s").Sliding("10s"), code: gcode.CodeInvalidParameter},
+		{name: "sliding without interval", clause: NewClause().Sliding("5s"), code: gcode.CodeInvalidParameter},
+		{name: "natural sliding", clause: NewClause().Interval("1y").Sliding("1n"), code: gcode.CodeInvalidParameter},
+		{name: "invalid sliding", clause: NewClause().Interval("10s").Sliding("5x"), code: gcode.CodeInvalidParameter},
+	})
+}
+
+func TestClauseSlidingSplice(t *testing.T) {
+	clause := NewClause().Interval("10s").Sliding("5s").Fill(FillNull)
+	cases := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "time range",
+			sql:  "SELECT _wstart, AVG(current) FROM meters WHERE ts BETWEEN '2023-01-01 00:00:00' AND '2023-01-02 00:00:00'",
+			want: "SELECT _wstart, AVG(current) FROM meters WHERE ts BETWEEN '2023-01-01 00:00:00' AND '2023-01-02 00:00:00' " +
+				"INTERVAL(10s) SLIDING(5s) FILL(NULL)",
+		},
+		{
+			name: "before order",
+			sql:  "SELECT _wstart, AVG(current) FROM meters WHERE ts > NOW - 1h ORDER BY _wstart DESC",
+			want: "SELECT _wstart, AVG(current) FROM meters WHERE ts > NOW - 1h INTERVAL(10s) SLIDING(5s) FILL(NULL) ORDER BY _wstart DESC",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := clause.splice(c.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestClauseEventWindow(t *testing.T) {
+	runClauseCases(t, []clauseCase{
+		{
+			name:   "event window",
+			clause: NewClause().EventWindow("status = 'open'", "status = 'closed'"),
+			want:   "EVENT_WINDOW START WITH status = 'open' END WITH status = 'closed'",
+		},
+		{
+			name:   "partitioned",
+			clause: NewClause().PartitionBy("tbname").EventWindow("voltage > 220", "voltage <= 220"),
+			want:   "PARTITION BY tbname EVENT_WINDOW START WITH voltage > 220 END WITH voltage <= 220",
+		},
+		{
+			name:   "with interval",
+			clause: NewClause().Interval("10s").EventWindow("voltage > 220", "voltage <= 220"),
+			code:   gcode.CodeInvalidParameter,
+		},
+		{
+			name:   "invalid condition",
+			clause: NewClause().EventWindow("voltage > 220; DROP TABLE meters", "voltage <= 220"),
+			code:   gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestClauseEventWindowVersion(t *testing.T) {
+	cases := []struct {
+		name    string
+		version string
+		code    gcode.Code
+	}{
+		{name: "supported", version: "3.0.4.0"},
+		{name: "later", version: "3.1.0.0"},
+		{name: "earlier", version: "3.0.3.2", code: gcode.CodeNotSupported},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+				if q.Sql == "SELECT SERVER_VERSION()" {
+					return mockRecords([]string{"server_version()"}, []interface{}{c.version})
+				}
+				return mockRecords([]string{"_wstart", "cnt"})
+			}))
+			ctx := WithClause(context.Background(), NewClause().EventWindow("voltage > 220", "voltage <= 220"))
+			_, err := d.GetAll(ctx, "SELECT _wstart, COUNT(*) AS cnt FROM d1001")
+			checkCode(t, err, c.code)
+			sqls := server.Sqls()
+			if c.code != nil {
+				if len(sqls) != 1 {
+					t.Fatalf("got sqls %q, want the statement rejected before sending", sqls)
+				}
+				return
+			}
+			want := "SELECT _wstart, COUNT(*) AS cnt FROM d1001 EVENT_WINDOW START WITH voltage > 220 END WITH voltage <= 220"
+			if got := sqls[len(sqls)-1]; got != want {
+				t.Fatalf("got sql %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestClauseFill(t *testing.T) {
+	runClauseCases(t, []clauseCase{
+		{name: "none", clause: NewClause().Interval("10s").Fill(FillNone), want: "INTERVAL(10s) FILL(NONE)"},
+		{name: "null", clause: NewClause().Interval("10s").Fill(FillNull), want: "INTERVAL(10s) FILL(NULL)"},
+		{name: "prev", clause: NewClause().Interval("10s").Fill(FillPrev), want: "INTERVAL(10s) FILL(PREV)"},
+		{name: "next", clause: NewClause().Interval("10s").Fill(FillNext), want: "INTERVAL(10s) FILL(NEXT)"},
+		{name: "linear", clause: NewClause().Interval("10s").Fill(FillLinear), want: "INTERVAL(10s) FILL(LINEAR)"},
+		{name: "value", clause: NewClause().Interval("10s").Fill(FillValue, 0), want: "INTERVAL(10s) FILL(VALUE, 0)"},
+		{
+			name:   "values",
+			clause: NewClause().Interval("10s").Fill(FillValue, 1.5, "n/a", nil),
+			want:   "INTERVAL(10s) FILL(VALUE, 1.5, 'n/a', NULL)",
+		},
+		{
+			name:   "quoted value",
+			clause: NewClause().Interval("10s").Fill(FillValue, "it's"),
+			want:   "INTERVAL(10s) FILL(VALUE, 'it\\'s')",
+		},
+		{
+			name:   "interp",
+			clause: NewClause().Range(time.Unix(0, 0).UTC(), time.Unix(60, 0).UTC()).Every("1s").Fill(FillLinear),
+			want:   "RANGE('1970-01-01T00:00:00Z', '1970-01-01T00:01:00Z') EVERY(1s) FILL(LINEAR)",
+		},
+		{name: "value without values", clause: NewClause().Interval("10s").Fill(FillValue), code: gcode.CodeInvalidParameter},
+		{name: "values of other mode", clause: NewClause().Interval("10s").Fill(FillPrev, 0), code: gcode.CodeInvalidParameter},
+		{name: "invalid mode", clause: NewClause().Interval("10s").Fill("SPLINE"), code: gcode.CodeInvalidParameter},
+		{name: "without interval", clause: NewClause().Fill(FillNull), code: gcode.CodeInvalidParameter},
+	})
+}
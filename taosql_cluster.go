@@ -0,0 +1,209 @@
+package taosql
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gstr"
+	taosErrors "github.com/taosdata/driver-go/v2/errors"
+)
+
+const (
+	mnodeRoleLeader  = "leader"
+	mnodeStatusReady = "ready"
+
+	// minVersionForEventWindow is the minimum server version supporting EVENT_WINDOW.
+	minVersionForEventWindow = "3.0.4.0"
+)
+
+// MnodeInfo is the information of a management node, which is retrieved by `SHOW MNODES`.
+type MnodeInfo struct {
+	Id         int         // Mnode id, which is the same as its dnode id.
+	Endpoint   string      // Endpoint of the mnode, like: host:6030.
+	Role       string      // Role of the mnode, like: leader, follower, candidate, offline.
+	Status     string      // Status of the mnode, like: ready, creating, dropping.
+	CreateTime *gtime.Time // Creation time of the mnode.
+	Raw        gdb.Record  // Raw record of the mnode, for the columns that are not parsed or of different server versions.
+}
+
+// IsLeader checks and returns whether the mnode is the leader of the cluster.
+func (m MnodeInfo) IsLeader() bool {
+	return gstr.Equal(m.Role, mnodeRoleLeader)
+}
+
+// ClusterInfo is the summary of the cluster, which is retrieved by `SHOW CLUSTER` and `SHOW MNODES`.
+type ClusterInfo struct {
+	Id         int64       // Cluster id.
+	Name       string      // Cluster name.
+	Version    string      // Server version of the cluster.
+	Uptime     int64       // Uptime of the cluster in seconds.
+	CreateTime *gtime.Time // Creation time of the cluster.
+	Mnodes     []MnodeInfo // Management nodes of the cluster.
+	Raw        gdb.Record  // Raw record of `SHOW CLUSTER`.
+}
+
+// Leader returns the leader mnode of the cluster, or nil if there's no leader elected.
+func (c *ClusterInfo) Leader() *MnodeInfo {
+	for i := range c.Mnodes {
+		if c.Mnodes[i].IsLeader() {
+			return &c.Mnodes[i]
+		}
+	}
+	return nil
+}
+
+// Healthy checks and returns whether the cluster has exactly one leader mnode and all its mnodes are ready.
+func (c *ClusterInfo) Healthy() bool {
+	leaders := 0
+	for _, mnode := range c.Mnodes {
+		if !gstr.Equal(mnode.Status, mnodeStatusReady) {
+			return false
+		}
+		if mnode.IsLeader() {
+			leaders++
+		}
+	}
+	return leaders == 1
+}
+
+// ShowMnodes retrieves and returns the management nodes of the cluster, by `SHOW MNODES`.
+func (d *Driver) ShowMnodes(ctx context.Context) ([]MnodeInfo, error) {
+	result, err := d.GetAll(withoutClause(ctx), `SHOW MNODES`)
+	if err != nil {
+		return nil, err
+	}
+	mnodes := make([]MnodeInfo, 0, len(result))
+	for _, record := range result {
+		mnodes = append(mnodes, MnodeInfo{
+			Id:         record["id"].Int(),
+			Endpoint:   record["endpoint"].String(),
+			Role:       record["role"].String(),
+			Status:     record["status"].String(),
+			CreateTime: record["create_time"].GTime(),
+			Raw:        record,
+		})
+	}
+	return mnodes, nil
+}
+
+// ClusterSummary retrieves and returns the summary of the cluster, along with its management nodes,
+// which can be used for verifying the mnode roles and cluster health, see ClusterInfo.Healthy.
+func (d *Driver) ClusterSummary(ctx context.Context) (*ClusterInfo, error) {
+	result, err := d.GetAll(withoutClause(ctx), `SHOW CLUSTER`)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, gerror.NewCode(gcode.CodeNotFound, `no cluster information retrieved`)
+	}
+	record := result[0]
+	info := &ClusterInfo{
+		Id:         record["id"].Int64(),
+		Name:       record["name"].String(),
+		Version:    record["version"].String(),
+		Uptime:     record["uptime"].Int64(),
+		CreateTime: record["create_time"].GTime(),
+		Raw:        record,
+	}
+	if info.Mnodes, err = d.ShowMnodes(ctx); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ShowLocalVariables retrieves and returns the configuration variables of the client, like charset, timezone
+// and debug flags, by `SHOW LOCAL VARIABLES`, which helps diagnosing the NCHAR or timestamp handling of the client.
+//
+// The statement is executed by the client library of the native connection, so it returns an error of code
+// gcode.CodeNotSupported if the statement is rejected as unsupported, like by the REST or websocket connections
+// that have no local client, see unsupportedStatementCodes. The other errors, like the network, authentication
+// and context deadline errors, are returned as they are for all the connectors.
+func (d *Driver) ShowLocalVariables(ctx context.Context) (map[string]string, error) {
+	result, err := d.GetAll(withoutClause(ctx), `SHOW LOCAL VARIABLES`)
+	if err != nil {
+		if !isUnsupportedStatement(err) {
+			return nil, err
+		}
+		return nil, gerror.WrapCode(gcode.CodeNotSupported, err, `SHOW LOCAL VARIABLES is not supported by the connection`)
+	}
+	variables := make(map[string]string, len(result))
+	for _, record := range result {
+		variables[record["name"].String()] = record["value"].String()
+	}
+	return variables, nil
+}
+
+// unsupportedStatementCodes are the TDengine error codes of the statements that the server or the client does not
+// support, in which 0x2600 is the syntax error of the parser of TDengine 3.0.
+var unsupportedStatementCodes = map[int32]struct{}{
+	taosErrors.COM_OPS_NOT_SUPPORT:   {},
+	taosErrors.TSC_INVALID_OPERATION: {},
+	taosErrors.TSC_SQL_SYNTAX_ERROR:  {},
+	0x2600:                           {},
+}
+
+// isUnsupportedStatement checks and returns whether `err` is the error of an unsupported statement.
+func isUnsupportedStatement(err error) bool {
+	code, ok := TaosCode(err)
+	if !ok {
+		return false
+	}
+	_, ok = unsupportedStatementCodes[code]
+	return ok
+}
+
+// ServerVersion retrieves and returns the version of the TDengine server by `SELECT SERVER_VERSION()`,
+// like: 3.0.4.0. The result is cached along with the table fields.
+func (d *Driver) ServerVersion(ctx context.Context) (version string, err error) {
+	v := tableFieldsMap.GetOrSetFuncLock(
+		d.cacheKey("server_version"),
+		func() interface{} {
+			var value gdb.Value
+			if value, err = d.GetValue(withoutDryRun(withoutClause(ctx)), `SELECT SERVER_VERSION()`); err != nil {
+				return nil
+			}
+			return value.String()
+		},
+	)
+	if v != nil {
+		version = v.(string)
+	}
+	return
+}
+
+// HealthCheck checks whether the server is reachable and serving on a master link by `SELECT SERVER_STATUS()`,
+// within the deadline of `ctx`, and returns an error of code gcode.CodeDbOperationError if not.
+// The broken connections are discarded by the connection pool, so that the next statement reconnects.
+// It is safe for concurrent use.
+func (d *Driver) HealthCheck(ctx context.Context) error {
+	link, err := d.MasterLink()
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeDbOperationError, err, `health check failed`)
+	}
+	result, err := d.DoSelect(withoutDryRun(withoutClause(ctx)), link, `SELECT SERVER_STATUS()`)
+	if err != nil {
+		return gerror.WrapCode(gcode.CodeDbOperationError, err, `health check failed`)
+	}
+	if len(result) == 0 || result.Array()[0].Int() != 1 {
+		return gerror.NewCode(gcode.CodeDbOperationError, `health check failed: server is not ready`)
+	}
+	return nil
+}
+
+// checkServerVersion checks whether the server version is `minVersion` or later, which is required by `feature`,
+// and returns an error of code gcode.CodeNotSupported if not.
+func (d *Driver) checkServerVersion(ctx context.Context, minVersion, feature string) error {
+	version, err := d.ServerVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if gstr.CompareVersion(version, minVersion) < 0 {
+		return gerror.NewCodef(
+			gcode.CodeNotSupported, `%s requires server version %s or later, but got %s`, feature, minVersion, version,
+		)
+	}
+	return nil
+}
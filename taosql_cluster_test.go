@@ -0,0 +1,251 @@
+package taosql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	taosErrors "github.com/taosdata/driver-go/v2/errors"
+)
+
+// mnodeRecords returns the response of `SHOW MNODES` of the mnodes of given roles and statuses.
+func mnodeRecords(mnodes ...[2]string) mockResponse {
+	response := mockRecords([]string{"id", "endpoint", "role", "status", "create_time"})
+	for i, mnode := range mnodes {
+		response.Rows = append(response.Rows, []interface{}{
+			int64(i + 1), fmt.Sprintf("dnode%d:6030", i+1), mnode[0], mnode[1], "2023-01-01 00:00:00",
+		})
+	}
+	return response
+}
+
+func TestClusterSummary(t *testing.T) {
+	cases := []struct {
+		name       string
+		mnodes     mockResponse
+		wantLeader string
+		healthy    bool
+	}{
+		{
+			name:       "healthy",
+			mnodes:     mnodeRecords([2]string{"follower", "ready"}, [2]string{"leader", "ready"}, [2]string{"follower", "ready"}),
+			wantLeader: "dnode2:6030",
+			healthy:    true,
+		},
+		{
+			name:   "no leader",
+			mnodes: mnodeRecords([2]string{"candidate", "ready"}, [2]string{"follower", "ready"}),
+		},
+		{
+			name:       "not ready",
+			mnodes:     mnodeRecords([2]string{"leader", "ready"}, [2]string{"follower", "creating"}),
+			wantLeader: "dnode1:6030",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+				switch q.Sql {
+				case `SHOW CLUSTER`:
+					return mockRecords(
+						[]string{"id", "name", "version", "uptime", "create_time"},
+						[]interface{}{int64(7), "cluster", "3.0.4.0", int64(3600), "2023-01-01 00:00:00"},
+					)
+				case `SHOW MNODES`:
+					return c.mnodes
+				}
+				return mockResponse{}
+			})
+			info, err := d.ClusterSummary(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Id != 7 || info.Version != "3.0.4.0" || info.Uptime != 3600 || info.Raw["name"].String() != "cluster" {
+				t.Fatalf("unexpected cluster info %+v", info)
+			}
+			if len(info.Mnodes) != len(c.mnodes.Rows) {
+				t.Fatalf("got %d mnodes, want %d", len(info.Mnodes), len(c.mnodes.Rows))
+			}
+			leader := ""
+			if mnode := info.Leader(); mnode != nil {
+				leader = mnode.Endpoint
+			}
+			if leader != c.wantLeader {
+				t.Fatalf("got leader %q, want %q", leader, c.wantLeader)
+			}
+			if info.Healthy() != c.healthy {
+				t.Fatalf("got healthy %v, want %v", info.Healthy(), c.healthy)
+			}
+		})
+	}
+}
+
+func TestClusterSummaryNotFound(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, func(mockQuery) mockResponse {
+		return mockRecords([]string{"id"})
+	})
+	_, err := d.ClusterSummary(context.Background())
+	checkCode(t, err, gcode.CodeNotFound)
+}
+
+func TestShowLocalVariables(t *testing.T) {
+	var (
+		networkErr     = &taosErrors.TaosError{Code: taosErrors.RPC_NETWORK_UNAVAIL, ErrStr: "Unable to establish connection"}
+		syntaxErr      = &taosErrors.TaosError{Code: 0x2600, ErrStr: "syntax error near 'local variables'"}
+		notSupportErr  = &taosErrors.TaosError{Code: taosErrors.COM_OPS_NOT_SUPPORT, ErrStr: "Operation not supported"}
+		connectorErr   = errors.New("unknown statement")
+		variablesReply = mockRecords(
+			[]string{"name", "value", "scope"},
+			[]interface{}{"charset", "UTF-8", "local"},
+			[]interface{}{"timezone", "Asia/Shanghai (CST, +0800)", "local"},
+			[]interface{}{"debugFlag", "131", "local"},
+		)
+	)
+	cases := []struct {
+		name      string
+		connector Connector
+		response  mockResponse
+		want      map[string]string
+		code      gcode.Code
+	}{
+		{
+			name:     "variables",
+			response: variablesReply,
+			want: map[string]string{
+				"charset": "UTF-8", "timezone": "Asia/Shanghai (CST, +0800)", "debugFlag": "131",
+			},
+		},
+		{name: "empty", response: mockRecords([]string{"name", "value"}), want: map[string]string{}},
+		{name: "unsupported statement", response: mockResponse{Err: syntaxErr}, code: gcode.CodeNotSupported},
+		{name: "unsupported operation", response: mockResponse{Err: notSupportErr}, code: gcode.CodeNotSupported},
+		{
+			name:      "rest connector",
+			connector: ConnectorREST,
+			response:  mockResponse{Err: syntaxErr},
+			code:      gcode.CodeNotSupported,
+		},
+		{
+			name:      "websocket connector",
+			connector: ConnectorWebSocket,
+			response:  mockResponse{Err: notSupportErr},
+			code:      gcode.CodeNotSupported,
+		},
+		{
+			name:      "rest network error",
+			connector: ConnectorREST,
+			response:  mockResponse{Err: networkErr},
+			code:      gcode.CodeDbOperationError,
+		},
+		{
+			name:      "websocket other error",
+			connector: ConnectorWebSocket,
+			response:  mockResponse{Err: connectorErr},
+			code:      gcode.CodeDbOperationError,
+		},
+		{
+			name:      "rest deadline",
+			connector: ConnectorREST,
+			response:  mockResponse{Err: context.DeadlineExceeded},
+			code:      gcode.CodeDbOperationError,
+		},
+		{name: "network error", response: mockResponse{Err: networkErr}, code: gcode.CodeDbOperationError},
+		{name: "other error", response: mockResponse{Err: connectorErr}, code: gcode.CodeDbOperationError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{Connector: c.connector}, func(mockQuery) mockResponse {
+				return c.response
+			})
+			variables, err := d.ShowLocalVariables(context.Background())
+			if c.code != nil {
+				if gerror.Code(err).Code() != c.code.Code() {
+					t.Fatalf("got error %v, want error of code %v", err, c.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != "SHOW LOCAL VARIABLES" {
+				t.Fatalf("got sqls %q", sqls)
+			}
+			if !reflect.DeepEqual(variables, c.want) {
+				t.Fatalf("got variables %v, want %v", variables, c.want)
+			}
+		})
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		healthy = mockRecords([]string{"server_status()"}, []interface{}{int32(1)})
+	)
+	expired, cancel := context.WithTimeout(ctx, -time.Second)
+	defer cancel()
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		response mockResponse
+		sqls     int
+		code     gcode.Code
+	}{
+		{name: "healthy", ctx: ctx, response: healthy, sqls: 1},
+		{name: "query failed", ctx: ctx, response: mockResponse{Err: errors.New("connection refused")}, sqls: 1, code: gcode.CodeDbOperationError},
+		{
+			name:     "not ready",
+			ctx:      ctx,
+			response: mockRecords([]string{"server_status()"}, []interface{}{int32(0)}),
+			sqls:     1,
+			code:     gcode.CodeDbOperationError,
+		},
+		{name: "no result", ctx: ctx, response: mockRecords([]string{"server_status()"}), sqls: 1, code: gcode.CodeDbOperationError},
+		{name: "deadline exceeded", ctx: expired, response: healthy, code: gcode.CodeDbOperationError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse { return c.response })
+			checkCode(t, d.HealthCheck(c.ctx), c.code)
+			sqls := server.Sqls()
+			if len(sqls) != c.sqls {
+				t.Fatalf("got sqls %q, want %d statements", sqls, c.sqls)
+			}
+			if c.sqls > 0 && sqls[0] != "SELECT SERVER_STATUS()" {
+				t.Fatalf("got sql %q", sqls[0])
+			}
+		})
+	}
+}
+
+func TestHealthCheckConcurrent(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse {
+		return mockRecords([]string{"server_status()"}, []interface{}{int32(1)})
+	})
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, 10)
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- d.HealthCheck(context.Background())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(server.Sqls()); n != 10 {
+		t.Fatalf("got %d statements, want 10", n)
+	}
+}
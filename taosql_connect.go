@@ -0,0 +1,121 @@
+package taosql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	taosErrors "github.com/taosdata/driver-go/v2/errors"
+)
+
+// Connector is the type of the connector for connecting to the server, see Option.Connector.
+type Connector string
+
+const (
+	// ConnectorNative connects by the native client library with driver "taosSql",
+	// which is registered by this package. Its default port is 6030 of taosd.
+	ConnectorNative Connector = ""
+	// ConnectorREST connects by the REST API of taosAdapter with driver "taosRestful". The driver is not provided
+	// by github.com/taosdata/driver-go/v2 v2.0.1 that this package depends on, so it should be registered by the
+	// application, like by importing package github.com/taosdata/driver-go/v3/taosRestful, which adds driver-go v3
+	// to the dependencies of the application. Its default port is 6041 of taosAdapter.
+	ConnectorREST Connector = "rest"
+	// ConnectorWebSocket connects by the websocket API of taosAdapter with driver "taosWS", which should be
+	// registered by the application like ConnectorREST, like by importing package
+	// github.com/taosdata/driver-go/v3/taosWS. Its default port is 6041 of taosAdapter.
+	ConnectorWebSocket Connector = "websocket"
+)
+
+const (
+	defaultNativePort  = "6030" // Default port of taosd for the native connector.
+	defaultAdapterPort = "6041" // Default port of taosAdapter for the REST and websocket connectors.
+)
+
+// driverName returns the name of the underlying sql driver of the connector.
+func (c Connector) driverName() string {
+	switch c {
+	case ConnectorREST:
+		return "taosRestful"
+	case ConnectorWebSocket:
+		return "taosWS"
+	default:
+		return "taosSql"
+	}
+}
+
+// defaultPort returns the default port of the server that the connector connects to.
+func (c Connector) defaultPort() string {
+	switch c {
+	case ConnectorREST, ConnectorWebSocket:
+		return defaultAdapterPort
+	default:
+		return defaultNativePort
+	}
+}
+
+// buildSource builds the data source name of the connector from the discrete fields of `config` with password
+// `pass`, like: user:pass@tcp(host:port)/db for the native connector, user:pass@ws(host:port)/db for the websocket
+// connector and user:pass@http(host:port)/db for the REST connector.
+// The port is the default port of the connector if it's not configured, see Connector.
+//
+// The timezone is passed as the `loc` parameter, which is the location of the timestamps read by the driver.
+func (d *Driver) buildSource(config *gdb.ConfigNode, pass string) string {
+	protocol := "tcp"
+	switch d.option.Connector {
+	case ConnectorREST:
+		protocol = "http"
+	case ConnectorWebSocket:
+		protocol = "ws"
+	}
+	port := config.Port
+	if port == "" {
+		port = d.option.Connector.defaultPort()
+	}
+	source := fmt.Sprintf(
+		"%s:%s@%s(%s:%s)/%s",
+		config.User, pass, protocol, config.Host, port, config.Name,
+	)
+	if config.Timezone != "" {
+		source = fmt.Sprintf("%s?loc=%s", source, url.QueryEscape(config.Timezone))
+	}
+	return source
+}
+
+// connectRetryableCodes are the TDengine error codes of connection-establishment failures,
+// which are usually transient when the server is not ready yet.
+var connectRetryableCodes = map[int32]struct{}{
+	taosErrors.RPC_NETWORK_UNAVAIL: {},
+	taosErrors.RPC_NOT_READY:       {},
+	taosErrors.APP_NOT_READY:       {},
+	taosErrors.RPC_FQDN_ERROR:      {},
+}
+
+// pingWithRetry establishes the connection of `db`, retrying with exponential backoff on
+// connection-establishment errors as configured by Option.ConnectRetryCount and Option.ConnectRetryInterval.
+// Any other error, like authentication failure, is returned immediately.
+func (d *Driver) pingWithRetry(db *sql.DB) (err error) {
+	interval := d.option.ConnectRetryInterval
+	if interval <= 0 {
+		interval = defaultConnectRetryInterval
+	}
+	for i := 0; ; i++ {
+		if err = db.Ping(); err == nil || i >= d.option.ConnectRetryCount || !isConnectError(err) {
+			return
+		}
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+// isConnectError checks and returns whether `err` is a connection-establishment error.
+func isConnectError(err error) bool {
+	var taosErr *taosErrors.TaosError
+	if errors.As(err, &taosErr) {
+		_, ok := connectRetryableCodes[taosErr.Code]
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/text/gstr"
+	taosErrors "github.com/taosdata/driver-go/v2/errors"
+)
+
+func TestIsConnectError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "network unavailable", err: &taosErrors.TaosError{Code: taosErrors.RPC_NETWORK_UNAVAIL}, want: true},
+		{name: "not ready", err: &taosErrors.TaosError{Code: taosErrors.APP_NOT_READY}, want: true},
+		{name: "wrapped", err: fmt.Errorf("open: %w", &taosErrors.TaosError{Code: taosErrors.RPC_FQDN_ERROR}), want: true},
+		{name: "other code", err: &taosErrors.TaosError{Code: taosErrors.TSC_INVALID_OPERATION}, want: false},
+		{name: "plain error", err: errors.New("Unable to establish connection"), want: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isConnectError(c.err); got != c.want {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// failingConnector fails the first `failures` connections with `err`.
+type failingConnector struct {
+	failures int
+	err      error
+	attempts int
+}
+
+func (c *failingConnector) Connect(context.Context) (driver.Conn, error) {
+	c.attempts++
+	if c.attempts <= c.failures {
+		return nil, c.err
+	}
+	return &mockConn{server: &mockServer{}}, nil
+}
+
+func (c *failingConnector) Driver() driver.Driver { return mockSqlDriver{} }
+
+func TestPingWithRetry(t *testing.T) {
+	var (
+		connectErr = &taosErrors.TaosError{Code: taosErrors.RPC_NETWORK_UNAVAIL, ErrStr: "Unable to establish connection"}
+		authErr    = &taosErrors.TaosError{Code: taosErrors.RPC_AUTH_FAILURE, ErrStr: "Authentication failure"}
+	)
+	cases := []struct {
+		name         string
+		retryCount   int
+		failures     int
+		err          error
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "no failure", retryCount: 3, failures: 0, err: connectErr, wantAttempts: 1},
+		{name: "recovered", retryCount: 3, failures: 2, err: connectErr, wantAttempts: 3},
+		{name: "exhausted", retryCount: 2, failures: 5, err: connectErr, wantErr: true, wantAttempts: 3},
+		{name: "retry disabled", retryCount: 0, failures: 1, err: connectErr, wantErr: true, wantAttempts: 1},
+		{name: "not retryable", retryCount: 3, failures: 1, err: authErr, wantErr: true, wantAttempts: 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				connector = &failingConnector{failures: c.failures, err: c.err}
+				db        = sql.OpenDB(connector)
+				d         = &Driver{option: Option{ConnectRetryCount: c.retryCount, ConnectRetryInterval: time.Millisecond}}
+			)
+			defer db.Close()
+			err := d.pingWithRetry(db)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, want error %v", err, c.wantErr)
+			}
+			if connector.attempts != c.wantAttempts {
+				t.Fatalf("got %d attempts, want %d", connector.attempts, c.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestBuildSource(t *testing.T) {
+	node := gdb.ConfigNode{User: "root", Pass: "taosdata", Host: "127.0.0.1", Port: "6030", Name: "power"}
+	cases := []struct {
+		name      string
+		connector Connector
+		timezone  string
+		noPort    bool // Whether the port is not configured, for the default port of the connector.
+		want      string
+	}{
+		{name: "native", want: "root:taosdata@tcp(127.0.0.1:6030)/power"},
+		{name: "websocket", connector: ConnectorWebSocket, want: "root:taosdata@ws(127.0.0.1:6030)/power"},
+		{name: "rest", connector: ConnectorREST, want: "root:taosdata@http(127.0.0.1:6030)/power"},
+		{name: "timezone", timezone: "Asia/Shanghai", want: "root:taosdata@tcp(127.0.0.1:6030)/power?loc=Asia%2FShanghai"},
+		{name: "utc", timezone: "UTC", want: "root:taosdata@tcp(127.0.0.1:6030)/power?loc=UTC"},
+		{name: "native default port", noPort: true, want: "root:taosdata@tcp(127.0.0.1:6030)/power"},
+		{name: "websocket default port", connector: ConnectorWebSocket, noPort: true, want: "root:taosdata@ws(127.0.0.1:6041)/power"},
+		{name: "rest default port", connector: ConnectorREST, noPort: true, want: "root:taosdata@http(127.0.0.1:6041)/power"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				d      = &Driver{option: Option{Connector: c.connector}}
+				config = node
+			)
+			config.Timezone = c.timezone
+			if c.noPort {
+				config.Port = ""
+			}
+			got := d.buildSource(&config, config.Pass)
+			if got != c.want {
+				t.Fatalf("got source %q, want %q", got, c.want)
+			}
+			if c.timezone == "" {
+				return
+			}
+			// The timezone is the query parameter of the source, which is decoded by the underlying driver.
+			query, err := url.ParseQuery(got[gstr.Pos(got, "?")+1:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loc := query.Get("loc"); loc != c.timezone {
+				t.Fatalf("got loc %q, want %q", loc, c.timezone)
+			}
+			if _, err = time.LoadLocation(query.Get("loc")); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// recordingSqlDriver is the sql driver that records the data source names it opens, standing in for the
+// underlying drivers of the REST and websocket connectors.
+type recordingSqlDriver struct {
+	mu      sync.Mutex
+	sources []string
+}
+
+func (r *recordingSqlDriver) Open(name string) (driver.Conn, error) {
+	r.mu.Lock()
+	r.sources = append(r.sources, name)
+	r.mu.Unlock()
+	return nil, errors.New("recording driver does not connect")
+}
+
+var connectorDrivers = map[Connector]*recordingSqlDriver{
+	ConnectorREST:      {},
+	ConnectorWebSocket: {},
+}
+
+func init() {
+	for connector, r := range connectorDrivers {
+		sql.Register(connector.driverName(), r)
+	}
+}
+
+func TestOpenConnector(t *testing.T) {
+	cases := []struct {
+		name       string
+		connector  Connector
+		driverName string
+		want       string
+	}{
+		{name: "websocket", connector: ConnectorWebSocket, driverName: "taosWS", want: "root:taosdata@ws(127.0.0.1:6041)/power"},
+		{name: "rest", connector: ConnectorREST, driverName: "taosRestful", want: "root:taosdata@http(127.0.0.1:6041)/power"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if name := c.connector.driverName(); name != c.driverName {
+				t.Fatalf("got driver name %q, want %q", name, c.driverName)
+			}
+			d := &Driver{option: Option{Connector: c.connector}}
+			db, err := d.Open(&gdb.ConfigNode{User: "root", Pass: "taosdata", Host: "127.0.0.1", Port: "6041", Name: "power"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			r := connectorDrivers[c.connector]
+			if db.Driver() != r {
+				t.Fatalf("got driver %T, want the driver of %q", db.Driver(), c.driverName)
+			}
+			_ = db.Ping()
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if len(r.sources) == 0 || r.sources[len(r.sources)-1] != c.want {
+				t.Fatalf("got sources %q, want %q", r.sources, c.want)
+			}
+		})
+	}
+	if name := ConnectorNative.driverName(); name != "taosSql" {
+		t.Fatalf("got driver name %q of native connector, want %q", name, "taosSql")
+	}
+}
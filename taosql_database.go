@@ -0,0 +1,165 @@
+package taosql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+var (
+	// builtinDatabases is the set of the built-in databases, which are excluded from function Databases.
+	builtinDatabases = map[string]struct{}{
+		"information_schema": {},
+		"performance_schema": {},
+	}
+)
+
+// UpdateMode is the behavior of the database for the inserted rows with duplicate timestamp,
+// which is the UPDATE option of the database.
+type UpdateMode int
+
+const (
+	UpdateModeKeepFirst UpdateMode = 0 // Keep the first row, the later rows with duplicate timestamp are discarded.
+	UpdateModeOverwrite UpdateMode = 1 // Overwrite the whole row with the later one, the absent columns are set to NULL.
+	UpdateModePartial   UpdateMode = 2 // Overwrite only the columns that are not NULL in the later row.
+)
+
+// String returns the description of the update mode.
+func (m UpdateMode) String() string {
+	switch m {
+	case UpdateModeKeepFirst:
+		return "KEEP_FIRST"
+	case UpdateModeOverwrite:
+		return "OVERWRITE"
+	case UpdateModePartial:
+		return "PARTIAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// UpdateMode retrieves and returns the update mode of current schema, which is the `update` column of
+// `SHOW DATABASES`. It determines how an insert behaves if a row with the same timestamp already exists
+// in the table, see UpdateModeKeepFirst, UpdateModeOverwrite and UpdateModePartial.
+//
+// Note that the update mode is specified at database creation, and the server does not support
+// controlling it per write. TDengine 3.x has no UPDATE option and always overwrites the existing rows,
+// for which it returns UpdateModeOverwrite.
+func (d *Driver) UpdateMode(ctx context.Context, schema ...string) (UpdateMode, error) {
+	record, err := d.showDatabase(ctx, schema...)
+	if err != nil {
+		return UpdateModeOverwrite, err
+	}
+	value, ok := record["update"]
+	if !ok {
+		return UpdateModeOverwrite, nil
+	}
+	return UpdateMode(value.Int()), nil
+}
+
+// GetKeep retrieves and returns the data retention of database `db`, which is the `keep` column
+// of `SHOW DATABASES`, like: 3650d,3650d,3650d. It uses current schema if `db` is empty.
+func (d *Driver) GetKeep(ctx context.Context, db string) (string, error) {
+	record, err := d.showDatabase(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	if value, ok := record["keep"]; ok {
+		return value.String(), nil
+	}
+	// The column is named like `keep0,keep1,keep2` in TDengine 2.x.
+	for name, value := range record {
+		if gstr.HasPrefix(name, "keep") {
+			return value.String(), nil
+		}
+	}
+	return "", gerror.NewCodef(gcode.CodeNotFound, `keep of database "%s" not found`, db)
+}
+
+// SetKeep alters the data retention of database `db` to `keep` by `ALTER DATABASE db KEEP keep`,
+// in which `keep` is one to three comma separated durations in days or with unit m/h/d,
+// like: 365, 365d, 8760h or 30d,180d,365d. It requires Option.AdminMode.
+func (d *Driver) SetKeep(ctx context.Context, db, keep string) error {
+	if err := d.checkAdmin(`ALTER DATABASE KEEP`, db); err != nil {
+		return err
+	}
+	if !gregex.IsMatchString(`^\d+[mhd]?(,\d+[mhd]?){0,2}$`, keep) {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid keep "%s"`, keep)
+	}
+	_, err := d.Exec(withoutClause(ctx), fmt.Sprintf(`ALTER DATABASE %s KEEP %s`, d.QuoteWord(db), keep))
+	return err
+}
+
+// Precision retrieves and returns the timestamp precision of current schema, or of the optional `schema`,
+// which is one of: ms, us, ns. The timestamps are read as time.Time at the precision by the connector,
+// but the epoch integers, like the results of CAST(ts AS BIGINT), are counts of the precision.
+// The result is cached along with the table fields.
+func (d *Driver) Precision(ctx context.Context, schema ...string) (string, error) {
+	useSchema := d.GetSchema()
+	if len(schema) > 0 && schema[0] != "" {
+		useSchema = schema[0]
+	}
+	return d.precision(withoutDryRun(withoutClause(ctx)), useSchema)
+}
+
+// precision retrieves and returns the timestamp precision of database `schema`, which is the `precision`
+// column of `SHOW DATABASES`, like: ms, us, ns. The result is cached along with the table fields.
+func (d *Driver) precision(ctx context.Context, schema string) (precision string, err error) {
+	v := tableFieldsMap.GetOrSetFuncLock(
+		d.cacheKey("precision", schema),
+		func() interface{} {
+			var record gdb.Record
+			if record, err = d.showDatabase(ctx, schema); err != nil {
+				return nil
+			}
+			return record["precision"].String()
+		},
+	)
+	if v != nil {
+		precision = v.(string)
+	}
+	return
+}
+
+// Databases retrieves and returns the names of the databases visible to the connection in ascending order,
+// excluding the built-in databases information_schema and performance_schema of TDengine 3.x.
+func (d *Driver) Databases(ctx context.Context) ([]string, error) {
+	result, err := d.GetAll(withoutDryRun(withoutClause(ctx)), `SHOW DATABASES`)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(result))
+	for _, record := range result {
+		name := record["name"].String()
+		if _, ok := builtinDatabases[gstr.ToLower(name)]; ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// showDatabase retrieves and returns the record of current schema in `SHOW DATABASES`.
+func (d *Driver) showDatabase(ctx context.Context, schema ...string) (gdb.Record, error) {
+	useSchema := d.GetSchema()
+	if len(schema) > 0 && schema[0] != "" {
+		useSchema = schema[0]
+	}
+	result, err := d.GetAll(withoutClause(ctx), `SHOW DATABASES`)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range result {
+		if record["name"].String() == useSchema {
+			return record, nil
+		}
+	}
+	return nil, gerror.NewCodef(gcode.CodeNotFound, `database "%s" not found`, useSchema)
+}
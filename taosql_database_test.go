@@ -0,0 +1,201 @@
+package taosql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+func TestUpdateMode(t *testing.T) {
+	cases := []struct {
+		name      string
+		databases mockResponse
+		schema    string
+		want      UpdateMode
+		code      gcode.Code
+	}{
+		{name: "keep first", databases: mockDatabases, want: UpdateModeKeepFirst},
+		{name: "overwrite", databases: mockDatabases, schema: "archive", want: UpdateModeOverwrite},
+		{
+			name: "partial",
+			databases: mockRecords(
+				[]string{"name", "update"}, []interface{}{"power", int64(2)},
+			),
+			want: UpdateModePartial,
+		},
+		{
+			// TDengine 3.x has no UPDATE option and always overwrites the rows with duplicate timestamp.
+			name:      "no update option",
+			databases: mockRecords([]string{"name", "precision"}, []interface{}{"power", "ms"}),
+			want:      UpdateModeOverwrite,
+		},
+		{name: "database not found", databases: mockDatabases, schema: "missing", want: UpdateModeOverwrite, code: gcode.CodeNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, Option{}, func(mockQuery) mockResponse { return c.databases })
+			mode, err := d.UpdateMode(context.Background(), c.schema)
+			checkCode(t, err, c.code)
+			if mode != c.want {
+				t.Fatalf("got %s, want %s", mode, c.want)
+			}
+		})
+	}
+}
+
+func TestKeep(t *testing.T) {
+	var keep = "3650d,3650d,3650d"
+	d, server := newMockDriver(t, Option{AdminMode: true}, func(q mockQuery) mockResponse {
+		if q.Sql == "ALTER DATABASE `power` KEEP 365d,365d,365d" {
+			keep = "365d,365d,365d"
+		}
+		return mockRecords(
+			[]string{"name", "keep"},
+			[]interface{}{"power", keep},
+		)
+	})
+	ctx := context.Background()
+	cases := []struct {
+		name string
+		keep string
+		want string
+		code gcode.Code
+	}{
+		{name: "read", want: "3650d,3650d,3650d"},
+		{name: "write", keep: "365d,365d,365d", want: "365d,365d,365d"},
+		{name: "invalid", keep: "1y", want: "365d,365d,365d", code: gcode.CodeInvalidParameter},
+		{name: "too many", keep: "1d,2d,3d,4d", want: "365d,365d,365d", code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.keep != "" {
+				checkCode(t, d.SetKeep(ctx, "power", c.keep), c.code)
+			}
+			got, err := d.GetKeep(ctx, "power")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Fatalf("got keep %q, want %q", got, c.want)
+			}
+		})
+	}
+	for _, sql := range server.Sqls() {
+		if sql != "SHOW DATABASES" && sql != "ALTER DATABASE `power` KEEP 365d,365d,365d" {
+			t.Fatalf("unexpected statement %q", sql)
+		}
+	}
+}
+
+func TestGetKeepLegacyColumn(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, func(mockQuery) mockResponse {
+		return mockRecords([]string{"name", "keep0,keep1,keep2"}, []interface{}{"power", "3650,3650,3650"})
+	})
+	keep, err := d.GetKeep(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keep != "3650,3650,3650" {
+		t.Fatalf("got keep %q", keep)
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	ctx := context.Background()
+	cases := []struct {
+		name   string
+		schema string
+		want   string
+		code   gcode.Code
+	}{
+		{name: "current schema", want: "ms"},
+		{name: "other schema", schema: "archive", want: "us"},
+		{name: "cached", schema: "archive", want: "us"},
+		{name: "database not found", schema: "missing", code: gcode.CodeNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := d.Precision(ctx, c.schema)
+			checkCode(t, err, c.code)
+			if got != c.want {
+				t.Fatalf("got precision %q, want %q", got, c.want)
+			}
+		})
+	}
+	if sqls := server.Sqls(); len(sqls) != 3 {
+		t.Fatalf("got statements %v, want one SHOW DATABASES per schema", sqls)
+	}
+}
+
+func TestPrecisionConversion(t *testing.T) {
+	var (
+		epoch = time.Date(2023, 1, 1, 0, 0, 0, 123456000, time.UTC)
+		cases = []struct {
+			name   string
+			schema string
+			value  int64
+			want   time.Time
+		}{
+			{name: "ms", schema: "power", value: epoch.UnixNano() / int64(time.Millisecond), want: epoch.Truncate(time.Millisecond)},
+			{name: "us", schema: "archive", value: epoch.UnixNano() / int64(time.Microsecond), want: epoch},
+		}
+	)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockSchemaCluster(t, c.schema, Option{}, metersHandler(func(mockQuery) mockResponse {
+				return mockRecords([]string{"_wstart", "cnt"}, []interface{}{c.value, int64(1)})
+			}))
+			result, err := d.GetAll(context.Background(), "SELECT _wstart, COUNT(*) AS cnt FROM d1001 INTERVAL(1s)")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result[0]["_wstart"].GTime(); got == nil || !got.Time.Equal(c.want) {
+				t.Fatalf("got _wstart %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDatabases(t *testing.T) {
+	cases := []struct {
+		name      string
+		databases mockResponse
+		want      []string
+	}{
+		{
+			name: "user databases in order",
+			databases: mockRecords(
+				[]string{"name", "precision"},
+				[]interface{}{"power", "ms"},
+				[]interface{}{"information_schema", "ms"},
+				[]interface{}{"archive", "us"},
+				[]interface{}{"PERFORMANCE_SCHEMA", "ms"},
+			),
+			want: []string{"archive", "power"},
+		},
+		{
+			name:      "built-in only",
+			databases: mockRecords([]string{"name"}, []interface{}{"information_schema"}, []interface{}{"performance_schema"}),
+			want:      []string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse { return c.databases })
+			got, err := d.Databases(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got databases %q, want %q", got, c.want)
+			}
+			if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != "SHOW DATABASES" {
+				t.Fatalf("got sqls %q", sqls)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+const (
+	// RowsAffectedUnknown is the sentinel affected rows count of a DELETE statement,
+	// which means the server does not report the number of deleted rows.
+	RowsAffectedUnknown int64 = -1
+
+	errMsgDeletedRowsUnknown = `the number of deleted rows is not reported by the server`
+)
+
+// deleteResult is the result of a DELETE statement.
+type deleteResult struct {
+	sql.Result
+}
+
+// DoUpdate returns an error of code gcode.CodeNotSupported, as TDengine does not support UPDATE statements.
+// The rows are updated by inserting the rows of the same timestamps, see UpdateMode.
+func (d *Driver) DoUpdate(ctx context.Context, link gdb.Link, table string, data interface{}, condition string, args ...interface{}) (result sql.Result, err error) {
+	return nil, gerror.NewCode(
+		gcode.CodeNotSupported,
+		`Update operation is not supported by taossql driver, insert the rows of the same timestamps instead`,
+	)
+}
+
+// DoDelete does "DELETE FROM ... " statement for the table.
+//
+// TDengine supports only the predicates on the primary timestamp for DELETE statements, like:
+// WHERE ts >= ? AND ts < ?, and ts BETWEEN ? AND ?. It returns an error of code gcode.CodeNotSupported for the
+// other conditions before sending the statement, for which DeleteByTime is recommended.
+//
+// The RowsAffected of the returned result is the number of deleted rows reported by TDengine.
+// If the server does not report it, RowsAffected returns RowsAffectedUnknown along with an error
+// of code gcode.CodeNotSupported.
+func (d *Driver) DoDelete(ctx context.Context, link gdb.Link, table string, condition string, args ...interface{}) (result sql.Result, err error) {
+	tsColumn, err := d.primaryTsColumn(withoutDryRun(withoutClause(ctx)), table)
+	if err != nil {
+		return nil, err
+	}
+	if !isTimeRangeCondition(condition, tsColumn) {
+		return nil, gerror.NewCodef(
+			gcode.CodeNotSupported,
+			`Delete operation supports only the conditions on primary timestamp "%s" in taossql driver, `+
+				`use DeleteByTime instead`,
+			tsColumn,
+		)
+	}
+	if result, err = d.Core.DoDelete(ctx, link, table, condition, args...); err != nil {
+		return result, err
+	}
+	return &deleteResult{Result: result}, nil
+}
+
+// RowsAffected returns the number of rows deleted by the DELETE statement.
+func (r *deleteResult) RowsAffected() (int64, error) {
+	var (
+		n   int64
+		err error
+	)
+	if r.Result != nil {
+		if n, err = r.Result.RowsAffected(); err == nil && n >= 0 {
+			return n, nil
+		}
+	}
+	if err != nil {
+		return RowsAffectedUnknown, gerror.WrapCode(gcode.CodeNotSupported, err, errMsgDeletedRowsUnknown)
+	}
+	return RowsAffectedUnknown, gerror.NewCode(gcode.CodeNotSupported, errMsgDeletedRowsUnknown)
+}
+
+// DeleteByTime deletes the rows of `table` within time range [start, end) of the primary timestamp,
+// which is the only predicate that TDengine supports for DELETE statements, like:
+// DELETE FROM meters WHERE ts >= 1672502400000 AND ts < 1672588800000.
+// The bounds are rendered as epoch integers at the precision of the database of `table`, which is its qualifier
+// if it's qualified, like `archive` of `archive.meters`, or else current schema.
+//
+// It returns an error of code gcode.CodeInvalidParameter if either bound is zero or the range is empty,
+// which avoids deleting all data of the table by mistake. See DoDelete for the RowsAffected of the result.
+func (d *Driver) DeleteByTime(ctx context.Context, table string, start, end time.Time) (sql.Result, error) {
+	if start.IsZero() || end.IsZero() {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, `both start and end are required for deleting by time`)
+	}
+	if !end.After(start) {
+		return nil, gerror.NewCodef(
+			gcode.CodeInvalidParameter, `empty time range [%s, %s) for deleting by time`, start, end,
+		)
+	}
+	ctx = withoutClause(ctx)
+	precision, err := d.Precision(ctx, d.statementSchema(`FROM `+table))
+	if err != nil {
+		return nil, err
+	}
+	tsColumn, err := d.primaryTsColumn(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	result, err := d.Exec(ctx, fmt.Sprintf(
+		`DELETE FROM %s WHERE %s >= %d AND %s < %d`,
+		d.QuotePrefixTableName(table),
+		d.QuoteWord(tsColumn), timeToEpoch(start, precision),
+		d.QuoteWord(tsColumn), timeToEpoch(end, precision),
+	))
+	if err != nil {
+		return result, err
+	}
+	return &deleteResult{Result: result}, nil
+}
+
+// isTimeRangeCondition checks whether WHERE condition `condition` consists of only the comparisons on
+// timestamp column `tsColumn` joined by AND, like: WHERE (`ts`>=?) AND (`ts`<?), or WHERE `ts` BETWEEN ? AND ?.
+func isTimeRangeCondition(condition, tsColumn string) bool {
+	match, _ := gregex.MatchString(`^(?is)\s*WHERE\s+(.+)$`, condition)
+	if len(match) == 0 || topLevelIndex(" "+match[1], clauseInsertKeywords...) >= 0 {
+		return false
+	}
+	var (
+		pattern    = "^(?i)`?" + regexp.QuoteMeta(tsColumn) + "`?\\s*(>=|<=|>|<|=|\\sBETWEEN\\s)"
+		predicates = splitTopLevel(match[1], " AND ")
+	)
+	for i := 0; i < len(predicates); i++ {
+		predicate := trimParentheses(predicates[i])
+		if topLevelIndex(predicate, " OR ") >= 0 {
+			return false
+		}
+		operator, _ := gregex.MatchString(pattern, predicate)
+		if len(operator) == 0 {
+			return false
+		}
+		// The upper bound of BETWEEN is split as the next predicate.
+		if gstr.Equal(gstr.Trim(operator[1]), "BETWEEN") {
+			if i++; i >= len(predicates) {
+				return false
+			}
+		}
+	}
+	return true
+}
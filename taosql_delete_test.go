@@ -0,0 +1,196 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+func TestDeleteRowsAffected(t *testing.T) {
+	var (
+		start = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		end   = start.Add(time.Hour)
+	)
+	cases := []struct {
+		name     string
+		affected int64
+		want     int64
+		code     gcode.Code
+	}{
+		{name: "reported", affected: 3, want: 3},
+		{name: "none deleted", affected: 0, want: 0},
+		{name: "not reported", affected: -1, want: RowsAffectedUnknown, code: gcode.CodeNotSupported},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+				switch {
+				case gstr.HasPrefix(q.Sql, "INSERT"):
+					return mockResponse{Affected: 5}
+				case gstr.HasPrefix(q.Sql, "DELETE"):
+					return mockResponse{Affected: c.affected}
+				}
+				return mockResponse{}
+			}))
+			ctx := context.Background()
+			var list []map[string]interface{}
+			for i := 0; i < 5; i++ {
+				list = append(list, map[string]interface{}{"ts": start.Add(time.Duration(i) * 20 * time.Minute), "current": 10.5})
+			}
+			if _, err := d.Model("d1001").Ctx(ctx).Data(list).Insert(); err != nil {
+				t.Fatal(err)
+			}
+			result, err := d.Model("d1001").Ctx(ctx).Where("ts >= ? AND ts < ?", start, end).Delete()
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, err := result.RowsAffected()
+			checkCode(t, err, c.code)
+			if n != c.want {
+				t.Fatalf("got %d rows affected, want %d", n, c.want)
+			}
+			sqls := server.Sqls()
+			if want := "DELETE FROM `d1001` WHERE ts >= ? AND ts < ?"; sqls[len(sqls)-1] != want {
+				t.Fatalf("got sql %q, want %q", sqls[len(sqls)-1], want)
+			}
+		})
+	}
+}
+
+func TestDeleteUnsupportedCondition(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	_, err := d.Model("d1001").Where("current > ?", 10).Delete()
+	checkCode(t, err, gcode.CodeNotSupported)
+	for _, sql := range server.Sqls() {
+		if gstr.HasPrefix(sql, "DELETE") {
+			t.Fatalf("unexpected statement %q", sql)
+		}
+	}
+}
+
+func TestDeleteByTimePrecision(t *testing.T) {
+	var (
+		start = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		end   = start.Add(time.Hour)
+	)
+	cases := []struct {
+		name  string
+		table string
+		want  string
+	}{
+		{name: "current schema", table: "d1001", want: "DELETE FROM `d1001` WHERE `ts` >= 1672531200000 AND `ts` < 1672534800000"},
+		{name: "qualified", table: "archive.d1001", want: "DELETE FROM `archive`.`d1001` WHERE `ts` >= 1672531200000000 AND `ts` < 1672534800000000"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(nil))
+			if _, err := d.DeleteByTime(context.Background(), c.table, start, end); err != nil {
+				t.Fatal(err)
+			}
+			sqls := server.Sqls()
+			if sqls[len(sqls)-1] != c.want {
+				t.Fatalf("got sql %q, want %q", sqls[len(sqls)-1], c.want)
+			}
+		})
+	}
+}
+
+func TestDeleteByTimeEmptyRange(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{name: "zero start", end: start},
+		{name: "zero end", start: start},
+		{name: "zero range", start: start, end: start},
+		{name: "reversed range", start: start, end: start.Add(-time.Hour)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(nil))
+			_, err := d.DeleteByTime(context.Background(), "d1001", c.start, c.end)
+			checkCode(t, err, gcode.CodeInvalidParameter)
+			if sqls := server.Sqls(); len(sqls) != 0 {
+				t.Fatalf("got sqls %q, want none for empty range", sqls)
+			}
+		})
+	}
+}
+
+func TestUpdateNotSupported(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	_, err := d.Model("d1001").Data("current", 10.5).Where("ts", time.Now()).Update()
+	checkCode(t, err, gcode.CodeNotSupported)
+	for _, sql := range server.Sqls() {
+		if gstr.HasPrefix(sql, "UPDATE") {
+			t.Fatalf("unexpected statement %q", sql)
+		}
+	}
+}
+
+func TestDeleteTimeRange(t *testing.T) {
+	var (
+		start = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		end   = start.Add(time.Hour)
+	)
+	cases := []struct {
+		name  string
+		where string
+		args  []interface{}
+		want  string
+		code  gcode.Code
+	}{
+		{name: "range", where: "ts >= ? AND ts < ?", args: []interface{}{start, end}, want: "DELETE FROM `d1001` WHERE ts >= ? AND ts < ?"},
+		{name: "between", where: "ts BETWEEN ? AND ?", args: []interface{}{start, end}, want: "DELETE FROM `d1001` WHERE ts BETWEEN ? AND ?"},
+		{name: "quoted", where: "`ts` = ?", args: []interface{}{start}, want: "DELETE FROM `d1001` WHERE `ts` = ?"},
+		{name: "other column", where: "ts >= ? AND current > ?", args: []interface{}{start, 10}, code: gcode.CodeNotSupported},
+		{name: "or", where: "ts < ? OR ts > ?", args: []interface{}{start, end}, code: gcode.CodeNotSupported},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(nil))
+			_, err := d.Model("d1001").Where(c.where, c.args...).Delete()
+			checkCode(t, err, c.code)
+			sqls := server.Sqls()
+			if c.code != nil {
+				for _, sql := range sqls {
+					if gstr.HasPrefix(sql, "DELETE") {
+						t.Fatalf("unexpected statement %q", sql)
+					}
+				}
+				return
+			}
+			if sqls[len(sqls)-1] != c.want {
+				t.Fatalf("got sql %q, want %q", sqls[len(sqls)-1], c.want)
+			}
+		})
+	}
+}
+
+func TestIsTimeRangeCondition(t *testing.T) {
+	cases := []struct {
+		condition string
+		want      bool
+	}{
+		{" WHERE ts >= ? AND ts < ?", true},
+		{" WHERE (`ts`>=?) AND (`ts`<?)", true},
+		{" WHERE ts BETWEEN ? AND ?", true},
+		{" where TS = ?", true},
+		{" WHERE ts BETWEEN ?", false},
+		{" WHERE ts >= ? AND current > ?", false},
+		{" WHERE ts < ? OR ts > ?", false},
+		{" WHERE (ts < ? OR ts > ?)", false},
+		{" WHERE ts2 > ?", false},
+		{" WHERE ts > ? ORDER BY ts", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := isTimeRangeCondition(c.condition, "ts"); got != c.want {
+			t.Errorf("isTimeRangeCondition(%q) = %v, want %v", c.condition, got, c.want)
+		}
+	}
+}
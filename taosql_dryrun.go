@@ -0,0 +1,113 @@
+package taosql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/text/gregex"
+)
+
+const (
+	ctxKeyForDryRun gctx.StrKey = `TaossqlDryRun`
+)
+
+// DryRunStatement is the statement recorded in dry run mode, which is what would be sent to the server.
+type DryRunStatement struct {
+	Group string        // Configuration group of the DB.
+	Type  string        // Statement type, like: DB.QueryContext, DB.ExecContext.
+	Sql   string        // Rewritten sql after DoFilter, in which the credentials are redacted.
+	Args  []interface{} // Rewritten arguments after DoFilter.
+}
+
+// DryRun is the recorder of the statements in dry run mode, see WithDryRun.
+type DryRun struct {
+	mu         sync.Mutex
+	statements []DryRunStatement
+}
+
+// NewDryRun creates and returns an empty dry run recorder.
+func NewDryRun() *DryRun {
+	return &DryRun{}
+}
+
+// WithDryRun creates and returns a new context from `ctx` with dry run `recorder` bound. The query, exec
+// and insert statements executed with the returned context are recorded by `recorder` after being rewritten
+// by DoFilter, without being sent to the server, eg:
+//
+// recorder := taosql.NewDryRun()
+// db.Model("meters").Ctx(taosql.WithDryRun(ctx, recorder)).Data(data).Insert()
+// statements := recorder.Statements()
+//
+// The queries return empty result and the execs return result of 0 affected rows in dry run mode,
+// but the queries of the prepared statements return an error of code gcode.CodeNotSupported and are not
+// recorded, as their result of rows cannot be faked. Note that gdb.Stmt.QueryRowContext panics with the error.
+// Unlike gdb.ConfigNode.DryRun, which only skips the exec statements, it skips the queries too.
+// Note that the statement preparing is not skipped, as it requires the server, and neither are the metadata
+// statements that the rewriting depends on, like TableFields.
+func WithDryRun(ctx context.Context, recorder *DryRun) context.Context {
+	return context.WithValue(ctx, ctxKeyForDryRun, recorder)
+}
+
+// withoutDryRun returns a new context from `ctx` without dry run recorder bound, which is used for
+// internal metadata statements that the rewriting of the dry run statements depends on, like TableFields.
+func withoutDryRun(ctx context.Context) context.Context {
+	if recorder, _ := ctx.Value(ctxKeyForDryRun).(*DryRun); recorder == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKeyForDryRun, (*DryRun)(nil))
+}
+
+// Statements returns a copy of the recorded statements in execution order.
+func (r *DryRun) Statements() []DryRunStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statements := make([]DryRunStatement, len(r.statements))
+	copy(statements, r.statements)
+	return statements
+}
+
+// dryRun records the committed statement `in` if there's dry run recorder bound to `ctx`,
+// and returns whether the statement is recorded and should be skipped.
+func (d *Driver) dryRun(ctx context.Context, in gdb.DoCommitInput) bool {
+	recorder, _ := ctx.Value(ctxKeyForDryRun).(*DryRun)
+	if recorder == nil {
+		return false
+	}
+	switch in.Type {
+	case gdb.SqlTypeQueryContext, gdb.SqlTypeExecContext, gdb.SqlTypeStmtExecContext:
+	default:
+		return false
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.statements = append(recorder.statements, DryRunStatement{
+		Group: d.GetGroup(),
+		Type:  in.Type,
+		Sql:   redactSql(in.Sql),
+		Args:  in.Args,
+	})
+	return true
+}
+
+// dryRunUnavailable checks whether the committed statement `in` is not available in dry run mode with
+// `ctx`, and returns an error of code gcode.CodeNotSupported if so, see WithDryRun.
+func dryRunUnavailable(ctx context.Context, in gdb.DoCommitInput) error {
+	if recorder, _ := ctx.Value(ctxKeyForDryRun).(*DryRun); recorder == nil {
+		return nil
+	}
+	switch in.Type {
+	case gdb.SqlTypeStmtQueryContext, gdb.SqlTypeStmtQueryRowContext:
+		return gerror.NewCodef(gcode.CodeNotSupported, `%s is not available in dry run mode: %s`, in.Type, in.Sql)
+	}
+	return nil
+}
+
+// redactSql redacts the credentials in `sql`, like the password of `CREATE USER ... PASS '...'`.
+func redactSql(sql string) string {
+	sql, _ = gregex.ReplaceString(`(?i)(\bPASS\s+)'(?:[^'\\]|\\.)*'`, `$1'***'`, sql)
+	return sql
+}
@@ -0,0 +1,124 @@
+package taosql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+func TestDryRun(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		run  func(ctx context.Context, d *Driver) error
+		want []DryRunStatement
+	}{
+		{
+			name: "insert",
+			run: func(ctx context.Context, d *Driver) error {
+				_, err := d.Model("d1001").Ctx(ctx).Data(map[string]interface{}{"ts": ts}).Insert()
+				return err
+			},
+			want: []DryRunStatement{{
+				Type: gdb.SqlTypeExecContext,
+				Sql:  "INSERT INTO `d1001`(`ts`) VALUES(?) ",
+				Args: []interface{}{ts.UnixNano() / 1e6},
+			}},
+		},
+		{
+			name: "window query",
+			run: func(ctx context.Context, d *Driver) error {
+				ctx = WithClause(ctx, NewClause().Interval("1h"))
+				_, err := d.GetAll(ctx, "SELECT _wstart, AVG(current) FROM meters WHERE ts >= ?", ts)
+				return err
+			},
+			want: []DryRunStatement{{
+				Type: gdb.SqlTypeQueryContext,
+				Sql:  "SELECT _wstart, AVG(current) FROM meters WHERE ts >= ? INTERVAL(1h)",
+				Args: []interface{}{ts.UnixNano() / 1e6},
+			}},
+		},
+		{
+			name: "credentials",
+			run: func(ctx context.Context, d *Driver) error {
+				_, err := d.Exec(ctx, "CREATE USER reader PASS 'secret'")
+				return err
+			},
+			want: []DryRunStatement{{Type: gdb.SqlTypeExecContext, Sql: "CREATE USER reader PASS '***'"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				d, server = newMockDriver(t, Option{}, metersHandler(nil))
+				recorder  = NewDryRun()
+			)
+			if err := c.run(WithDryRun(context.Background(), recorder), d); err != nil {
+				t.Fatal(err)
+			}
+			got := recorder.Statements()
+			for i := range c.want {
+				c.want[i].Group = d.GetGroup()
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %#v, want %#v", got, c.want)
+			}
+			for _, sql := range server.Sqls() {
+				if sql != `SHOW DATABASES` && sql[:5] != `desc ` {
+					t.Fatalf("got statement %q sent in dry run mode", sql)
+				}
+			}
+		})
+	}
+}
+
+func TestDryRunStmtQuery(t *testing.T) {
+	var (
+		d, server = newMockDriver(t, Option{}, metersHandler(nil))
+		recorder  = NewDryRun()
+		ctx       = WithDryRun(context.Background(), recorder)
+	)
+	stmt, err := d.Prepare(ctx, "SELECT * FROM d1001 WHERE voltage > ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	_, err = stmt.QueryContext(ctx, 200)
+	checkCode(t, err, gcode.CodeNotSupported)
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			checkCode(t, err, gcode.CodeNotSupported)
+		}()
+		stmt.QueryRowContext(ctx, 200)
+	}()
+	if got := recorder.Statements(); len(got) != 0 {
+		t.Fatalf("got statements %#v recorded, want none", got)
+	}
+	if got := server.Sqls(); len(got) != 0 {
+		t.Fatalf("got statements %q sent, want none", got)
+	}
+}
+
+func TestRedactSql(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{name: "create user", sql: "CREATE USER reader PASS 'secret'", want: "CREATE USER reader PASS '***'"},
+		{name: "alter user", sql: "alter user reader pass 'it\\'s'", want: "alter user reader pass '***'"},
+		{name: "no credentials", sql: "SELECT * FROM meters WHERE note = 'PASS'", want: "SELECT * FROM meters WHERE note = 'PASS'"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := redactSql(c.sql); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
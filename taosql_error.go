@@ -0,0 +1,61 @@
+package taosql
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gregex"
+	taosErrors "github.com/taosdata/driver-go/v2/errors"
+)
+
+// TaosCode retrieves and returns the original TDengine error code of `err`, and whether it is found,
+// which can be used for branching on the exact server errors, eg:
+//
+// if code, ok := taosql.TaosCode(err); ok && code == taosErrors.MND_INVALID_TABLE_NAME {
+// }
+//
+// The error of statement execution keeps gf's code gcode.CodeDbOperationError, with the original server error
+// as its detail, see wrapTaosError.
+func TaosCode(err error) (int32, bool) {
+	if err == nil {
+		return 0, false
+	}
+	if taosErr, ok := gerror.Code(err).Detail().(*taosErrors.TaosError); ok {
+		return taosErr.Code, true
+	}
+	var taosErr *taosErrors.TaosError
+	if errors.As(err, &taosErr) {
+		return taosErr.Code, true
+	}
+	return 0, false
+}
+
+// wrapTaosError wraps the error of statement execution `err` with the original TDengine error as the detail
+// of its code, as package gdb wraps the error of the underlying driver by its message, which loses the original
+// error. The original error code is recovered from the message, which is in format `[0x2603] message`.
+// It returns `err` unchanged if it has no TDengine error code.
+func wrapTaosError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := TaosCode(err); ok {
+		return err
+	}
+	match, _ := gregex.MatchString(`\[0x([0-9a-fA-F]+)\]\s*([^,\n]*)`, err.Error())
+	if len(match) == 0 {
+		return err
+	}
+	code, parseErr := strconv.ParseInt(match[1], 16, 32)
+	if parseErr != nil {
+		return err
+	}
+	// The code message is left empty, so that the wrapped error message stays the same as `err`.
+	return gerror.WrapCode(
+		gcode.New(
+			gcode.CodeDbOperationError.Code(), "", &taosErrors.TaosError{Code: int32(code), ErrStr: match[2]},
+		),
+		err,
+	)
+}
@@ -0,0 +1,105 @@
+package taosql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	taosErrors "github.com/taosdata/driver-go/v2/errors"
+)
+
+func TestWrapTaosError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int32
+		ok   bool
+	}{
+		{name: "nil", err: nil},
+		{name: "plain", err: errors.New("connection refused")},
+		{name: "taos error", err: &taosErrors.TaosError{Code: 0x2603, ErrStr: "Table does not exist"}, code: 0x2603, ok: true},
+		{
+			name: "wrapped message",
+			err:  gerror.WrapCode(gcode.CodeDbOperationError, errors.New("[0x2662] Database not exist"), "SELECT * FROM t"),
+			code: 0x2662,
+			ok:   true,
+		},
+		{name: "invalid code", err: errors.New("[0xfffffffff] overflow")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := wrapTaosError(c.err)
+			if (err == nil) != (c.err == nil) {
+				t.Fatalf("got error %v, want %v", err, c.err)
+			}
+			if err != nil && err.Error() != c.err.Error() {
+				t.Fatalf("got message %q, want %q", err.Error(), c.err.Error())
+			}
+			code, ok := TaosCode(err)
+			if code != c.code || ok != c.ok {
+				t.Fatalf("got code (%#x, %t), want (%#x, %t)", code, ok, c.code, c.ok)
+			}
+		})
+	}
+}
+
+func TestTaosCodeOfStatement(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+		return mockResponse{Err: &taosErrors.TaosError{Code: 0x2603, ErrStr: "Table does not exist"}}
+	})
+	ctx := context.Background()
+	for name, run := range map[string]func() error{
+		"query": func() error { _, err := d.GetAll(ctx, "SELECT * FROM d9999"); return err },
+		"exec":  func() error { _, err := d.Exec(ctx, "DROP TABLE d9999"); return err },
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The error code is replaced by the one with the TDengine error of the same number, see wrapTaosError.
+			err := run()
+			if got := gerror.Code(err).Code(); got != gcode.CodeDbOperationError.Code() {
+				t.Fatalf("got code %d of error %v, want %d", got, err, gcode.CodeDbOperationError.Code())
+			}
+			if code, ok := TaosCode(err); !ok || code != 0x2603 {
+				t.Fatalf("got code (%#x, %t) of error %v, want 0x2603", code, ok, err)
+			}
+		})
+	}
+}
+
+// failingValuer is the driver.Valuer that always fails, which is kept by gdb.DataToMapDeep as a pointer.
+type failingValuer struct{ value float64 }
+
+func (*failingValuer) Value() (driver.Value, error) { return nil, fmt.Errorf("invalid value") }
+
+func TestToRecordList(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, metersHandler(nil))
+	cases := []struct {
+		name string
+		data interface{}
+		want int
+		code gcode.Code
+	}{
+		{name: "nil", data: nil},
+		{name: "map", data: map[string]interface{}{"current": 10.5}, want: 1},
+		{name: "slice", data: []map[string]interface{}{{"current": 10.5}, {"current": 11.5}}, want: 2},
+		{name: "failing valuer", data: []map[string]interface{}{{"current": 10.5}, {"current": &failingValuer{10.5}}}, code: gcode.CodeInvalidParameter},
+		{name: "not record", data: []interface{}{map[string]interface{}{"current": 10.5}, 3}, code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			list, err := d.toRecordList(context.Background(), c.data)
+			checkCode(t, err, c.code)
+			if len(list) != c.want {
+				t.Fatalf("got %d records, want %d", len(list), c.want)
+			}
+			for i, record := range list {
+				if record == nil {
+					t.Fatalf("got nil record at index %d", i)
+				}
+			}
+		})
+	}
+}
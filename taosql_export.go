@@ -0,0 +1,208 @@
+package taosql
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+const (
+	defaultExportBatchSize = 100
+	maxImportStatementSize = 64 * 1024 * 1024
+)
+
+// ExportOptions is the options for function ExportStable.
+type ExportOptions struct {
+	Start     time.Time // (Optional) Start of the exported data, inclusive.
+	End       time.Time // (Optional) End of the exported data, exclusive.
+	BatchSize int       // (Optional) Max rows of each INSERT statement, which is 100 in default.
+}
+
+// ExportStable exports the schema and data of super table `stable` of current schema to `w`
+// as SQL statements, which can be restored by ImportStable, like for environment cloning.
+//
+// The exported format is one statement per line, each of which ends with `;`, in order:
+// the CREATE STABLE statement from `SHOW CREATE STABLE`, then for each child table, its CREATE TABLE statement
+// from `SHOW CREATE TABLE` and the INSERT statements of its rows within [Start, End). The timestamps are exported
+// as epoch integers at the database precision, and the line breaks in strings are escaped. The rows are written
+// as they are read, in statements of at most BatchSize rows, so that the exported data is not held in memory.
+func (d *Driver) ExportStable(ctx context.Context, stable string, w io.Writer, opts ExportOptions) error {
+	charL, charR := d.GetChars()
+	stable = gstr.Trim(stable, charL+charR)
+	ctx = withoutClause(ctx)
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultExportBatchSize
+	}
+	precision, err := d.precision(ctx, d.GetSchema())
+	if err != nil {
+		return err
+	}
+	tsColumn, err := d.primaryTsColumn(ctx, stable)
+	if err != nil {
+		return err
+	}
+	ddl, err := d.showCreate(ctx, "STABLE", stable)
+	if err != nil {
+		return err
+	}
+	if err = writeStatement(w, ddl); err != nil {
+		return err
+	}
+	tables, err := d.GetArray(ctx, fmt.Sprintf(
+		`SELECT table_name FROM information_schema.ins_tables WHERE db_name=%s AND stable_name=%s`,
+		quoteString(d.GetSchema()), quoteString(stable),
+	))
+	if err != nil {
+		return err
+	}
+	var conditions []string
+	if !opts.Start.IsZero() {
+		conditions = append(conditions, fmt.Sprintf(
+			`%s >= %d`, d.QuoteWord(tsColumn), timeToEpoch(opts.Start, precision),
+		))
+	}
+	if !opts.End.IsZero() {
+		conditions = append(conditions, fmt.Sprintf(
+			`%s < %d`, d.QuoteWord(tsColumn), timeToEpoch(opts.End, precision),
+		))
+	}
+	for _, table := range tables {
+		if ddl, err = d.showCreate(ctx, "TABLE", table.String()); err != nil {
+			return err
+		}
+		if err = writeStatement(w, ddl); err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`SELECT * FROM %s`, d.QuotePrefixTableName(table.String()))
+		if len(conditions) > 0 {
+			query += ` WHERE ` + gstr.Join(conditions, ` AND `)
+		}
+		if err = d.exportRows(ctx, w, table.String(), query+fmt.Sprintf(` ORDER BY %s`, d.QuoteWord(tsColumn)), precision, opts.BatchSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportStable restores the super table exported by ExportStable from `r` into current schema,
+// by executing the exported statements in order. It stops at the first failed statement and returns the error.
+//
+// Note that each line is executed as it is, as the data lines are SQL statements that cannot be told apart
+// from the DDL, so `r` should be trusted, or any statement in it is executed, like DROP DATABASE. And the import
+// is not atomic, a failed import leaves the tables created and the rows inserted by the statements before the
+// failed one, which should be dropped before retrying, or the retried CREATE statements fail. The timestamps are
+// epoch integers, which should be imported into a database of the same precision.
+func (d *Driver) ImportStable(ctx context.Context, r io.Reader) error {
+	ctx = withoutClause(ctx)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportStatementSize)
+	for line := 1; scanner.Scan(); line++ {
+		statement := gstr.TrimRight(gstr.Trim(scanner.Text()), ";")
+		if statement == "" {
+			continue
+		}
+		if _, err := d.Exec(ctx, statement); err != nil {
+			return gerror.WrapCodef(gcode.CodeDbOperationError, err, `import statement of line %d failed`, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return gerror.WrapCode(gcode.CodeInvalidParameter, err, `read exported statements failed`)
+	}
+	return nil
+}
+
+// exportRows writes the rows of `query` on `table` to `w` as INSERT statements of at most `batchSize` rows,
+// which are written as the rows are read, so that the exported rows are not held in memory.
+func (d *Driver) exportRows(ctx context.Context, w io.Writer, table, query, precision string, batchSize int) error {
+	var (
+		columns []string
+		values  = make([]string, 0, batchSize)
+	)
+	flush := func() error {
+		if len(values) == 0 {
+			return nil
+		}
+		err := writeStatement(w, fmt.Sprintf(
+			`INSERT INTO %s (%s) VALUES %s`,
+			d.QuotePrefixTableName(table), gstr.Join(columns, `, `), gstr.Join(values, ` `),
+		))
+		values = values[:0]
+		return err
+	}
+	_, err := d.queryRows(ctx, query, nil, func(names []string, row []interface{}) error {
+		if columns == nil {
+			columns = make([]string, len(names))
+			for i, name := range names {
+				columns[i] = d.QuoteWord(name)
+			}
+		}
+		array := make([]string, len(row))
+		for i, v := range row {
+			array[i] = formatExportValue(v, precision)
+		}
+		if values = append(values, `(`+gstr.Join(array, `, `)+`)`); len(values) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// showCreate retrieves and returns the CREATE statement of `table` of `kind`, which is STABLE or TABLE,
+// by `SHOW CREATE kind table`.
+func (d *Driver) showCreate(ctx context.Context, kind, table string) (string, error) {
+	record, err := d.GetOne(ctx, fmt.Sprintf(`SHOW CREATE %s %s`, kind, d.QuotePrefixTableName(table)))
+	if err != nil {
+		return "", err
+	}
+	if record == nil {
+		return "", gerror.NewCodef(gcode.CodeNotFound, `table "%s" not found`, table)
+	}
+	return record["Create Table"].String(), nil
+}
+
+// writeStatement writes `statement` to `w` as one line ending with `;`.
+func writeStatement(w io.Writer, statement string) error {
+	if _, err := io.WriteString(w, statement+";\n"); err != nil {
+		return gerror.WrapCode(gcode.CodeInternalError, err, `write exported statement failed`)
+	}
+	return nil
+}
+
+// formatExportValue formats value `v` of the exported rows as SQL literal, in which the timestamps are epoch
+// integers at `precision`, and the strings are quoted with the line breaks escaped.
+func formatExportValue(v interface{}, precision string) string {
+	switch value := v.(type) {
+	case nil:
+		return "NULL"
+	case time.Time:
+		return gconv.String(timeToEpoch(value, precision))
+	case *gtime.Time:
+		if value == nil {
+			return "NULL"
+		}
+		return gconv.String(timeToEpoch(value.Time, precision))
+	case []byte:
+		return quoteExportString(string(value))
+	case string:
+		return quoteExportString(value)
+	default:
+		return gconv.String(value)
+	}
+}
+
+// quoteExportString quotes `s` as string literal in one line, escaping its line breaks.
+func quoteExportString(s string) string {
+	return gstr.Replace(gstr.Replace(quoteString(s), "\n", `\n`), "\r", `\r`)
+}
@@ -0,0 +1,136 @@
+package taosql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// exportHandler responds the statements of exporting super table "meters" with child tables "d1001" and "d1002".
+func exportHandler(start time.Time) mockHandler {
+	return metersHandler(func(q mockQuery) mockResponse {
+		switch {
+		case q.Sql == "SHOW CREATE STABLE `meters`":
+			return mockRecords([]string{"Table", "Create Table"}, []interface{}{
+				"meters", "CREATE STABLE `meters` (`ts` TIMESTAMP, `current` FLOAT) TAGS (`location` VARCHAR(64))",
+			})
+		case gstr.HasPrefix(q.Sql, "SHOW CREATE TABLE"):
+			table := gstr.Trim(gstr.Replace(q.Sql, "SHOW CREATE TABLE", ""), " `")
+			return mockRecords([]string{"Table", "Create Table"}, []interface{}{
+				table, "CREATE TABLE `" + table + "` USING `meters` (`location`) TAGS (\"beijing\")",
+			})
+		case gstr.Contains(q.Sql, "ins_tables"):
+			return mockRecords([]string{"table_name"}, []interface{}{"d1001"}, []interface{}{"d1002"})
+		case gstr.HasPrefix(q.Sql, "SELECT * FROM `d1001`"):
+			return mockRecords(
+				[]string{"ts", "current", "note"},
+				[]interface{}{start, 10.5, "it's\nok"},
+				[]interface{}{start.Add(time.Second), nil, []byte("b")},
+				[]interface{}{start.Add(2 * time.Second), 11.5, nil},
+			)
+		}
+		return mockResponse{}
+	})
+}
+
+func TestExportStable(t *testing.T) {
+	var (
+		start     = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		d, server = newMockDriver(t, Option{}, exportHandler(start))
+		buffer    = new(bytes.Buffer)
+	)
+	opts := ExportOptions{Start: start, End: start.Add(time.Hour), BatchSize: 2}
+	if err := d.ExportStable(context.Background(), "meters", buffer, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"CREATE STABLE `meters` (`ts` TIMESTAMP, `current` FLOAT) TAGS (`location` VARCHAR(64));",
+		"CREATE TABLE `d1001` USING `meters` (`location`) TAGS (\"beijing\");",
+		"INSERT INTO `d1001` (`ts`, `current`, `note`) VALUES (1672531200000, 10.5, 'it\\'s\\nok') (1672531201000, NULL, 'b');",
+		"INSERT INTO `d1001` (`ts`, `current`, `note`) VALUES (1672531202000, 11.5, NULL);",
+		"CREATE TABLE `d1002` USING `meters` (`location`) TAGS (\"beijing\");",
+	}
+	if got := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got exported\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	var selected bool
+	for _, sql := range server.Sqls() {
+		if gstr.HasPrefix(sql, "SELECT * FROM `d1001`") {
+			selected = true
+			if want := "SELECT * FROM `d1001` WHERE `ts` >= 1672531200000 AND `ts` < 1672534800000 ORDER BY `ts`"; sql != want {
+				t.Fatalf("got sql %q, want %q", sql, want)
+			}
+		}
+	}
+	if !selected {
+		t.Fatal("got no rows selected")
+	}
+
+	// The exported statements are executed in order by the import.
+	imported, importServer := newMockDriver(t, Option{}, metersHandler(nil))
+	if err := imported.ImportStable(context.Background(), bytes.NewReader(buffer.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, sql := range importServer.Sqls() {
+		if sql != "SHOW DATABASES" && !gstr.HasPrefix(sql, "desc ") {
+			got = append(got, sql+";")
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got imported\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestImportStableFailure(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+		if gstr.HasPrefix(q.Sql, "INSERT") {
+			return mockResponse{Err: errors.New("[0x2603] Table does not exist")}
+		}
+		return mockResponse{}
+	})
+	archive := "CREATE STABLE `meters` (`ts` TIMESTAMP) TAGS (`g` INT);\n\nINSERT INTO `d1` (`ts`) VALUES (1);\nCREATE TABLE `d2` USING `meters` TAGS (1);\n"
+	err := d.ImportStable(context.Background(), strings.NewReader(archive))
+	checkCode(t, err, gcode.CodeDbOperationError)
+	if !gstr.Contains(err.Error(), "line 3") {
+		t.Fatalf("got error %v, want error of line 3", err)
+	}
+	if sqls := server.Sqls(); len(sqls) != 2 {
+		t.Fatalf("got statements %q, want stopped at the failed statement", sqls)
+	}
+}
+
+func TestFormatExportValue(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 0, 0, 0, 1000, time.UTC)
+	cases := []struct {
+		name      string
+		value     interface{}
+		precision string
+		want      string
+	}{
+		{name: "nil", value: nil, precision: "ms", want: "NULL"},
+		{name: "time ms", value: ts, precision: "ms", want: "1672531200000"},
+		{name: "time us", value: ts, precision: "us", want: "1672531200000001"},
+		{name: "gtime", value: gtime.NewFromTime(ts), precision: "ns", want: "1672531200000001000"},
+		{name: "nil gtime", value: (*gtime.Time)(nil), precision: "ms", want: "NULL"},
+		{name: "string", value: "a'b\r\nc", precision: "ms", want: `'a\'b\r\nc'`},
+		{name: "bytes", value: []byte("abc"), precision: "ms", want: "'abc'"},
+		{name: "float", value: 10.5, precision: "ms", want: "10.5"},
+		{name: "bool", value: true, precision: "ms", want: "true"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := formatExportValue(c.value, c.precision); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package taosql
+
+import (
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// ColumnFill is the client side fill strategy of a column, see FillColumns.
+type ColumnFill struct {
+	Mode  FillMode    // Fill mode, which is one of FillNull, FillValue, FillPrev, FillNext and FillLinear.
+	Value interface{} // Fill value for FillValue mode.
+}
+
+// FillColumns fills the NULL values of the columns of `result` in place, by the fill strategies of `fills`
+// keyed by column name, and the columns that are not in `fills` are left unchanged. It returns an error of code
+// gcode.CodeInvalidParameter without filling any column if any fill strategy is invalid.
+//
+// TDengine applies the same FILL mode to all the columns of the windowed query, and only FILL(VALUE, ...) has
+// per-column values, so the per-column fill is done at the client side: query with FILL(NULL) so that the
+// empty windows are returned as NULL rows, then fill them per column, like filling the averages linearly but
+// leaving the counts at 0, eg:
+//
+// result, err := db.Model("meters").Ctx(ctx).Fields("_wstart", "AVG(current) AS avg", "COUNT(*) AS cnt").All()
+// fills := map[string]taosql.ColumnFill{"avg": {Mode: taosql.FillLinear}, "cnt": {Mode: taosql.FillValue, Value: 0}}
+// err = taosql.FillColumns(result, fills)
+//
+// The records should be in window order, and FillLinear interpolates by the record positions, as the windows
+// are of the same interval. The NULL values at the boundaries are left NULL for FillPrev, FillNext and FillLinear.
+func FillColumns(result gdb.Result, fills map[string]ColumnFill) error {
+	for column, fill := range fills {
+		switch fill.Mode {
+		case FillNone, FillNull, FillPrev, FillNext, FillLinear:
+		case FillValue:
+			if fill.Value == nil {
+				return gerror.NewCodef(gcode.CodeInvalidParameter, `fill mode VALUE requires value for column "%s"`, column)
+			}
+		default:
+			return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid fill mode "%s" for column "%s"`, fill.Mode, column)
+		}
+	}
+	for column, fill := range fills {
+		switch fill.Mode {
+		case FillValue:
+			for _, record := range result {
+				if isNullValue(record[column]) {
+					record[column] = gvar.New(fill.Value)
+				}
+			}
+		case FillPrev:
+			var prev *gvar.Var
+			for _, record := range result {
+				if !isNullValue(record[column]) {
+					prev = record[column]
+				} else if prev != nil {
+					record[column] = prev
+				}
+			}
+		case FillNext:
+			var next *gvar.Var
+			for i := len(result) - 1; i >= 0; i-- {
+				if !isNullValue(result[i][column]) {
+					next = result[i][column]
+				} else if next != nil {
+					result[i][column] = next
+				}
+			}
+		case FillLinear:
+			fillLinear(result, column)
+		}
+	}
+	return nil
+}
+
+// fillLinear fills the NULL values of `column` of `result` by the linear interpolation of the previous and next
+// non-NULL values by the record positions.
+func fillLinear(result gdb.Result, column string) {
+	prev := -1
+	for i, record := range result {
+		if isNullValue(record[column]) {
+			continue
+		}
+		if prev >= 0 && i-prev > 1 {
+			var (
+				start = result[prev][column].Float64()
+				step  = (record[column].Float64() - start) / float64(i-prev)
+			)
+			for j := prev + 1; j < i; j++ {
+				result[j][column] = gvar.New(start + step*float64(j-prev))
+			}
+		}
+		prev = i
+	}
+}
+
+// isNullValue checks whether `v` is absent or NULL value.
+func isNullValue(v *gvar.Var) bool {
+	return v == nil || v.IsNil()
+}
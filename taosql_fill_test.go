@@ -0,0 +1,84 @@
+package taosql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+// fillResult creates and returns the result of columns "avg" and "cnt" with `rows`, in which nil is NULL.
+func fillResult(rows ...[2]interface{}) gdb.Result {
+	result := make(gdb.Result, len(rows))
+	for i, row := range rows {
+		result[i] = gdb.Record{"avg": gvar.New(row[0]), "cnt": gvar.New(row[1])}
+	}
+	return result
+}
+
+// fillValues returns the values of `column` of `result`.
+func fillValues(result gdb.Result, column string) []interface{} {
+	values := make([]interface{}, len(result))
+	for i, record := range result {
+		values[i] = record[column].Val()
+	}
+	return values
+}
+
+func TestFillColumns(t *testing.T) {
+	rows := [][2]interface{}{{nil, nil}, {1.0, 3}, {nil, nil}, {nil, nil}, {4.0, 2}, {nil, nil}}
+	cases := []struct {
+		name  string
+		fills map[string]ColumnFill
+		avg   []interface{}
+		cnt   []interface{}
+		code  gcode.Code
+	}{
+		{
+			name:  "linear and value",
+			fills: map[string]ColumnFill{"avg": {Mode: FillLinear}, "cnt": {Mode: FillValue, Value: 0}},
+			avg:   []interface{}{nil, 1.0, 2.0, 3.0, 4.0, nil},
+			cnt:   []interface{}{0, 3, 0, 0, 2, 0},
+		},
+		{
+			name:  "prev and next",
+			fills: map[string]ColumnFill{"avg": {Mode: FillPrev}, "cnt": {Mode: FillNext}},
+			avg:   []interface{}{nil, 1.0, 1.0, 1.0, 4.0, 4.0},
+			cnt:   []interface{}{3, 3, 2, 2, 2, nil},
+		},
+		{
+			name:  "null and unlisted",
+			fills: map[string]ColumnFill{"avg": {Mode: FillNull}},
+			avg:   []interface{}{nil, 1.0, nil, nil, 4.0, nil},
+			cnt:   []interface{}{nil, 3, nil, nil, 2, nil},
+		},
+		{
+			name:  "missing value",
+			fills: map[string]ColumnFill{"avg": {Mode: FillLinear}, "cnt": {Mode: FillValue}},
+			avg:   []interface{}{nil, 1.0, nil, nil, 4.0, nil},
+			cnt:   []interface{}{nil, 3, nil, nil, 2, nil},
+			code:  gcode.CodeInvalidParameter,
+		},
+		{
+			name:  "invalid mode",
+			fills: map[string]ColumnFill{"avg": {Mode: FillPrev}, "cnt": {Mode: "SPLINE"}},
+			avg:   []interface{}{nil, 1.0, nil, nil, 4.0, nil},
+			cnt:   []interface{}{nil, 3, nil, nil, 2, nil},
+			code:  gcode.CodeInvalidParameter,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := fillResult(rows...)
+			checkCode(t, FillColumns(result, c.fills), c.code)
+			if got := fillValues(result, "avg"); !reflect.DeepEqual(got, c.avg) {
+				t.Fatalf("got avg %v, want %v", got, c.avg)
+			}
+			if got := fillValues(result, "cnt"); !reflect.DeepEqual(got, c.cnt) {
+				t.Fatalf("got cnt %v, want %v", got, c.cnt)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package taosql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+const (
+	// stringTypePattern matches the upper case string types with length, like: NCHAR(64).
+	stringTypePattern = `^(VARCHAR|BINARY|NCHAR)\s*\(\s*(\d+)\s*\)$`
+	// fixedTypePattern matches the upper case fixed width types.
+	fixedTypePattern = `^((TINYINT|SMALLINT|INT|BIGINT)( UNSIGNED)?|BOOL|FLOAT|DOUBLE|TIMESTAMP)$`
+)
+
+// Mode returns the `MODE(expr)` call, which selects the most frequent value of `expr`.
+// It is an aggregate function, so it can be used within INTERVAL windows and PARTITION BY groups.
+func Mode(expr string) (string, error) {
+	return buildFunc("MODE", expr)
+}
+
+// Round returns the `ROUND(expr[, digits])` call, which rounds `expr` to the nearest value.
+// The optional parameter `digits` specifies the number of decimal places to keep,
+// which should not be negative. The `expr` can be an aggregate, like: Round("AVG(current)", 2).
+func Round(expr string, digits ...int) (string, error) {
+	if len(digits) == 0 {
+		return buildFunc("ROUND", expr)
+	}
+	if len(digits) > 1 || digits[0] < 0 {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid digits %v for function ROUND`, digits)
+	}
+	return buildFunc("ROUND", expr, fmt.Sprintf(`%d`, digits[0]))
+}
+
+// Floor returns the `FLOOR(expr)` call, which rounds `expr` down to the nearest integer.
+func Floor(expr string) (string, error) {
+	return buildFunc("FLOOR", expr)
+}
+
+// Ceil returns the `CEIL(expr)` call, which rounds `expr` up to the nearest integer.
+func Ceil(expr string) (string, error) {
+	return buildFunc("CEIL", expr)
+}
+
+// Abs returns the `ABS(expr)` call, which returns the absolute value of `expr`.
+func Abs(expr string) (string, error) {
+	return buildFunc("ABS", expr)
+}
+
+// PercentileAlgo is the algorithm of approximate percentile function APERCENTILE.
+type PercentileAlgo string
+
+const (
+	// AlgoDefault is the default histogram based algorithm, which has bounded memory usage
+	// but lower accuracy for skewed data.
+	AlgoDefault PercentileAlgo = "default"
+	// AlgoTDigest is the t-digest algorithm, which is more accurate at the extreme percentiles,
+	// like p99, at the cost of more memory usage.
+	AlgoTDigest PercentileAlgo = "t-digest"
+)
+
+// APercentile returns the `APERCENTILE(expr, p[, algo])` call, which returns the approximate `p`th
+// percentile of `expr`. The parameter `p` should be within [0, 100], and the optional parameter
+// `algo` specifies the algorithm, see AlgoDefault and AlgoTDigest.
+func APercentile(expr string, p float64, algo ...PercentileAlgo) (string, error) {
+	if p < 0 || p > 100 {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid percentile %v, it should be within [0, 100]`, p)
+	}
+	args := []string{expr, gconv.String(p)}
+	if len(algo) > 0 {
+		switch algo[0] {
+		case AlgoDefault, AlgoTDigest:
+			args = append(args, quoteString(string(algo[0])))
+		default:
+			return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid percentile algorithm "%s"`, algo[0])
+		}
+	}
+	return buildFunc("APERCENTILE", args...)
+}
+
+// Interp returns the `INTERP(expr)` call, which returns the interpolation value of `expr` at the points
+// specified by RANGE/EVERY clauses, see Clause.Range, Clause.Every and Driver.Interp.
+func Interp(expr string) (string, error) {
+	return buildFunc("INTERP", expr)
+}
+
+// Derivative returns the `DERIVATIVE(expr, unit, ignore_negative)` call, which returns the rate of change
+// of `expr` per `unit`, that is the result is per-time-unit, like the counter rate per second with unit
+// time.Second. The `unit` should be whole seconds and not less than 1 second, as TDengine requires.
+// The negative rates are dropped from the result if `ignoreNegative` is true, like for counter resets.
+func Derivative(expr string, unit time.Duration, ignoreNegative bool) (string, error) {
+	if unit < time.Second || unit%time.Second != 0 {
+		return "", gerror.NewCodef(
+			gcode.CodeInvalidParameter,
+			`invalid time unit "%s" for function DERIVATIVE, it should be whole seconds of at least 1s`, unit,
+		)
+	}
+	return buildFunc("DERIVATIVE", expr, formatDuration(unit), gconv.String(gconv.Int(ignoreNegative)))
+}
+
+// formatDuration formats `duration` as TDengine duration literal of the largest unit that divides it,
+// like: 1d, 90m, 1500a.
+func formatDuration(duration time.Duration) string {
+	units := []struct {
+		unit   time.Duration
+		suffix string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+		{time.Millisecond, "a"},
+		{time.Microsecond, "u"},
+	}
+	for _, u := range units {
+		if duration%u.unit == 0 {
+			return fmt.Sprintf(`%d%s`, duration/u.unit, u.suffix)
+		}
+	}
+	return fmt.Sprintf(`%db`, duration)
+}
+
+// Cast returns the `CAST(expr AS typ)` call, which converts `expr` to type `typ`, like: BIGINT, DOUBLE,
+// TIMESTAMP, or the string types with explicit length VARCHAR(n), BINARY(n) and NCHAR(n),
+// in which the length `n` should be positive. The string longer than `n` is truncated to `n`, which limits
+// the display width of long columns, like: Cast("location", "VARCHAR(32)").
+// The truncated result is read as a Go string by gdb.Value.String.
+func Cast(expr string, typ string) (string, error) {
+	if err := checkExpr(expr); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid argument for function CAST`)
+	}
+	typ = gstr.ToUpper(gstr.Trim(typ))
+	if match, _ := gregex.MatchString(stringTypePattern, typ); len(match) > 0 {
+		if gconv.Int(match[2]) <= 0 {
+			return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid length of type "%s", it should be positive`, typ)
+		}
+		typ = fmt.Sprintf(`%s(%s)`, match[1], match[2])
+	} else if !gregex.IsMatchString(fixedTypePattern, typ) {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid type "%s" for function CAST`, typ)
+	}
+	return fmt.Sprintf(`CAST(%s AS %s)`, expr, typ), nil
+}
+
+// TruncateTsPrecision returns the `TIMETRUNCATE(expr, 1x)` call, which truncates timestamp `expr` to
+// `precision` on the server, like the ns timestamps displayed as ms: TruncateTsPrecision("ts", "ms") for
+// `TIMETRUNCATE(ts, 1a)`. The `precision` should be one of: s, ms, us, ns, which should not be finer
+// than the database precision.
+func TruncateTsPrecision(expr string, precision string) (string, error) {
+	units := map[string]string{"s": "1s", "ms": "1a", "us": "1u", "ns": "1b"}
+	unit, ok := units[precision]
+	if !ok {
+		return "", gerror.NewCodef(
+			gcode.CodeInvalidParameter, `invalid precision "%s", it should be one of: s, ms, us, ns`, precision,
+		)
+	}
+	return buildFunc("TIMETRUNCATE", expr, unit)
+}
+
+// CastCondition returns the `CAST((condition) AS INT)` call, which projects boolean `condition` as 1 for true
+// and 0 for false, like the occurrences of a condition counted by Sum("...") of it, eg:
+//
+// overload, err := taosql.CastCondition("current > 10")
+// sum, err := taosql.Sum(overload)
+//
+// The `condition` should contain a comparison or logical operator, and the optional parameter `typ`
+// is the integer type of the result, which is INT in default.
+func CastCondition(condition string, typ ...string) (string, error) {
+	if err := checkExpr(condition); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid condition for CAST`)
+	}
+	if !gregex.IsMatchString(`(?i)(=|<|>|\bAND\b|\bOR\b|\bNOT\b|\bIS\b|\bIN\b|\bBETWEEN\b|\bN?MATCH\b|\bLIKE\b)`, condition) {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `"%s" is not a boolean condition`, condition)
+	}
+	intType := "INT"
+	if len(typ) > 0 {
+		intType = gstr.ToUpper(gstr.Trim(typ[0]))
+		if !gregex.IsMatchString(`^(TINYINT|SMALLINT|INT|BIGINT)( UNSIGNED)?$`, intType) {
+			return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid integer type "%s" for condition`, typ[0])
+		}
+	}
+	return Cast("("+condition+")", intType)
+}
+
+// Sum returns the `SUM(expr)` call, or `SUM(CAST(expr AS typ))` if the optional parameter `typ` is given,
+// like: Sum("voltage", "BIGINT"). Summing many values of narrow integer columns, like TINYINT, SMALLINT and INT,
+// might overflow the ranges the clients expect, so cast them to BIGINT or DOUBLE before aggregation, see Cast.
+func Sum(expr string, typ ...string) (string, error) {
+	if len(typ) > 0 {
+		var err error
+		if expr, err = Cast(expr, typ[0]); err != nil {
+			return "", err
+		}
+	}
+	return buildFunc("SUM", expr)
+}
+
+// buildFunc validates `args` and renders them as SQL function call `name(args...)`.
+func buildFunc(name string, args ...string) (string, error) {
+	for _, arg := range args {
+		if err := checkExpr(arg); err != nil {
+			return "", gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid argument for function %s`, name)
+		}
+	}
+	return fmt.Sprintf(`%s(%s)`, name, gstr.Join(args, ", ")), nil
+}
+
+// quoteString quotes `s` as a TDengine string literal, escaping the quote and escape chars in it.
+func quoteString(s string) string {
+	return `'` + gstr.Replace(gstr.Replace(s, `\`, `\\`), `'`, `\'`) + `'`
+}
+
+// formatTimeLiteral formats `t` as a TDengine timestamp string literal in RFC3339 format with nanoseconds.
+func formatTimeLiteral(t time.Time) string {
+	return quoteString(t.Format(time.RFC3339Nano))
+}
+
+// checkExpr checks whether `expr` is a non-empty and well-formed SQL expression that can be
+// embedded into a function call or clause, that is, its quotes and parentheses are balanced
+// and it contains no statement separator. The backslash escaped chars in quotes are skipped.
+func checkExpr(expr string) error {
+	if gstr.Trim(expr) == "" {
+		return gerror.NewCode(gcode.CodeInvalidParameter, `expression should not be empty`)
+	}
+	var (
+		depth   int
+		quote   rune
+		escaped bool
+	)
+	for _, c := range expr {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if c == '\\' {
+				escaped = true
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth--; depth < 0 {
+				return gerror.NewCodef(gcode.CodeInvalidParameter, `unbalanced parentheses in expression "%s"`, expr)
+			}
+		case c == ';':
+			return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid char ";" in expression "%s"`, expr)
+		}
+	}
+	if quote != 0 {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `unclosed quote in expression "%s"`, expr)
+	}
+	if depth != 0 {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `unbalanced parentheses in expression "%s"`, expr)
+	}
+	return nil
+}
+
+// TimeDiff returns the `TIMEDIFF(ts1, ts2[, unit])` call, which returns the difference `ts1 - ts2`
+// in `unit`, like: 1a, 1s, 1h. The difference is in the database precision if `unit` is not given.
+// It can be used in both projections and predicates, eg:
+//
+// diff, err := taosql.TimeDiff("ts", time.Now(), "1s")
+// db.Model("meters").Fields(diff).Where(diff + " > 60").All()
+//
+// The timestamp arguments can be time.Time, *gtime.Time, which are quoted as timestamp literals,
+// or expression strings like column names and `NOW()`, which are used as they are.
+func TimeDiff(ts1, ts2 interface{}, unit ...string) (string, error) {
+	args := make([]string, 0, 3)
+	for _, ts := range []interface{}{ts1, ts2} {
+		arg, err := formatTimeArg(ts)
+		if err != nil {
+			return "", gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid argument for function TIMEDIFF`)
+		}
+		args = append(args, arg)
+	}
+	if len(unit) > 0 {
+		if err := checkTimeUnit(unit[0]); err != nil {
+			return "", err
+		}
+		args = append(args, unit[0])
+	}
+	return buildFunc("TIMEDIFF", args...)
+}
+
+// TimeAdd returns the `(ts + duration)` expression, which adds `duration` like 1d to timestamp `ts`.
+// TDengine has no TIMEADD function, the time arithmetic is done by the operators with duration literals.
+// The `ts` argument is formatted the same as function TimeDiff.
+func TimeAdd(ts interface{}, duration string) (string, error) {
+	return buildTimeArithmetic(ts, "+", duration)
+}
+
+// TimeSub returns the `(ts - duration)` expression, which subtracts `duration` like 1d from timestamp `ts`,
+// like: TimeSub("NOW()", "1h") returns `(NOW() - 1h)`.
+// The `ts` argument is formatted the same as function TimeDiff.
+func TimeSub(ts interface{}, duration string) (string, error) {
+	return buildTimeArithmetic(ts, "-", duration)
+}
+
+// buildTimeArithmetic validates and renders the time arithmetic expression `(ts op duration)`.
+func buildTimeArithmetic(ts interface{}, op string, duration string) (string, error) {
+	arg, err := formatTimeArg(ts)
+	if err != nil {
+		return "", gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid timestamp for time arithmetic "%s"`, op)
+	}
+	if err = checkTimeUnit(duration); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`(%s %s %s)`, arg, op, duration), nil
+}
+
+// formatTimeArg formats timestamp argument `ts` of time functions, in which time.Time and *gtime.Time
+// are quoted as timestamp literals, and strings are validated as expressions.
+func formatTimeArg(ts interface{}) (string, error) {
+	switch value := ts.(type) {
+	case time.Time:
+		return formatTimeLiteral(value), nil
+	case *gtime.Time:
+		if value == nil {
+			return "", gerror.NewCode(gcode.CodeInvalidParameter, `timestamp should not be nil`)
+		}
+		return formatTimeLiteral(value.Time), nil
+	case string:
+		return value, checkExpr(value)
+	default:
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid timestamp type %T`, ts)
+	}
+}
+
+// checkTimeUnit checks whether `s` is a valid time unit or duration for time functions and arithmetic,
+// like: 1b, 1u, 1a, 1s, 1m, 1h, 1d, 1w. The variable length units n(month) and y(year) are not supported.
+func checkTimeUnit(s string) error {
+	if !gregex.IsMatchString(`^\d+[buasmhdw]$`, s) {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid time unit "%s"`, s)
+	}
+	return nil
+}
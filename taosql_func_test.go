@@ -0,0 +1,375 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// funcCase is a test case of the function helpers that return the rendered call or an error.
+type funcCase struct {
+	name string
+	call func() (string, error)
+	want string
+	code gcode.Code // Expected error code, or nil for success.
+}
+
+// runFuncCases runs the test cases of the function helpers.
+func runFuncCases(t *testing.T, cases []funcCase) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.call()
+			if c.code != nil {
+				if gerror.Code(err) != c.code {
+					t.Fatalf("got (%q, %v), want error of code %v", got, err, c.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMode(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{name: "column", call: func() (string, error) { return Mode("status") }, want: "MODE(status)"},
+		{name: "expression", call: func() (string, error) { return Mode("CAST(v AS INT)") }, want: "MODE(CAST(v AS INT))"},
+		{name: "empty", call: func() (string, error) { return Mode(" ") }, code: gcode.CodeInvalidParameter},
+		{name: "unbalanced", call: func() (string, error) { return Mode("v)") }, code: gcode.CodeInvalidParameter},
+		{name: "separator", call: func() (string, error) { return Mode("v; DROP TABLE t") }, code: gcode.CodeInvalidParameter},
+	})
+}
+
+func TestMathFunctions(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{name: "round", call: func() (string, error) { return Round("current") }, want: "ROUND(current)"},
+		{name: "round digits", call: func() (string, error) { return Round("AVG(current)", 2) }, want: "ROUND(AVG(current), 2)"},
+		{name: "round zero digits", call: func() (string, error) { return Round("current", 0) }, want: "ROUND(current, 0)"},
+		{name: "round negative digits", call: func() (string, error) { return Round("current", -1) }, code: gcode.CodeInvalidParameter},
+		{name: "round many digits", call: func() (string, error) { return Round("current", 1, 2) }, code: gcode.CodeInvalidParameter},
+		{name: "floor", call: func() (string, error) { return Floor("voltage") }, want: "FLOOR(voltage)"},
+		{name: "ceil", call: func() (string, error) { return Ceil("MAX(voltage)") }, want: "CEIL(MAX(voltage))"},
+		{name: "abs", call: func() (string, error) { return Abs("phase") }, want: "ABS(phase)"},
+		{name: "abs empty", call: func() (string, error) { return Abs("") }, code: gcode.CodeInvalidParameter},
+	})
+}
+
+func TestAPercentile(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "default",
+			call: func() (string, error) { return APercentile("current", 99) },
+			want: "APERCENTILE(current, 99)",
+		},
+		{
+			name: "default algorithm",
+			call: func() (string, error) { return APercentile("current", 50, AlgoDefault) },
+			want: "APERCENTILE(current, 50, 'default')",
+		},
+		{
+			name: "t-digest algorithm",
+			call: func() (string, error) { return APercentile("current", 99.9, AlgoTDigest) },
+			want: "APERCENTILE(current, 99.9, 't-digest')",
+		},
+		{
+			name: "invalid algorithm",
+			call: func() (string, error) { return APercentile("current", 99, "tdigest") },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "percentile out of range",
+			call: func() (string, error) { return APercentile("current", 101) },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "negative percentile",
+			call: func() (string, error) { return APercentile("current", -1, AlgoTDigest) },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestTimeArithmetic(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 8, 0, 0, 0, time.UTC)
+	runFuncCases(t, []funcCase{
+		{
+			name: "timediff",
+			call: func() (string, error) { return TimeDiff("ts", ts, "1s") },
+			want: "TIMEDIFF(ts, '2023-01-01T08:00:00Z', 1s)",
+		},
+		{
+			name: "timediff without unit",
+			call: func() (string, error) { return TimeDiff(gtime.NewFromTime(ts), "NOW()") },
+			want: "TIMEDIFF('2023-01-01T08:00:00Z', NOW())",
+		},
+		{
+			name: "timediff invalid unit",
+			call: func() (string, error) { return TimeDiff("ts", "NOW()", "1n") },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "timediff invalid timestamp",
+			call: func() (string, error) { return TimeDiff("ts", 1) },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "timediff nil timestamp",
+			call: func() (string, error) { return TimeDiff("ts", (*gtime.Time)(nil)) },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "timeadd",
+			call: func() (string, error) { return TimeAdd(ts, "1d") },
+			want: "('2023-01-01T08:00:00Z' + 1d)",
+		},
+		{
+			name: "timesub",
+			call: func() (string, error) { return TimeSub("NOW()", "1h") },
+			want: "(NOW() - 1h)",
+		},
+		{
+			name: "timesub invalid duration",
+			call: func() (string, error) { return TimeSub("NOW()", "1 h") },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestTimeDiffStatement(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	diff, err := TimeDiff("ts", "NOW()", "1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.Model("meters").Ctx(context.Background()).Fields(diff).Where(diff + " > 60").All(); err != nil {
+		t.Fatal(err)
+	}
+	sqls := server.Sqls()
+	want := "SELECT TIMEDIFF(ts,NOW(),1s) FROM `meters` WHERE TIMEDIFF(ts, NOW(), 1s) > 60"
+	if got := sqls[len(sqls)-1]; got != want {
+		t.Fatalf("got sql %q, want %q", got, want)
+	}
+}
+
+func TestCast(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{name: "varchar", call: func() (string, error) { return Cast("location", "VARCHAR(32)") }, want: "CAST(location AS VARCHAR(32))"},
+		{name: "lower case", call: func() (string, error) { return Cast("location", "nchar( 8 )") }, want: "CAST(location AS NCHAR(8))"},
+		{name: "binary", call: func() (string, error) { return Cast("name", "BINARY(16)") }, want: "CAST(name AS BINARY(16))"},
+		{name: "fixed type", call: func() (string, error) { return Cast("voltage", "bigint unsigned") }, want: "CAST(voltage AS BIGINT UNSIGNED)"},
+		{name: "zero length", call: func() (string, error) { return Cast("location", "VARCHAR(0)") }, code: gcode.CodeInvalidParameter},
+		{name: "no length", call: func() (string, error) { return Cast("location", "VARCHAR") }, code: gcode.CodeInvalidParameter},
+		{name: "invalid type", call: func() (string, error) { return Cast("location", "TEXT") }, code: gcode.CodeInvalidParameter},
+		{name: "invalid expression", call: func() (string, error) { return Cast("location)", "INT") }, code: gcode.CodeInvalidParameter},
+	})
+}
+
+func TestTruncateTsPrecision(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{name: "s", call: func() (string, error) { return TruncateTsPrecision("ts", "s") }, want: "TIMETRUNCATE(ts, 1s)"},
+		{name: "ms", call: func() (string, error) { return TruncateTsPrecision("ts", "ms") }, want: "TIMETRUNCATE(ts, 1a)"},
+		{name: "us", call: func() (string, error) { return TruncateTsPrecision("ts", "us") }, want: "TIMETRUNCATE(ts, 1u)"},
+		{name: "ns", call: func() (string, error) { return TruncateTsPrecision("ts", "ns") }, want: "TIMETRUNCATE(ts, 1b)"},
+		{name: "unknown precision", call: func() (string, error) { return TruncateTsPrecision("ts", "m") }, code: gcode.CodeInvalidParameter},
+		{name: "upper case precision", call: func() (string, error) { return TruncateTsPrecision("ts", "MS") }, code: gcode.CodeInvalidParameter},
+		{name: "empty precision", call: func() (string, error) { return TruncateTsPrecision("ts", "") }, code: gcode.CodeInvalidParameter},
+		{name: "invalid expression", call: func() (string, error) { return TruncateTsPrecision("ts)", "ms") }, code: gcode.CodeInvalidParameter},
+	})
+}
+
+func TestTruncateTsPrecisionResult(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse {
+		// The server truncates the ns timestamp to ms.
+		return mockRecords([]string{"timetruncate(ts, 1a)"}, []interface{}{ts.Truncate(time.Millisecond)})
+	})
+	field, err := TruncateTsPrecision("ts", "ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := d.GetValue(context.Background(), "SELECT "+field+" FROM d1001 LIMIT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.Time(); !got.Equal(time.Date(2023, 1, 1, 0, 0, 0, 123000000, time.UTC)) {
+		t.Fatalf("got %v, want the timestamp truncated to ms", got)
+	}
+	if sqls := server.Sqls(); sqls[0] != "SELECT TIMETRUNCATE(ts, 1a) FROM d1001 LIMIT 1" {
+		t.Fatalf("got sql %q", sqls[0])
+	}
+}
+
+func TestCastCondition(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{name: "comparison", call: func() (string, error) { return CastCondition("current > 10") }, want: "CAST((current > 10) AS INT)"},
+		{
+			name: "logical",
+			call: func() (string, error) { return CastCondition("voltage < 200 OR voltage > 240") },
+			want: "CAST((voltage < 200 OR voltage > 240) AS INT)",
+		},
+		{name: "is null", call: func() (string, error) { return CastCondition("phase IS NULL") }, want: "CAST((phase IS NULL) AS INT)"},
+		{name: "like", call: func() (string, error) { return CastCondition("location LIKE 'Cal%'") }, want: "CAST((location LIKE 'Cal%') AS INT)"},
+		{name: "type", call: func() (string, error) { return CastCondition("current > 10", " bigint ") }, want: "CAST((current > 10) AS BIGINT)"},
+		{name: "unsigned type", call: func() (string, error) { return CastCondition("current > 10", "TINYINT UNSIGNED") }, want: "CAST((current > 10) AS TINYINT UNSIGNED)"},
+		{
+			name: "summed",
+			call: func() (string, error) {
+				overload, err := CastCondition("current > 10")
+				if err != nil {
+					return "", err
+				}
+				return Sum(overload)
+			},
+			want: "SUM(CAST((current > 10) AS INT))",
+		},
+		{name: "not a condition", call: func() (string, error) { return CastCondition("current") }, code: gcode.CodeInvalidParameter},
+		{name: "non-integer type", call: func() (string, error) { return CastCondition("current > 10", "DOUBLE") }, code: gcode.CodeInvalidParameter},
+		{name: "unbalanced", call: func() (string, error) { return CastCondition("(current > 10") }, code: gcode.CodeInvalidParameter},
+		{name: "separator", call: func() (string, error) { return CastCondition("current > 10; DROP TABLE meters") }, code: gcode.CodeInvalidParameter},
+	})
+}
+
+func TestCastTruncatedResult(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse {
+		// The server truncates the string to the length of the cast type.
+		return mockRecords([]string{"cast(location as varchar(8))"}, []interface{}{[]byte("Californ")})
+	})
+	field, err := Cast("location", "VARCHAR(8)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := d.GetValue(context.Background(), "SELECT "+field+" FROM meters LIMIT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.String() != "Californ" {
+		t.Fatalf("got %q, want the truncated string", value.String())
+	}
+	if sqls := server.Sqls(); sqls[0] != "SELECT CAST(location AS VARCHAR(8)) FROM meters LIMIT 1" {
+		t.Fatalf("got sql %q", sqls[0])
+	}
+}
+
+func TestDerivative(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "per second",
+			call: func() (string, error) { return Derivative("bytes", time.Second, true) },
+			want: "DERIVATIVE(bytes, 1s, 1)",
+		},
+		{
+			name: "per minute keeping negative",
+			call: func() (string, error) { return Derivative("bytes", time.Minute, false) },
+			want: "DERIVATIVE(bytes, 1m, 0)",
+		},
+		{
+			name: "per 90 minutes",
+			call: func() (string, error) { return Derivative("bytes", 90*time.Minute, true) },
+			want: "DERIVATIVE(bytes, 90m, 1)",
+		},
+		{
+			name: "per day",
+			call: func() (string, error) { return Derivative("bytes", 24*time.Hour, false) },
+			want: "DERIVATIVE(bytes, 1d, 0)",
+		},
+		{
+			name: "sub-second unit",
+			call: func() (string, error) { return Derivative("bytes", 500*time.Millisecond, true) },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "fractional seconds unit",
+			call: func() (string, error) { return Derivative("bytes", 1500*time.Millisecond, true) },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestFormatDuration(t *testing.T) {
+	cases := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{48 * time.Hour, "2d"},
+		{90 * time.Minute, "90m"},
+		{1500 * time.Millisecond, "1500a"},
+		{3 * time.Microsecond, "3u"},
+		{7 * time.Nanosecond, "7b"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			if got := formatDuration(c.duration); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{name: "plain", call: func() (string, error) { return Sum("voltage") }, want: "SUM(voltage)"},
+		{name: "bigint", call: func() (string, error) { return Sum("voltage", "BIGINT") }, want: "SUM(CAST(voltage AS BIGINT))"},
+		{name: "double", call: func() (string, error) { return Sum("voltage", "double") }, want: "SUM(CAST(voltage AS DOUBLE))"},
+		{
+			name: "unsigned",
+			call: func() (string, error) { return Sum("counter", "bigint unsigned") },
+			want: "SUM(CAST(counter AS BIGINT UNSIGNED))",
+		},
+		{name: "invalid type", call: func() (string, error) { return Sum("voltage", "TEXT") }, code: gcode.CodeInvalidParameter},
+		{name: "invalid expression", call: func() (string, error) { return Sum("voltage)") }, code: gcode.CodeInvalidParameter},
+	})
+}
+
+func TestSumStatement(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(func(mockQuery) mockResponse {
+		return mockRecords([]string{"total"}, []interface{}{int64(4294967296)})
+	}))
+	sum, err := Sum("voltage", "BIGINT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := d.Model("meters").Fields(sum + " AS total").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Int64() != 4294967296 {
+		t.Fatalf("got %v, want the sum beyond the range of INT", value)
+	}
+	sqls := server.Sqls()
+	if want := "SELECT SUM(CAST(voltage AS BIGINT)) AS total FROM `meters` LIMIT 1"; sqls[len(sqls)-1] != want {
+		t.Fatalf("got sql %q, want %q", sqls[len(sqls)-1], want)
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	cases := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "plain", s: "beijing", want: "'beijing'"},
+		{name: "empty", s: "", want: "''"},
+		{name: "quote", s: "O'Brien", want: `'O\'Brien'`},
+		{name: "escape", s: `C:\data`, want: `'C:\\data'`},
+		{name: "escaped quote", s: `\'`, want: `'\\\''`},
+		{name: "double quote", s: `say "hi"`, want: `'say "hi"'`},
+		{name: "like wildcards", s: "d10%_", want: "'d10%_'"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := quoteString(c.s); got != c.want {
+				t.Fatalf("got %s, want %s", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// Insert does "INSERT INTO ..." statement for the table, the same as gdb.Core.Insert, except that the
+// keys of the records of `data` are validated against the table fields if Option.ValidateSchema is enabled,
+// before gdb.Model drops the unknown keys.
+func (d *Driver) Insert(ctx context.Context, table string, data interface{}, batch ...int) (sql.Result, error) {
+	if d.option.ValidateSchema {
+		list, err := d.toRecordList(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		if err = d.checkRecordColumns(ctx, table, list); err != nil {
+			return nil, err
+		}
+	}
+	return d.Core.Insert(ctx, table, data, batch...)
+}
+
+// Upsert inserts `data` into `table`, which overwrites the existing rows with the same primary timestamp.
+// The `data` can be a map, struct, or slice of them. The optional parameter `batch` specifies the batch
+// count of the records for each INSERT statement.
+//
+// It is a plain insert, as TDengine identifies rows by the primary timestamp and overwrites the row on
+// duplicate timestamp, see UpdateMode for the behavior of the databases, so that there's no need for
+// the Save/Replace operations, which are not supported by the driver. As an upsert without the primary
+// timestamp inserts a new row at current time instead of overwriting, it returns an error of code
+// gcode.CodeMissingParameter if any record has no primary timestamp, without inserting any records.
+func (d *Driver) Upsert(ctx context.Context, table string, data interface{}, batch ...int) (sql.Result, error) {
+	list, err := d.toRecordList(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, `no data for upsert`)
+	}
+	tsColumn, err := d.primaryTsColumn(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	if d.option.ValidateSchema {
+		if err = d.checkRecordColumns(ctx, table, list); err != nil {
+			return nil, err
+		}
+	}
+	for i, record := range list {
+		if !hasColumn(record, tsColumn) {
+			return nil, gerror.NewCodef(
+				gcode.CodeMissingParameter,
+				`primary timestamp "%s" is required for upsert, but missing in record %d`, tsColumn, i,
+			)
+		}
+	}
+	model := d.Model(table).Ctx(ctx).Data(list)
+	if len(batch) > 0 && batch[0] > 0 {
+		model = model.Batch(batch[0])
+	}
+	return model.Insert()
+}
+
+// insertResult is the result of an INSERT statement.
+type insertResult struct {
+	sql.Result
+}
+
+// LastInsertId returns an error of code gcode.CodeNotSupported, as TDengine has no auto-increment keys,
+// the rows are identified by the primary timestamp.
+func (r *insertResult) LastInsertId() (int64, error) {
+	return 0, gerror.NewCode(
+		gcode.CodeNotSupported,
+		`LastInsertId is not supported by taossql driver, as TDengine has no auto-increment keys`,
+	)
+}
+
+// toRecordList converts `data` that is a map, struct, or slice of them to record list.
+// It returns an error of code gcode.CodeInvalidParameter if any item is not a map or struct,
+// or fails to convert, see convertDataForRecord.
+func (d *Driver) toRecordList(ctx context.Context, data interface{}) (gdb.List, error) {
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	var items []interface{}
+	switch rv.Kind() {
+	case reflect.Invalid, reflect.Ptr:
+		return nil, nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+	default:
+		items = []interface{}{data}
+	}
+	list := make(gdb.List, 0, len(items))
+	for i, item := range items {
+		record, err := d.convertDataForRecord(ctx, item)
+		if err != nil {
+			return nil, gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid record at index %d`, i)
+		}
+		if record == nil {
+			return nil, gerror.NewCodef(
+				gcode.CodeInvalidParameter, `invalid record of type %T at index %d, which should be map or struct`, item, i,
+			)
+		}
+		list = append(list, record)
+	}
+	return list, nil
+}
+
+// primaryTsColumn retrieves and returns the primary timestamp column of `table`, which is always its first column, see TableFields.
+func (d *Driver) primaryTsColumn(ctx context.Context, table string) (string, error) {
+	fields, err := d.TableFields(withoutClause(ctx), table)
+	if err != nil {
+		return "", err
+	}
+	for _, field := range fields {
+		if field.Key == fieldKeyPrimary {
+			return field.Name, nil
+		}
+	}
+	return "", gerror.NewCodef(gcode.CodeNotFound, `primary timestamp of table "%s" not found`, table)
+}
+
+// hasColumn checks whether `record` has non-nil value of `column` case-insensitively.
+func hasColumn(record gdb.Map, column string) bool {
+	for k, v := range record {
+		if gstr.Equal(k, column) {
+			return v != nil
+		}
+	}
+	return false
+}
+
+// SubtableRows is the rows of a subtable for function InsertAutoCreate.
+type SubtableRows struct {
+	Subtable string      // Name of the subtable, which is created with Tags if it does not exist.
+	Tags     gdb.Map     // Tag values of the subtable, keyed by the tag names.
+	Data     interface{} // Rows of the subtable, which can be a map, struct, or slice of them.
+}
+
+// InsertAutoCreate inserts the rows of `subtables` of super table `stable` by multi-table INSERT statements
+// with `USING ... TAGS`, which create the subtables that do not exist on the fly, like:
+// INSERT INTO d1 USING meters (location) TAGS (?) (ts,current) VALUES (?,?) d2 USING meters ...
+//
+// Each statement contains at most Option.MaxSubtablesPerBatch subtables and Option.MaxRowsPerBatch rows,
+// and the insert is split into multiple statements if exceeded. Note that the statements are not atomic,
+// the subtables and rows of the succeeded statements are kept if any later statement fails.
+// The subtable names are sanitized by SanitizeTableName if Option.SanitizeSubtableNames is enabled.
+func (d *Driver) InsertAutoCreate(ctx context.Context, stable string, subtables []SubtableRows) (sql.Result, error) {
+	if stable == "" {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, `super table name is required for insert`)
+	}
+	return d.doMultiInsert(ctx, stable, subtables)
+}
+
+// BatchInsert inserts the rows of `groups` that are keyed by the subtable names by multi-table INSERT statements,
+// like: INSERT INTO d1 (ts,current) VALUES (?,?) d2 (ts,current) VALUES (?,?), which writes to many subtables
+// in one round trip. The subtables should exist, see InsertAutoCreate for creating them on the fly.
+//
+// Each statement contains at most Option.MaxSubtablesPerBatch subtables and Option.MaxRowsPerBatch rows,
+// and the insert is split into multiple statements if exceeded, in the order of the subtable names.
+// Note that the statements are not atomic, the rows of the succeeded statements are kept if any later
+// statement fails.
+func (d *Driver) BatchInsert(ctx context.Context, groups map[string]gdb.List) (sql.Result, error) {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	subtables := make([]SubtableRows, len(names))
+	for i, name := range names {
+		subtables[i] = SubtableRows{Subtable: name, Data: groups[name]}
+	}
+	return d.doMultiInsert(ctx, "", subtables)
+}
+
+// doMultiInsert inserts the rows of `subtables` by multi-table INSERT statements, with `USING stable TAGS`
+// if `stable` is not empty. The rows of a subtable are split into multiple clauses if they exceed the max
+// rows of a statement.
+func (d *Driver) doMultiInsert(ctx context.Context, stable string, subtables []SubtableRows) (sql.Result, error) {
+	if len(subtables) == 0 {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, `no subtables for insert`)
+	}
+	maxSubtables := d.option.MaxSubtablesPerBatch
+	if maxSubtables <= 0 {
+		maxSubtables = defaultMaxSubtablesPerBatch
+	}
+	maxRows := d.option.MaxRowsPerBatch
+	if maxRows <= 0 {
+		maxRows = defaultMaxRowsPerBatch
+	}
+	var (
+		batchResult = new(gdb.SqlResult)
+		clauses     = make([]string, 0, maxSubtables)
+		params      []interface{}
+		rows        int
+	)
+	flush := func() error {
+		result, err := d.Exec(ctx, `INSERT INTO `+gstr.Join(clauses, " "), params...)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return gerror.WrapCode(gcode.CodeDbOperationError, err, `sql.Result.RowsAffected failed`)
+		}
+		batchResult.Result = result
+		batchResult.Affected += affected
+		clauses, params, rows = clauses[:0], nil, 0
+		return nil
+	}
+	for _, subtable := range subtables {
+		if subtable.Subtable == "" {
+			return nil, gerror.NewCode(gcode.CodeMissingParameter, `subtable name is required for insert`)
+		}
+		list, err := d.toRecordList(ctx, subtable.Data)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, gerror.NewCodef(gcode.CodeMissingParameter, `no data for subtable "%s"`, subtable.Subtable)
+		}
+		for start := 0; start < len(list); start += maxRows {
+			end := start + maxRows
+			if end > len(list) {
+				end = len(list)
+			}
+			if len(clauses) >= maxSubtables || (len(clauses) > 0 && rows+end-start > maxRows) {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			clause, clauseParams, err := d.formatInsertClause(ctx, stable, subtable, list[start:end])
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, clause)
+			params = append(params, clauseParams...)
+			rows += end - start
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return &insertResult{Result: batchResult}, nil
+}
+
+// InsertUsing inserts `data` into subtable `subtable` of super table `stable` by the statement
+// `INSERT INTO subtable USING stable (tags) TAGS (...) (columns) VALUES (...)`, which creates the subtable
+// with `tags` on the fly if it does not exist. The tag values are converted by ConvertDataForRecord and passed
+// as arguments, like the column values.
+//
+// The optional parameter `batch` specifies the batch count of the records for each INSERT statement,
+// which is Option.MaxRowsPerBatch in default.
+func (d *Driver) InsertUsing(ctx context.Context, subtable, stable string, tags gdb.Map, data gdb.List, batch ...int) (sql.Result, error) {
+	size := len(data)
+	if len(batch) > 0 && batch[0] > 0 {
+		size = batch[0]
+	}
+	if size == 0 {
+		return nil, gerror.NewCodef(gcode.CodeMissingParameter, `no data for subtable "%s"`, subtable)
+	}
+	batchResult := new(gdb.SqlResult)
+	for start := 0; start < len(data); start += size {
+		end := start + size
+		if end > len(data) {
+			end = len(data)
+		}
+		result, err := d.InsertAutoCreate(ctx, stable, []SubtableRows{{
+			Subtable: subtable,
+			Tags:     tags,
+			Data:     data[start:end],
+		}})
+		if err != nil {
+			return result, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return result, err
+		}
+		batchResult.Result = result
+		batchResult.Affected += affected
+	}
+	return &insertResult{Result: batchResult}, nil
+}
+
+// formatInsertClause formats the `<subtable> [USING <stable> (tags) TAGS (...)] (columns) VALUES (...)...`
+// clause of `list` of a multi-table INSERT statement, and returns it with its parameters.
+// The USING part is formatted only if `stable` is not empty, in which case the subtable name is sanitized if
+// Option.SanitizeSubtableNames is enabled. The columns are the keys of the first row, the same as the inserts
+// of package gdb, and the keys of the rows and tags are validated if Option.ValidateSchema is enabled.
+func (d *Driver) formatInsertClause(ctx context.Context, stable string, rows SubtableRows, list gdb.List) (string, []interface{}, error) {
+	if d.option.ValidateSchema {
+		table, records := rows.Subtable, list
+		if stable != "" {
+			table, records = stable, append(gdb.List{rows.Tags}, list...)
+		}
+		if err := d.checkRecordColumns(ctx, table, records); err != nil {
+			return "", nil, err
+		}
+	}
+	var (
+		params []interface{}
+		clause = d.QuotePrefixTableName(rows.Subtable)
+		keys   = sortedKeys(list[0])
+		values = make([]string, 0, len(list))
+	)
+	if stable != "" && d.option.SanitizeSubtableNames {
+		clause = d.QuoteWord(SanitizeTableName(rows.Subtable))
+	}
+	if stable != "" {
+		if len(rows.Tags) == 0 {
+			return "", nil, gerror.NewCodef(gcode.CodeMissingParameter, `no tags for subtable "%s"`, rows.Subtable)
+		}
+		tags, err := d.convertDataForRecord(ctx, rows.Tags)
+		if err != nil {
+			return "", nil, gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid tags of subtable "%s"`, rows.Subtable)
+		}
+		var (
+			tagKeys   = sortedKeys(tags)
+			tagValues = make([]string, 0, len(tagKeys))
+		)
+		for _, k := range tagKeys {
+			tagValues = append(tagValues, formatValueHolder(tags[k], &params))
+		}
+		clause += fmt.Sprintf(
+			` USING %s (%s) TAGS (%s)`,
+			d.QuotePrefixTableName(stable), d.quoteColumns(tagKeys), gstr.Join(tagValues, ","),
+		)
+	}
+	for _, record := range list {
+		holders := make([]string, 0, len(keys))
+		for _, k := range keys {
+			holders = append(holders, formatValueHolder(record[k], &params))
+		}
+		values = append(values, "("+gstr.Join(holders, ",")+")")
+	}
+	clause += fmt.Sprintf(` (%s) VALUES %s`, d.quoteColumns(keys), gstr.Join(values, " "))
+	return clause, params, nil
+}
+
+// quoteColumns quotes and joins `columns` with char ','.
+func (d *Driver) quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = d.QuoteWord(column)
+	}
+	return gstr.Join(quoted, ",")
+}
+
+// formatValueHolder returns the placeholder of `value` and appends it to `params`,
+// or returns `value` itself if it's gdb.Raw, the same as the inserts of package gdb.
+func formatValueHolder(value interface{}, params *[]interface{}) string {
+	if raw, ok := value.(gdb.Raw); ok {
+		return string(raw)
+	}
+	*params = append(*params, value)
+	return "?"
+}
+
+// sortedKeys returns the keys of `record` in ascending order.
+func sortedKeys(record gdb.Map) []string {
+	keys := make([]string, 0, len(record))
+	for k := range record {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
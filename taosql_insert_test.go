@@ -0,0 +1,552 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// insertedRecords returns the records of INSERT statement `q` keyed by column name,
+// which is of the columns of the first table, like gdb.Core.DoInsert renders.
+func insertedRecords(q mockQuery) []map[string]interface{} {
+	match, _ := gregex.MatchString("(?i)^INSERT INTO [`\\w.]+\\(([^)]*)\\)", q.Sql)
+	if len(match) == 0 {
+		return nil
+	}
+	var (
+		columns = gstr.SplitAndTrim(gstr.Replace(match[1], "`", ""), ",")
+		records []map[string]interface{}
+	)
+	for i := 0; i+len(columns) <= len(q.Args); i += len(columns) {
+		record := make(map[string]interface{}, len(columns))
+		for j, column := range columns {
+			record[column] = q.Args[i+j]
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestUpsert(t *testing.T) {
+	var (
+		ts    = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		epoch = ts.UnixNano() / int64(time.Millisecond)
+		// rows is the content of table d1001 keyed by the primary timestamp, which overwrites the row
+		// of the same timestamp as TDengine does.
+		rows = make(map[int64]interface{})
+	)
+	d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+		if gstr.HasPrefix(q.Sql, "INSERT") {
+			record := insertedRecords(q)[0]
+			rows[record["ts"].(int64)] = record["current"]
+			return mockResponse{Affected: 1}
+		}
+		return mockResponse{}
+	}))
+	ctx := context.Background()
+	for _, current := range []float64{10.5, 11.5} {
+		result, err := d.Upsert(ctx, "d1001", map[string]interface{}{"ts": ts, "current": current})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := result.RowsAffected(); n != 1 {
+			t.Fatalf("got %d rows affected, want 1", n)
+		}
+	}
+	if want := map[int64]interface{}{epoch: 11.5}; !reflect.DeepEqual(rows, want) {
+		t.Fatalf("got rows %v, want %v overwritten by the later upsert", rows, want)
+	}
+	for _, sql := range server.Sqls() {
+		if gstr.HasPrefix(sql, "INSERT") && !gregex.IsMatchString("^INSERT INTO `d1001`\\([`\\w,]+\\) VALUES", sql) {
+			t.Fatalf("got statement %q, want a plain INSERT", sql)
+		}
+	}
+
+	cases := []struct {
+		name string
+		data interface{}
+		code gcode.Code
+	}{
+		{name: "no data", data: []map[string]interface{}{}, code: gcode.CodeMissingParameter},
+		{
+			name: "missing timestamp",
+			data: []map[string]interface{}{{"ts": ts, "current": 1.0}, {"current": 2.0}},
+			code: gcode.CodeMissingParameter,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inserts := len(rows)
+			_, err := d.Upsert(ctx, "d1001", c.data)
+			checkCode(t, err, c.code)
+			if len(rows) != inserts {
+				t.Fatal("unexpected rows inserted")
+			}
+		})
+	}
+}
+
+func TestInsertAutoCreateSanitize(t *testing.T) {
+	cases := []struct {
+		name     string
+		option   Option
+		subtable string
+		want     string
+	}{
+		{name: "disabled", subtable: "d1001", want: "INSERT INTO `d1001` USING "},
+		{name: "enabled", option: Option{SanitizeSubtableNames: true}, subtable: "Dev.01 北", want: "INSERT INTO `_44ev_2e01_20_e5_8c_97` USING "},
+		{name: "enabled plain", option: Option{SanitizeSubtableNames: true}, subtable: "d1001", want: "INSERT INTO `d1001` USING "},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, c.option, metersHandler(nil))
+			subtables := []SubtableRows{{
+				Subtable: c.subtable,
+				Tags:     map[string]interface{}{"location": "beijing"},
+				Data:     map[string]interface{}{"ts": int64(1672531200000), "current": 10.5},
+			}}
+			if _, err := d.InsertAutoCreate(context.Background(), "meters", subtables); err != nil {
+				t.Fatal(err)
+			}
+			sqls := server.Sqls()
+			if got := sqls[len(sqls)-1]; !gstr.HasPrefix(got, c.want) {
+				t.Fatalf("got sql %q, want prefix %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestInsertResult(t *testing.T) {
+	var (
+		ts      = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		data    = gdb.List{{"ts": ts, "current": 10.3}, {"ts": ts.Add(time.Second), "current": 10.4}}
+		inserts = map[string]func(ctx context.Context, d *Driver) (sql.Result, error){
+			"model insert": func(ctx context.Context, d *Driver) (sql.Result, error) {
+				return d.Model("d1001").Ctx(ctx).Data(data).Insert()
+			},
+			"insert using": func(ctx context.Context, d *Driver) (sql.Result, error) {
+				return d.InsertUsing(ctx, "d1001", "meters", gdb.Map{"location": "beijing"}, data)
+			},
+			"batch insert": func(ctx context.Context, d *Driver) (sql.Result, error) {
+				return d.BatchInsert(ctx, map[string]gdb.List{"d1001": data[:1], "d1002": data[1:]})
+			},
+		}
+	)
+	for name, insert := range inserts {
+		t.Run(name, func(t *testing.T) {
+			d, _ := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+				return mockResponse{Affected: 2}
+			}))
+			result, err := insert(context.Background(), d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, err := result.RowsAffected(); err != nil || n != 2 {
+				t.Fatalf("got (%d, %v) rows affected, want 2", n, err)
+			}
+			id, err := result.LastInsertId()
+			checkCode(t, err, gcode.CodeNotSupported)
+			if id != 0 || !gstr.Contains(err.Error(), "auto-increment") {
+				t.Fatalf("got (%d, %v), want the error explaining no auto-increment keys", id, err)
+			}
+		})
+	}
+}
+
+func TestDoInsertEmptyList(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	link, err := d.MasterLink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.DoInsert(context.Background(), link, "d1001", gdb.List{}, gdb.DoInsertOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 0 {
+		t.Fatalf("got (%d, %v) rows affected, want 0", n, err)
+	}
+	_, err = result.LastInsertId()
+	checkCode(t, err, gcode.CodeNotSupported)
+	if sqls := server.Sqls(); len(sqls) != 0 {
+		t.Fatalf("got sqls %q, want none for empty list", sqls)
+	}
+}
+
+func TestDoInsertRowsAffected(t *testing.T) {
+	cases := []struct {
+		name  string
+		rows  int
+		batch int
+		want  []int
+	}{
+		{name: "single statement", rows: 3, want: []int{3}},
+		{name: "batches", rows: 5, batch: 2, want: []int{2, 2, 1}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+				return mockResponse{Affected: int64(gstr.Count(q.Sql, "(?,?)"))}
+			}))
+			var data gdb.List
+			for i := 0; i < c.rows; i++ {
+				data = append(data, gdb.Map{"ts": int64(1672531200000 + i), "current": 10.5})
+			}
+			model := d.Model("d1001").Ctx(context.Background()).Data(data)
+			if c.batch > 0 {
+				model = model.Batch(c.batch)
+			}
+			result, err := model.Insert()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := result.RowsAffected(); n != int64(c.rows) {
+				t.Fatalf("got %d rows affected, want %d", n, c.rows)
+			}
+			var got []int
+			for _, sql := range server.Sqls() {
+				if gstr.HasPrefix(sql, "INSERT") {
+					got = append(got, gstr.Count(sql, "(?,?)"))
+				}
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got rows per statement %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestInsertUnsupportedOptions(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, metersHandler(nil))
+	data := gdb.Map{"ts": time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "current": 10.3}
+	_, err := d.Model("d1001").Data(data).Save()
+	checkCode(t, err, gcode.CodeNotSupported)
+	_, err = d.Model("d1001").Data(data).Replace()
+	checkCode(t, err, gcode.CodeNotSupported)
+}
+
+func TestInsertAutoCreateBatches(t *testing.T) {
+	cases := []struct {
+		name      string
+		option    Option
+		subtables int
+		want      []int
+	}{
+		{name: "default cap", subtables: 5, want: []int{5}},
+		{name: "split at cap", option: Option{MaxSubtablesPerBatch: 2}, subtables: 5, want: []int{2, 2, 1}},
+		{name: "exactly cap", option: Option{MaxSubtablesPerBatch: 5}, subtables: 5, want: []int{5}},
+		{name: "cap of one", option: Option{MaxSubtablesPerBatch: 1}, subtables: 3, want: []int{1, 1, 1}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, c.option, metersHandler(func(q mockQuery) mockResponse {
+				return mockResponse{Affected: int64(gstr.Count(q.Sql, " USING "))}
+			}))
+			subtables := make([]SubtableRows, c.subtables)
+			for i := range subtables {
+				subtables[i] = SubtableRows{
+					Subtable: fmt.Sprintf("d%d", 1001+i),
+					Tags:     map[string]interface{}{"location": "beijing"},
+					Data:     map[string]interface{}{"ts": int64(1672531200000), "current": 10.5},
+				}
+			}
+			result, err := d.InsertAutoCreate(context.Background(), "meters", subtables)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, sql := range server.Sqls() {
+				if gstr.HasPrefix(sql, "INSERT") {
+					got = append(got, gstr.Count(sql, " USING "))
+				}
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got subtables per statement %v, want %v", got, c.want)
+			}
+			if n, _ := result.RowsAffected(); n != int64(c.subtables) {
+				t.Fatalf("got %d rows affected, want %d", n, c.subtables)
+			}
+		})
+	}
+}
+
+func TestBatchInsert(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+		return mockResponse{Affected: int64(gstr.Count(q.Sql, "(?,?)"))}
+	}))
+	result, err := d.BatchInsert(context.Background(), map[string]gdb.List{
+		"d1002": {{"ts": int64(1672531200000), "current": 11.5}},
+		"d1001": {{"ts": int64(1672531200000), "current": 10.3}, {"ts": int64(1672531201000), "current": 10.4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := result.RowsAffected(); n != 3 {
+		t.Fatalf("got %d rows affected, want 3", n)
+	}
+	q := server.Queries()[len(server.Queries())-1]
+	want := "INSERT INTO `d1001` (`current`,`ts`) VALUES (?,?) (?,?) `d1002` (`current`,`ts`) VALUES (?,?)"
+	if q.Sql != want {
+		t.Fatalf("got sql %q, want %q", q.Sql, want)
+	}
+	wantArgs := []interface{}{10.3, int64(1672531200000), 10.4, int64(1672531201000), 11.5, int64(1672531200000)}
+	if !reflect.DeepEqual(q.Args, wantArgs) {
+		t.Fatalf("got args %#v, want %#v", q.Args, wantArgs)
+	}
+	_, err = d.BatchInsert(context.Background(), nil)
+	checkCode(t, err, gcode.CodeMissingParameter)
+}
+
+func TestBatchInsertSplit(t *testing.T) {
+	cases := []struct {
+		name   string
+		option Option
+		rows   []int
+		want   []int
+	}{
+		{name: "below row cap", option: Option{MaxRowsPerBatch: 4}, rows: []int{2, 1}, want: []int{3}},
+		{name: "exactly row cap", option: Option{MaxRowsPerBatch: 4}, rows: []int{2, 2}, want: []int{4}},
+		{name: "over row cap", option: Option{MaxRowsPerBatch: 4}, rows: []int{2, 3}, want: []int{2, 3}},
+		{name: "subtable over row cap", option: Option{MaxRowsPerBatch: 2}, rows: []int{5}, want: []int{2, 2, 1}},
+		{name: "subtable cap", option: Option{MaxSubtablesPerBatch: 2}, rows: []int{1, 1, 1}, want: []int{2, 1}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, c.option, metersHandler(func(q mockQuery) mockResponse {
+				return mockResponse{Affected: int64(gstr.Count(q.Sql, "(?,?)"))}
+			}))
+			var (
+				groups = make(map[string]gdb.List, len(c.rows))
+				total  int
+			)
+			for i, n := range c.rows {
+				var list gdb.List
+				for j := 0; j < n; j++ {
+					list = append(list, gdb.Map{"ts": int64(1672531200000 + j), "current": 10.5})
+				}
+				groups[fmt.Sprintf("d%d", 1001+i)] = list
+				total += n
+			}
+			result, err := d.BatchInsert(context.Background(), groups)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, sql := range server.Sqls() {
+				if gstr.HasPrefix(sql, "INSERT") {
+					got = append(got, gstr.Count(sql, "(?,?)"))
+				}
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got rows per statement %v, want %v", got, c.want)
+			}
+			if n, _ := result.RowsAffected(); n != int64(total) {
+				t.Fatalf("got %d rows affected, want %d", n, total)
+			}
+		})
+	}
+}
+
+func TestPrimaryTsColumn(t *testing.T) {
+	cases := []struct {
+		name string
+		desc mockResponse
+		want string
+		code gcode.Code
+	}{
+		{name: "ts", desc: mockMetersDesc, want: "ts"},
+		{
+			name: "first column",
+			desc: mockRecords(
+				[]string{"field", "type", "length", "note"},
+				[]interface{}{"created", "TIMESTAMP", int64(8), ""},
+				[]interface{}{"ts", "BIGINT", int64(8), ""},
+			),
+			want: "created",
+		},
+		{name: "no columns", desc: mockRecords([]string{"field", "type", "length", "note"}), code: gcode.CodeNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, Option{}, func(mockQuery) mockResponse { return c.desc })
+			got, err := d.primaryTsColumn(context.Background(), "d1001")
+			checkCode(t, err, c.code)
+			if got != c.want {
+				t.Fatalf("got primary timestamp %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestInsertUsing(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+		return mockResponse{Affected: 1}
+	}))
+	tags := gdb.Map{"location": "California.SanFrancisco's", "groupid": 2}
+	result, err := d.InsertUsing(context.Background(), "d1001", "meters", tags, gdb.List{{"ts": ts, "current": 10.3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		t.Fatalf("got %d rows affected, want 1", n)
+	}
+	q := server.Queries()[len(server.Queries())-1]
+	want := "INSERT INTO `d1001` USING `meters` (`groupid`,`location`) TAGS (?,?) (`current`,`ts`) VALUES (?,?)"
+	if q.Sql != want {
+		t.Fatalf("got sql %q, want %q", q.Sql, want)
+	}
+	wantArgs := []interface{}{2, "California.SanFrancisco's", 10.3, ts.UnixNano() / int64(time.Millisecond)}
+	if !reflect.DeepEqual(q.Args, wantArgs) {
+		t.Fatalf("got args %#v, want %#v", q.Args, wantArgs)
+	}
+}
+
+func TestInsertUsingJSONTag(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	tags := gdb.Map{"info": gdb.Map{"model": "x1", "floor": 3}}
+	if _, err := d.InsertUsing(context.Background(), "d1001", "meters", tags, gdb.List{{"ts": int64(1672531200000)}}); err != nil {
+		t.Fatal(err)
+	}
+	q := server.Queries()[len(server.Queries())-1]
+	wantArgs := []interface{}{`{"floor":3,"model":"x1"}`, int64(1672531200000)}
+	if !reflect.DeepEqual(q.Args, wantArgs) {
+		t.Fatalf("got args %#v, want %#v", q.Args, wantArgs)
+	}
+}
+
+func TestInsertInvalidTags(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		tags = gdb.Map{"location": "beijing", "groupid": &failingValuer{2}}
+		data = gdb.List{{"ts": int64(1672531200000), "current": 10.5}}
+	)
+	cases := []struct {
+		name   string
+		insert func(d *Driver) error
+	}{
+		{
+			name: "using",
+			insert: func(d *Driver) error {
+				_, err := d.InsertUsing(ctx, "d1001", "meters", tags, data)
+				return err
+			},
+		},
+		{
+			name: "auto create",
+			insert: func(d *Driver) error {
+				_, err := d.InsertAutoCreate(ctx, "meters", []SubtableRows{{Subtable: "d1001", Tags: tags, Data: data}})
+				return err
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(nil))
+			err := c.insert(d)
+			checkCode(t, err, gcode.CodeInvalidParameter)
+			if !gstr.Contains(err.Error(), `invalid tags of subtable "d1001"`) {
+				t.Fatalf("got error %v, want the error of the tags", err)
+			}
+			for _, sql := range server.Sqls() {
+				if gstr.HasPrefix(sql, "INSERT") {
+					t.Fatalf("unexpected statement %q", sql)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatInsertClause(t *testing.T) {
+	list := gdb.List{{"ts": int64(1), "current": 10.5}, {"ts": int64(2), "current": gdb.Raw("NULL")}}
+	cases := []struct {
+		name       string
+		option     Option
+		stable     string
+		rows       SubtableRows
+		want       string
+		wantParams []interface{}
+		code       gcode.Code
+	}{
+		{
+			name:       "subtable",
+			rows:       SubtableRows{Subtable: "d1001"},
+			want:       "`d1001` (`current`,`ts`) VALUES (?,?) (NULL,?)",
+			wantParams: []interface{}{10.5, int64(1), int64(2)},
+		},
+		{
+			name:       "using",
+			stable:     "meters",
+			rows:       SubtableRows{Subtable: "d1001", Tags: gdb.Map{"location": "beijing", "groupid": 2}},
+			want:       "`d1001` USING `meters` (`groupid`,`location`) TAGS (?,?) (`current`,`ts`) VALUES (?,?) (NULL,?)",
+			wantParams: []interface{}{2, "beijing", 10.5, int64(1), int64(2)},
+		},
+		{
+			name:       "sanitized",
+			option:     Option{SanitizeSubtableNames: true},
+			stable:     "meters",
+			rows:       SubtableRows{Subtable: "d-1001", Tags: gdb.Map{"groupid": 2}},
+			want:       "`d_2d1001` USING `meters` (`groupid`) TAGS (?) (`current`,`ts`) VALUES (?,?) (NULL,?)",
+			wantParams: []interface{}{2, 10.5, int64(1), int64(2)},
+		},
+		{
+			name:   "no tags",
+			stable: "meters",
+			rows:   SubtableRows{Subtable: "d1001"},
+			code:   gcode.CodeMissingParameter,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, c.option, metersHandler(nil))
+			got, params, err := d.formatInsertClause(context.Background(), c.stable, c.rows, list)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				return
+			}
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+			if !reflect.DeepEqual(params, c.wantParams) {
+				t.Fatalf("got params %#v, want %#v", params, c.wantParams)
+			}
+		})
+	}
+}
+
+func TestInsertUsingBatch(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+		// Each row is of columns current and ts.
+		return mockResponse{Affected: int64(gstr.Count(q.Sql, "(?,?)"))}
+	}))
+	var data gdb.List
+	for i := 0; i < 5; i++ {
+		data = append(data, gdb.Map{"ts": int64(1672531200000 + i), "current": 10.5})
+	}
+	result, err := d.InsertUsing(context.Background(), "d1001", "meters", gdb.Map{"location": "beijing"}, data, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inserts int
+	for _, sql := range server.Sqls() {
+		if gstr.HasPrefix(sql, "INSERT") {
+			inserts++
+		}
+	}
+	if inserts != 3 {
+		t.Fatalf("got %d statements, want 3 of batch 2", inserts)
+	}
+	if n, _ := result.RowsAffected(); n != 5 {
+		t.Fatalf("got %d rows affected, want 5", n)
+	}
+	_, err = d.InsertUsing(context.Background(), "d1001", "meters", gdb.Map{"location": "beijing"}, nil)
+	checkCode(t, err, gcode.CodeMissingParameter)
+}
@@ -0,0 +1,58 @@
+package taosql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// Interval is the TDengine duration literal, like: 10s, 5m, 1d, which is accepted by the window and
+// interpolation clauses, like INTERVAL, SLIDING and EVERY. The untyped string constants are accepted as
+// Interval too, like: Clause.Interval("1d"), while the typed ones are created by NewInterval or IntervalOf.
+type Interval string
+
+// IntervalUnit is the unit of TDengine duration literal.
+type IntervalUnit string
+
+const (
+	UnitNanosecond  IntervalUnit = "b" // Nanosecond, which requires database precision ns.
+	UnitMicrosecond IntervalUnit = "u" // Microsecond, which requires database precision us or ns.
+	UnitMillisecond IntervalUnit = "a" // Millisecond.
+	UnitSecond      IntervalUnit = "s" // Second.
+	UnitMinute      IntervalUnit = "m" // Minute.
+	UnitHour        IntervalUnit = "h" // Hour.
+	UnitDay         IntervalUnit = "d" // Day.
+	UnitWeek        IntervalUnit = "w" // Week.
+	UnitMonth       IntervalUnit = "n" // Natural month, which has no fixed length.
+	UnitYear        IntervalUnit = "y" // Natural year, which has no fixed length.
+)
+
+// NewInterval creates and returns the duration literal of `n` `unit`, like: NewInterval(10, UnitSecond) for 10s.
+// It returns an error of code gcode.CodeInvalidParameter if `n` is not positive or `unit` is unknown.
+func NewInterval(n int64, unit IntervalUnit) (Interval, error) {
+	if n <= 0 {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid interval %d%s, it should be positive`, n, unit)
+	}
+	interval := Interval(fmt.Sprintf(`%d%s`, n, unit))
+	if err := checkDuration(interval.String()); err != nil {
+		return "", err
+	}
+	return interval, nil
+}
+
+// IntervalOf creates and returns the duration literal of `d` in the largest unit that divides it,
+// like: 90 * time.Minute for 90m, 1500 * time.Millisecond for 1500a.
+// It returns an error of code gcode.CodeInvalidParameter if `d` is not positive.
+func IntervalOf(d time.Duration) (Interval, error) {
+	if d <= 0 {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid interval %s, it should be positive`, d)
+	}
+	return Interval(formatDuration(d)), nil
+}
+
+// String returns the duration literal.
+func (i Interval) String() string {
+	return string(i)
+}
@@ -0,0 +1,78 @@
+package taosql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+func TestNewInterval(t *testing.T) {
+	cases := []struct {
+		name string
+		n    int64
+		unit IntervalUnit
+		want Interval
+		code gcode.Code
+	}{
+		{name: "seconds", n: 10, unit: UnitSecond, want: "10s"},
+		{name: "milliseconds", n: 500, unit: UnitMillisecond, want: "500a"},
+		{name: "microseconds", n: 20, unit: UnitMicrosecond, want: "20u"},
+		{name: "nanoseconds", n: 100, unit: UnitNanosecond, want: "100b"},
+		{name: "natural month", n: 1, unit: UnitMonth, want: "1n"},
+		{name: "zero", n: 0, unit: UnitSecond, code: gcode.CodeInvalidParameter},
+		{name: "negative", n: -1, unit: UnitDay, code: gcode.CodeInvalidParameter},
+		{name: "unknown unit", n: 1, unit: "x", code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := NewInterval(c.n, c.unit)
+			checkCode(t, err, c.code)
+			if got != c.want || got.String() != string(c.want) {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIntervalOf(t *testing.T) {
+	cases := []struct {
+		name     string
+		duration time.Duration
+		want     Interval
+		code     gcode.Code
+	}{
+		{name: "day", duration: 24 * time.Hour, want: "1d"},
+		{name: "minutes", duration: 90 * time.Minute, want: "90m"},
+		{name: "milliseconds", duration: 1500 * time.Millisecond, want: "1500a"},
+		{name: "microseconds", duration: 1500 * time.Microsecond, want: "1500u"},
+		{name: "nanoseconds", duration: 1500, want: "1500b"},
+		{name: "zero", duration: 0, code: gcode.CodeInvalidParameter},
+		{name: "negative", duration: -time.Second, code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := IntervalOf(c.duration)
+			checkCode(t, err, c.code)
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestClauseInterval(t *testing.T) {
+	var (
+		window = "10m"
+		minute = Interval("1m")
+	)
+	runClauseCases(t, []clauseCase{
+		{name: "constant", clause: NewClause().Interval("1h"), want: "INTERVAL(1h)"},
+		{name: "variable", clause: NewClause().Interval(Interval(window)).Sliding(minute), want: "INTERVAL(10m) SLIDING(1m)"},
+		{name: "invalid", clause: NewClause().Interval("1 hour"), code: gcode.CodeInvalidParameter},
+		{name: "zero", clause: NewClause().Interval("0s"), code: gcode.CodeInvalidParameter},
+		{name: "zero digits", clause: NewClause().Interval("00m"), code: gcode.CodeInvalidParameter},
+		{name: "zero sliding", clause: NewClause().Interval("10s").Sliding("0s"), code: gcode.CodeInvalidParameter},
+		{name: "zero every", clause: NewClause().Every("0s"), code: gcode.CodeInvalidParameter},
+	})
+}
@@ -0,0 +1,127 @@
+package taosql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// InsertJSON inserts JSON object `payload` into `table` as one row, like the device payloads received by HTTP,
+// in which the keys are the column or tag names, and the values are coerced to the column types of `table`.
+//
+// The JSON numbers are coerced to the numeric types, in which the integer types require integral numbers
+// within their widths, the JSON strings to the string types, the JSON booleans to BOOL only, and any JSON value
+// to JSON. The TIMESTAMP accepts the JSON strings of time, like ISO8601, or the JSON integers of epoch at the
+// database precision. The JSON nulls are inserted as NULL.
+//
+// It returns an error of code gcode.CodeInvalidParameter naming the key if the payload is malformed,
+// or any key is not a column of `table`, or its value mismatches the column type, without inserting the row.
+func (d *Driver) InsertJSON(ctx context.Context, table string, payload []byte) (sql.Result, error) {
+	var object map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid JSON payload`)
+	}
+	if len(object) == 0 {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, `no data in JSON payload`)
+	}
+	fields, err := d.TableFields(withoutClause(ctx), table)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]*gdb.TableField, len(fields))
+	for _, field := range fields {
+		columns[gstr.ToLower(field.Name)] = field
+	}
+	record := make(gdb.Map, len(object))
+	for key, value := range object {
+		field, ok := columns[gstr.ToLower(key)]
+		if !ok {
+			return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `unknown column "%s" for table %s`, key, table)
+		}
+		if record[field.Name], err = coerceJSONValue(value, field.Type); err != nil {
+			return nil, gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid value of key "%s"`, key)
+		}
+	}
+	return d.Model(table).Ctx(ctx).Data(record).Insert()
+}
+
+// integerBits are the bit sizes of the integer column types, which bound the JSON numbers coerced to them.
+var integerBits = map[string]int{
+	"tinyint":           8,
+	"smallint":          16,
+	"int":               32,
+	"bigint":            64,
+	"tinyint unsigned":  8,
+	"smallint unsigned": 16,
+	"int unsigned":      32,
+	"bigint unsigned":   64,
+	"timestamp":         64,
+}
+
+// coerceJSONValue converts decoded JSON value `value` to the Go value of column type `columnType`,
+// in which the JSON numbers are decoded as json.Number. The numbers of the integer types should be integral
+// and within the range of the column width, like -128 to 127 for TINYINT, or it returns an error rather than
+// letting the server truncate them. The JSON booleans are accepted for BOOL only, but not as 0 and 1 of the
+// numeric types, neither are the numbers 0 and 1 accepted for BOOL, so that a mismatched payload is reported.
+func coerceJSONValue(value interface{}, columnType string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	typeName := columnTypeName(columnType)
+	if typeName == "json" {
+		b, err := json.Marshal(value)
+		return string(b), err
+	}
+	switch v := value.(type) {
+	case json.Number:
+		if bits, ok := integerBits[typeName]; ok {
+			var (
+				n   interface{}
+				err error
+			)
+			if gstr.HasSuffix(typeName, " unsigned") {
+				n, err = strconv.ParseUint(v.String(), 10, bits)
+			} else {
+				n, err = strconv.ParseInt(v.String(), 10, bits)
+			}
+			if errors.Is(err, strconv.ErrRange) {
+				return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `JSON number %s is out of range of type %s`, v, columnType)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+		switch typeName {
+		case "float", "double":
+			return v.Float64()
+		}
+	case string:
+		switch typeName {
+		case "binary", "varchar", "nchar":
+			return v, nil
+		case "timestamp":
+			t, err := gtime.StrToTime(v)
+			if err != nil {
+				return nil, err
+			}
+			return t.Time, nil
+		}
+	case bool:
+		if typeName == "bool" {
+			return v, nil
+		}
+	}
+	return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `JSON value %v mismatches type %s`, value, columnType)
+}
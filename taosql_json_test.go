@@ -0,0 +1,117 @@
+package taosql
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+func TestCoerceJSONValue(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 8, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name       string
+		value      interface{}
+		columnType string
+		want       interface{}
+		wantErr    bool
+	}{
+		{name: "null", value: nil, columnType: "INT", want: nil},
+		{name: "tinyint", value: json.Number("-128"), columnType: "TINYINT", want: int64(-128)},
+		{name: "tinyint overflow", value: json.Number("128"), columnType: "TINYINT", wantErr: true},
+		{name: "smallint", value: json.Number("32767"), columnType: "SMALLINT", want: int64(32767)},
+		{name: "smallint overflow", value: json.Number("-32769"), columnType: "SMALLINT", wantErr: true},
+		{name: "int", value: json.Number("2147483647"), columnType: "INT", want: int64(2147483647)},
+		{name: "int overflow", value: json.Number("2147483648"), columnType: "INT", wantErr: true},
+		{name: "bigint", value: json.Number("-9223372036854775808"), columnType: "BIGINT", want: int64(-9223372036854775808)},
+		{name: "bigint overflow", value: json.Number("9223372036854775808"), columnType: "BIGINT", wantErr: true},
+		{name: "tinyint unsigned", value: json.Number("255"), columnType: "TINYINT UNSIGNED", want: uint64(255)},
+		{name: "tinyint unsigned overflow", value: json.Number("256"), columnType: "TINYINT UNSIGNED", wantErr: true},
+		{name: "smallint unsigned overflow", value: json.Number("65536"), columnType: "SMALLINT UNSIGNED", wantErr: true},
+		{name: "int unsigned", value: json.Number("4294967295"), columnType: "INT UNSIGNED", want: uint64(4294967295)},
+		{name: "int unsigned overflow", value: json.Number("4294967296"), columnType: "INT UNSIGNED", wantErr: true},
+		{name: "bigint unsigned", value: json.Number("18446744073709551615"), columnType: "BIGINT UNSIGNED", want: uint64(18446744073709551615)},
+		{name: "negative unsigned", value: json.Number("-1"), columnType: "INT UNSIGNED", wantErr: true},
+		{name: "fraction of integer", value: json.Number("1.5"), columnType: "INT", wantErr: true},
+		{name: "double", value: json.Number("10.3"), columnType: "DOUBLE", want: 10.3},
+		{name: "epoch timestamp", value: json.Number("1672560000000"), columnType: "TIMESTAMP", want: int64(1672560000000)},
+		{name: "string timestamp", value: "2023-01-01T08:00:00Z", columnType: "TIMESTAMP", want: ts},
+		{name: "invalid timestamp", value: "yesterday", columnType: "TIMESTAMP", wantErr: true},
+		{name: "nchar", value: "beijing", columnType: "NCHAR(64)", want: "beijing"},
+		{name: "number of nchar", value: json.Number("1"), columnType: "NCHAR(64)", wantErr: true},
+		{name: "bool", value: true, columnType: "BOOL", want: true},
+		{name: "bool of int", value: true, columnType: "INT", wantErr: true},
+		{name: "number of bool", value: json.Number("1"), columnType: "BOOL", wantErr: true},
+		{name: "json", value: map[string]interface{}{"k": json.Number("1")}, columnType: "JSON", want: `{"k":1}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := coerceJSONValue(c.value, c.columnType)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %#v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotTime, ok := got.(time.Time); ok {
+				if !gotTime.Equal(c.want.(time.Time)) {
+					t.Fatalf("got %v, want %v", got, c.want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestInsertJSON(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload string
+		want    map[string]interface{}
+		code    gcode.Code
+	}{
+		{
+			name:    "well formed",
+			payload: `{"ts": 1672560000000, "Current": 10.3, "voltage": 219, "location": "beijing", "phase": null}`,
+			want: map[string]interface{}{
+				"ts": int64(1672560000000), "current": 10.3, "voltage": int64(219), "location": "beijing", "phase": nil,
+			},
+		},
+		{name: "malformed", payload: `{"ts": 1672560000000,`, code: gcode.CodeInvalidParameter},
+		{name: "not object", payload: `[1, 2]`, code: gcode.CodeInvalidParameter},
+		{name: "empty object", payload: `{}`, code: gcode.CodeMissingParameter},
+		{name: "unknown column", payload: `{"ts": 1672560000000, "humidity": 30}`, code: gcode.CodeInvalidParameter},
+		{name: "type mismatch", payload: `{"ts": 1672560000000, "voltage": "high"}`, code: gcode.CodeInvalidParameter},
+		{name: "out of range", payload: `{"ts": 1672560000000, "voltage": 4294967296}`, code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var inserted []map[string]interface{}
+			d, _ := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+				if gstr.HasPrefix(q.Sql, "INSERT") {
+					inserted = insertedRecords(q)
+				}
+				return mockResponse{Affected: 1}
+			}))
+			_, err := d.InsertJSON(context.Background(), "d1001", []byte(c.payload))
+			checkCode(t, err, c.code)
+			var want []map[string]interface{}
+			if c.code == nil {
+				want = []map[string]interface{}{c.want}
+			}
+			if !reflect.DeepEqual(inserted, want) {
+				t.Fatalf("got inserted %#v, want %#v", inserted, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/gogf/gf/v2/container/gmap"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/gctx"
+)
+
+// ReadPreference is the preference of the node that read statements are routed to.
+type ReadPreference int
+
+const (
+	ReadPreferenceDefault ReadPreference = iota // Default routing of gf, which reads from slave nodes.
+	ReadPreferenceMaster                        // Read from master node, like for read-your-writes scenarios.
+	ReadPreferenceSlave                         // Read from slave nodes explicitly.
+)
+
+const (
+	ctxKeyForReadPreference gctx.StrKey = `TaossqlReadPreference`
+)
+
+var (
+	// linkSchemas maps the underlying sql.DB objects of the links to the schemas they are opened on,
+	// which are recorded by Master and Slave, as gdb.Link does not expose its schema.
+	linkSchemas = gmap.New(true)
+)
+
+// WithReadPreference creates and returns a new context from `ctx` with read preference `pref` bound,
+// which overrides the link routing of all read statements that are executed with the returned context, eg:
+//
+// ctx = taosql.WithReadPreference(ctx, taosql.ReadPreferenceMaster)
+// db.Model("meters").Ctx(ctx).All()
+//
+// It does not affect the statements within transactions, which are always executed on the transaction link.
+func WithReadPreference(ctx context.Context, pref ReadPreference) context.Context {
+	return context.WithValue(ctx, ctxKeyForReadPreference, pref)
+}
+
+// ReadPreferenceFromCtx retrieves and returns the read preference bound to `ctx`,
+// or ReadPreferenceDefault if there's no read preference bound.
+func ReadPreferenceFromCtx(ctx context.Context) ReadPreference {
+	if ctx == nil {
+		return ReadPreferenceDefault
+	}
+	if pref, ok := ctx.Value(ctxKeyForReadPreference).(ReadPreference); ok {
+		return pref
+	}
+	return ReadPreferenceDefault
+}
+
+// DoQuery commits the query string and its arguments to underlying driver
+// and returns the execution result, with the link routed by the read preference of `ctx`.
+func (d *Driver) DoQuery(ctx context.Context, link gdb.Link, sql string, args ...interface{}) (result gdb.Result, err error) {
+	if link, err = d.preferredLink(ctx, link); err != nil {
+		return nil, err
+	}
+	return d.Core.DoQuery(ctx, link, sql, args...)
+}
+
+// Master creates and returns a connection from master node like gdb.Core.Master,
+// and records the schema of the connection for the read preference, see preferredLink.
+func (d *Driver) Master(schema ...string) (*sql.DB, error) {
+	db, err := d.Core.Master(schema...)
+	if err == nil {
+		linkSchemas.Set(db, d.useSchema(schema...))
+	}
+	return db, err
+}
+
+// Slave creates and returns a connection from slave node like gdb.Core.Slave,
+// and records the schema of the connection for the read preference, see preferredLink.
+func (d *Driver) Slave(schema ...string) (*sql.DB, error) {
+	db, err := d.Core.Slave(schema...)
+	if err == nil {
+		linkSchemas.Set(db, d.useSchema(schema...))
+	}
+	return db, err
+}
+
+// useSchema returns the schema that a connection of `schema` is opened on, which is the schema of the DB
+// if `schema` is not given, or empty for the database of the configuration node.
+func (d *Driver) useSchema(schema ...string) string {
+	if len(schema) > 0 && schema[0] != "" {
+		return schema[0]
+	}
+	return d.GetSchema()
+}
+
+// linkSchema returns the schema that `link` is opened on, like the one of Model.Schema,
+// or empty if it's unknown, in which case the schema of the DB is used.
+func linkSchema(link gdb.Link) string {
+	// The links of package gdb embed the underlying sql.DB objects.
+	v := reflect.Indirect(reflect.ValueOf(link))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	field := v.FieldByName("DB")
+	if !field.IsValid() || !field.CanInterface() {
+		return ""
+	}
+	if db, ok := field.Interface().(*sql.DB); ok {
+		if schema, ok := linkSchemas.Get(db).(string); ok {
+			return schema
+		}
+	}
+	return ""
+}
+
+// preferredLink returns the link of the read preference of `ctx` in place of `link`.
+// It returns `link` unchanged if there's no preference bound, the link is already on the preferred node,
+// or the statement is within a transaction. The preferred link is created on the schema of `link`,
+// so that the statements of Model.Schema are executed in the same database.
+func (d *Driver) preferredLink(ctx context.Context, link gdb.Link) (gdb.Link, error) {
+	pref := ReadPreferenceFromCtx(ctx)
+	if pref == ReadPreferenceDefault {
+		return link, nil
+	}
+	if link != nil && link.IsTransaction() {
+		return link, nil
+	}
+	if gdb.TXFromCtx(ctx, d.GetGroup()) != nil {
+		return link, nil
+	}
+	var schema string
+	if link != nil {
+		schema = linkSchema(link)
+	}
+	switch pref {
+	case ReadPreferenceMaster:
+		if link != nil && link.IsOnMaster() {
+			return link, nil
+		}
+		return d.MasterLink(schema)
+	case ReadPreferenceSlave:
+		if link != nil && !link.IsOnMaster() {
+			return link, nil
+		}
+		return d.SlaveLink(schema)
+	}
+	return link, nil
+}
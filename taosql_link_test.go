@@ -0,0 +1,94 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+func TestReadPreference(t *testing.T) {
+	cases := []struct {
+		name       string
+		pref       ReadPreference
+		wantMaster bool
+	}{
+		{name: "default", pref: ReadPreferenceDefault, wantMaster: false},
+		{name: "master", pref: ReadPreferenceMaster, wantMaster: true},
+		{name: "slave", pref: ReadPreferenceSlave, wantMaster: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			handler := func(mockQuery) mockResponse { return mockRecords([]string{"v"}, []interface{}{int64(1)}) }
+			d, servers := newMockCluster(t, Option{}, handler, handler)
+			ctx := WithReadPreference(context.Background(), c.pref)
+			if _, err := d.Model("meters").Ctx(ctx).Fields("v").All(); err != nil {
+				t.Fatal(err)
+			}
+			master, slave := len(servers[0].Queries()), len(servers[1].Queries())
+			if c.wantMaster && (master == 0 || slave != 0) || !c.wantMaster && (master != 0 || slave == 0) {
+				t.Fatalf("got %d queries on master and %d on slave, want on master %v", master, slave, c.wantMaster)
+			}
+		})
+	}
+}
+
+func TestReadPreferenceExplicitLink(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, nil)
+	ctx := context.Background()
+	slave, err := d.SlaveLink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := d.preferredLink(WithReadPreference(ctx, ReadPreferenceMaster), slave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !link.IsOnMaster() {
+		t.Fatal("got slave link, want master link")
+	}
+	if link, err = d.preferredLink(ctx, slave); err != nil || link != slave {
+		t.Fatalf("got (%v, %v), want the given link", link, err)
+	}
+}
+
+func TestReadPreferenceSchema(t *testing.T) {
+	cases := []struct {
+		name   string
+		pref   ReadPreference
+		schema string
+		want   string
+	}{
+		{name: "master of model schema", pref: ReadPreferenceMaster, schema: "archive", want: "archive"},
+		{name: "slave of model schema", pref: ReadPreferenceSlave, schema: "archive", want: "archive"},
+		{name: "master of db schema", pref: ReadPreferenceMaster, want: mockSchema},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			handler := func(mockQuery) mockResponse { return mockRecords([]string{"v"}, []interface{}{int64(1)}) }
+			d, servers := newMockCluster(t, Option{}, handler, handler)
+			ctx := WithReadPreference(context.Background(), c.pref)
+			model := d.Model("meters").Ctx(ctx).Fields("v")
+			if c.schema != "" {
+				model = model.Schema(c.schema)
+			}
+			if _, err := model.All(); err != nil {
+				t.Fatal(err)
+			}
+			var selects int
+			for _, server := range servers {
+				for _, q := range server.Queries() {
+					if !gstr.HasPrefix(q.Sql, "SELECT") {
+						continue
+					}
+					if selects++; q.Schema != c.want {
+						t.Fatalf("got statement %q on database %q, want %q", q.Sql, q.Schema, c.want)
+					}
+				}
+			}
+			if selects != 1 {
+				t.Fatalf("got %d SELECT statements, want 1", selects)
+			}
+		})
+	}
+}
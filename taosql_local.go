@@ -0,0 +1,251 @@
+package taosql
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+const (
+	// queriedTablePattern matches the table following the top-level FROM keyword of a query, in which the first
+	// group is the database qualifier if the second group is not empty, like: FROM `archive`.`meters`.
+	queriedTablePattern = "^(?i)\\s*FROM\\s+`?(\\w+)`?(?:\\s*\\.\\s*`?(\\w+)`?)?"
+
+	// projectionPattern matches the beginning of the projection of a SELECT statement, like: SELECT DISTINCT.
+	projectionPattern = `^(?is)\s*SELECT\s+(?:DISTINCT\s+|ALL\s+)?`
+
+	// aliasPattern matches the projection item with an explicit alias, like: COUNT(*) AS cnt,
+	// and implicitAliasPattern matches the one with an implicit alias, like: COUNT(*) cnt.
+	aliasPattern         = "^(?is)(.+?)\\s+AS\\s+`?(\\w+)`?$"
+	implicitAliasPattern = "^(?is)(.*[\\w)`'\"])\\s+`?(\\w+)`?$"
+)
+
+// CheckLocalTypeForField returns the Go type of the values of column type `fieldType` that is returned by `desc`,
+// like: TIMESTAMP, NCHAR(64), TINYINT UNSIGNED, which is used for generating the models of the tables.
+// The TIMESTAMP values are of *gtime.Time like the other drivers of gdb, which are scanned into time.Time as well.
+// The values of the returned type are read by ConvertValueForLocal. It returns an error of code
+// gcode.CodeNotSupported if `fieldType` is unknown.
+func (d *Driver) CheckLocalTypeForField(ctx context.Context, fieldType string) (string, error) {
+	localType, ok := localTypes[localTypeKey(fieldType)]
+	if !ok {
+		return "", gerror.NewCodef(gcode.CodeNotSupported, `unsupported column type "%s"`, fieldType)
+	}
+	return localType, nil
+}
+
+// localTypes are the Go types of the column types keyed by localTypeKey.
+var localTypes = map[string]string{
+	"timestamp": "*gtime.Time", "bool": "bool", "float": "float32", "double": "float64",
+	"binary": "string", "varchar": "string", "nchar": "string", "json": "string",
+	"tinyint": "int8", "smallint": "int16", "int": "int32", "bigint": "int64",
+	"tinyint unsigned": "uint8", "smallint unsigned": "uint16", "int unsigned": "uint32", "bigint unsigned": "uint64",
+}
+
+// localTypeKey returns the lower case type name of column type `fieldType` with its UNSIGNED modifier,
+// like: tinyint unsigned.
+func localTypeKey(fieldType string) string {
+	typeName := gstr.TrimRightStr(columnTypeName(fieldType), " unsigned")
+	if gstr.ContainsI(fieldType, "unsigned") {
+		return typeName + " unsigned"
+	}
+	return typeName
+}
+
+// ConvertValueForLocal converts `fieldValue` of column type `fieldType` to the Go type returned by
+// CheckLocalTypeForField, in which the TIMESTAMP value read as epoch integer, like by the connectors without
+// the column types, is converted at the precision of current schema. The unsigned integers that gdb reads as
+// int are converted back to the unsigned integers bit by bit, so that the values beyond the signed range are
+// kept. The values of unknown column types and the NULL values are returned as they are.
+func (d *Driver) ConvertValueForLocal(ctx context.Context, fieldType string, fieldValue interface{}) (interface{}, error) {
+	return d.convertValueForLocal(fieldType, fieldValue, func() (string, error) {
+		return d.Precision(ctx)
+	})
+}
+
+// convertValueForLocal converts `fieldValue` like ConvertValueForLocal, in which the precision of the epoch
+// timestamps is retrieved by `precision` only if needed.
+func (d *Driver) convertValueForLocal(fieldType string, fieldValue interface{}, precision func() (string, error)) (interface{}, error) {
+	if fieldValue == nil {
+		return nil, nil
+	}
+	switch localTypeKey(fieldType) {
+	case "timestamp":
+		switch v := fieldValue.(type) {
+		case *gtime.Time:
+			return v, nil
+		case time.Time:
+			return gtime.NewFromTime(v), nil
+		case int, int32, int64, uint, uint32, uint64, float64:
+			p, err := precision()
+			if err != nil {
+				return nil, err
+			}
+			return gtime.NewFromTime(epochToTime(gconv.Int64(v), p)), nil
+		default:
+			t, err := gtime.StrToTime(gconv.String(v))
+			if err != nil {
+				return nil, gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid timestamp "%v"`, v)
+			}
+			return t, nil
+		}
+	case "binary", "varchar", "nchar", "json":
+		return gconv.String(fieldValue), nil
+	case "bool":
+		return gconv.Bool(fieldValue), nil
+	case "float":
+		return gconv.Float32(fieldValue), nil
+	case "double":
+		return gconv.Float64(fieldValue), nil
+	case "tinyint":
+		return gconv.Int8(fieldValue), nil
+	case "smallint":
+		return gconv.Int16(fieldValue), nil
+	case "int":
+		return gconv.Int32(fieldValue), nil
+	case "bigint":
+		return gconv.Int64(fieldValue), nil
+	case "tinyint unsigned":
+		return uint8(unsignedBits(fieldValue)), nil
+	case "smallint unsigned":
+		return uint16(unsignedBits(fieldValue)), nil
+	case "int unsigned":
+		return uint32(unsignedBits(fieldValue)), nil
+	case "bigint unsigned":
+		return unsignedBits(fieldValue), nil
+	default:
+		return fieldValue, nil
+	}
+}
+
+// unsignedBits returns the unsigned integer of the bits of integer `value`, which reverts the wrapping of
+// the unsigned integers converted to int by gdb, and converts the other values by gconv.
+func unsignedBits(value interface{}) uint64 {
+	switch v := value.(type) {
+	case int:
+		return uint64(v)
+	case int8:
+		return uint64(uint8(v))
+	case int16:
+		return uint64(uint16(v))
+	case int32:
+		return uint64(uint32(v))
+	case int64:
+		return uint64(v)
+	default:
+		return gconv.Uint64(v)
+	}
+}
+
+// convertLocalTypes converts the values of the columns of `result` of query `sql` to their Go types in place by
+// the table fields of the table queried by `sql`, see ConvertValueForLocal. The columns that are not of the table,
+// like expressions, aliases and the window pseudo columns, are left as they are, even if an alias is the same as
+// a column name, like: SELECT COUNT(*) AS current. So are the results of the statements other than SELECT, like
+// SHOW TAGS FROM d1001, and the queries without a plain table, like the subqueries and the system tables.
+func (d *Driver) convertLocalTypes(ctx context.Context, sql string, result gdb.Result) error {
+	if !gregex.IsMatchString(projectionPattern, sql) {
+		return nil
+	}
+	schema, table := queriedTable(sql)
+	if table == "" || gstr.Equal(schema, "information_schema") || gstr.Equal(schema, "performance_schema") {
+		return nil
+	}
+	fields, err := d.TableFields(ctx, table, schema)
+	if err != nil {
+		return err
+	}
+	var usePrecision string
+	precision := func() (string, error) {
+		if usePrecision != "" {
+			return usePrecision, nil
+		}
+		p, err := d.Precision(ctx, schema)
+		if err == nil {
+			usePrecision = p
+		}
+		return p, err
+	}
+	aliases := projectionAliases(sql)
+	for _, record := range result {
+		for name, value := range record {
+			field, ok := fields[name]
+			if !ok || isWindowColumn(name) || isNullValue(value) {
+				continue
+			}
+			if _, ok = aliases[gstr.ToLower(name)]; ok {
+				continue
+			}
+			v, err := d.convertValueForLocal(field.Type, value.Val(), precision)
+			if err != nil {
+				return err
+			}
+			record[name] = gvar.New(v)
+		}
+	}
+	return nil
+}
+
+// projectionAliases returns the aliases of the projection of SELECT query `sql` in lower case, except the ones
+// that are the same as the columns they name, like `current` of SELECT current AS current.
+func projectionAliases(sql string) map[string]struct{} {
+	var (
+		masked     = maskStringLiterals(sql)
+		pos        = topLevelIndex(masked, " FROM ")
+		match, _   = gregex.MatchString(projectionPattern, masked)
+		aliases    = make(map[string]struct{})
+		projection string
+	)
+	if len(match) == 0 {
+		return aliases
+	}
+	if pos < len(match[0]) {
+		projection = masked[len(match[0]):]
+	} else {
+		projection = masked[len(match[0]):pos]
+	}
+	for _, item := range splitTopLevel(projection, ",") {
+		alias, _ := gregex.MatchString(aliasPattern, item)
+		if len(alias) == 0 {
+			alias, _ = gregex.MatchString(implicitAliasPattern, item)
+		}
+		if len(alias) == 0 {
+			continue
+		}
+		// The column may be qualified by the table, like: d1001.current AS current.
+		column := gstr.Trim(alias[1], "`")
+		if i := gstr.PosR(column, "."); i >= 0 {
+			column = gstr.Trim(column[i+1:], "`")
+		}
+		if !gstr.Equal(column, alias[2]) {
+			aliases[gstr.ToLower(alias[2])] = struct{}{}
+		}
+	}
+	return aliases
+}
+
+// queriedTable returns the database and the name of the table following the top-level FROM keyword of query `sql`,
+// in which the database is empty if the table is not qualified. It returns an empty table if the query selects
+// from a subquery or has no FROM keyword.
+func queriedTable(sql string) (schema, table string) {
+	masked := maskStringLiterals(sql)
+	pos := topLevelIndex(masked, " FROM ")
+	if pos < 0 {
+		return "", ""
+	}
+	match, _ := gregex.MatchString(queriedTablePattern, masked[pos:])
+	switch {
+	case len(match) == 0:
+		return "", ""
+	case match[2] != "":
+		return match[1], match[2]
+	default:
+		return "", match[1]
+	}
+}
@@ -0,0 +1,252 @@
+package taosql
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+func TestCheckLocalTypeForField(t *testing.T) {
+	cases := []struct {
+		fieldType string
+		want      string
+		code      gcode.Code
+	}{
+		{fieldType: "TIMESTAMP", want: "*gtime.Time"},
+		{fieldType: "NCHAR(64)", want: "string"},
+		{fieldType: "BINARY(16)", want: "string"},
+		{fieldType: "VARCHAR(255)", want: "string"},
+		{fieldType: "JSON", want: "string"},
+		{fieldType: "BOOL", want: "bool"},
+		{fieldType: "TINYINT", want: "int8"},
+		{fieldType: "SMALLINT", want: "int16"},
+		{fieldType: "INT", want: "int32"},
+		{fieldType: "BIGINT", want: "int64"},
+		{fieldType: "TINYINT UNSIGNED", want: "uint8"},
+		{fieldType: "SMALLINT UNSIGNED", want: "uint16"},
+		{fieldType: "INT UNSIGNED", want: "uint32"},
+		{fieldType: "BIGINT UNSIGNED", want: "uint64"},
+		{fieldType: "bigint unsigned", want: "uint64"},
+		{fieldType: "FLOAT", want: "float32"},
+		{fieldType: "DOUBLE", want: "float64"},
+		{fieldType: "GEOMETRY(64)", code: gcode.CodeNotSupported},
+	}
+	d := &Driver{}
+	for _, c := range cases {
+		t.Run(c.fieldType, func(t *testing.T) {
+			got, err := d.CheckLocalTypeForField(context.Background(), c.fieldType)
+			checkCode(t, err, c.code)
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestConvertValueForLocal(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		fieldType string
+		value     interface{}
+		want      interface{}
+		code      gcode.Code
+	}{
+		{name: "time", fieldType: "TIMESTAMP", value: ts, want: gtime.NewFromTime(ts)},
+		{name: "epoch", fieldType: "TIMESTAMP", value: ts.UnixNano() / int64(time.Millisecond), want: gtime.NewFromTime(ts)},
+		{name: "time string", fieldType: "TIMESTAMP", value: "2023-01-01 00:00:00", want: gtime.NewFromTime(ts)},
+		{name: "invalid time", fieldType: "TIMESTAMP", value: "yesterday", code: gcode.CodeInvalidParameter},
+		{name: "binary", fieldType: "BINARY(16)", value: []byte("California"), want: "California"},
+		{name: "nchar", fieldType: "NCHAR(8)", value: "北京", want: "北京"},
+		{name: "bool", fieldType: "BOOL", value: true, want: true},
+		{name: "tinyint", fieldType: "TINYINT", value: -8, want: int8(-8)},
+		{name: "int", fieldType: "INT", value: 219, want: int32(219)},
+		{name: "bigint", fieldType: "BIGINT", value: int64(math.MinInt64), want: int64(math.MinInt64)},
+		{name: "tinyint unsigned", fieldType: "TINYINT UNSIGNED", value: 200, want: uint8(200)},
+		{name: "smallint unsigned", fieldType: "SMALLINT UNSIGNED", value: 65535, want: uint16(65535)},
+		{name: "int unsigned", fieldType: "INT UNSIGNED", value: 4294967295, want: uint32(4294967295)},
+		{name: "wrapped bigint unsigned", fieldType: "BIGINT UNSIGNED", value: -1, want: uint64(math.MaxUint64)},
+		{name: "bigint unsigned", fieldType: "BIGINT UNSIGNED", value: uint64(math.MaxUint64), want: uint64(math.MaxUint64)},
+		{name: "float", fieldType: "FLOAT", value: 10.5, want: float32(10.5)},
+		{name: "double", fieldType: "DOUBLE", value: 10.5, want: 10.5},
+		{name: "null", fieldType: "INT", value: nil, want: nil},
+		{name: "unknown type", fieldType: "GEOMETRY(64)", value: []byte{1}, want: []byte{1}},
+	}
+	d, _ := newMockDriver(t, Option{}, metersHandler(nil))
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := d.ConvertValueForLocal(context.Background(), c.fieldType, c.value)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				return
+			}
+			if want, ok := c.want.(*gtime.Time); ok {
+				if gt, ok := got.(*gtime.Time); !ok || !gt.Time.Equal(want.Time) {
+					t.Fatalf("got %#v, want %v", got, want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestConvertLocalTypes(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	desc := mockRecords(
+		[]string{"field", "type", "length", "note"},
+		[]interface{}{"ts", "TIMESTAMP", int64(8), ""},
+		[]interface{}{"counter", "BIGINT UNSIGNED", int64(8), ""},
+		[]interface{}{"status", "TINYINT UNSIGNED", int64(1), ""},
+		[]interface{}{"location", "BINARY(64)", int64(64), "TAG"},
+	)
+	handler := func(q mockQuery) mockResponse {
+		switch {
+		case q.Sql == "SHOW DATABASES":
+			return mockDatabases
+		case q.Sql == "desc d1001" || q.Sql == "desc d1002":
+			return desc
+		}
+		// The unsigned integers are read as int by gdb, and the timestamps as epoch by the connectors without types.
+		return mockRecords(
+			[]string{"ts", "counter", "status", "location", "total"},
+			[]interface{}{ts.UnixNano() / int64(time.Microsecond), -1, 200, []byte("beijing"), -1},
+		)
+	}
+	cases := []struct {
+		name   string
+		option Option
+		sql    string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "enabled",
+			option: Option{ConvertLocalTypes: true},
+			sql:    "SELECT *, -1 AS total FROM `archive`.`d1001` WHERE location = 'FROM d1002'",
+			want: map[string]interface{}{
+				"ts": gtime.NewFromTime(ts), "counter": uint64(math.MaxUint64), "status": uint8(200), "location": "beijing", "total": -1,
+			},
+		},
+		{
+			name: "disabled",
+			sql:  "SELECT *, -1 AS total FROM `archive`.`d1001`",
+			want: map[string]interface{}{
+				"ts": ts.UnixNano() / int64(time.Microsecond), "counter": -1, "status": 200, "location": []byte("beijing"), "total": -1,
+			},
+		},
+		{
+			name:   "alias of column name",
+			option: Option{ConvertLocalTypes: true},
+			sql:    "SELECT ts, COUNT(*) AS counter, status, location, total FROM `archive`.`d1001`",
+			want: map[string]interface{}{
+				"ts": gtime.NewFromTime(ts), "counter": -1, "status": uint8(200), "location": "beijing", "total": -1,
+			},
+		},
+		{
+			name:   "subquery",
+			option: Option{ConvertLocalTypes: true},
+			sql:    "SELECT * FROM (SELECT *, -1 AS total FROM d1001)",
+			want: map[string]interface{}{
+				"ts": ts.UnixNano() / int64(time.Microsecond), "counter": -1, "status": 200, "location": []byte("beijing"), "total": -1,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, c.option, handler)
+			result, err := d.GetAll(context.Background(), c.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := result[0].Map()
+			if want, ok := c.want["ts"].(*gtime.Time); ok {
+				if gt, ok := got["ts"].(*gtime.Time); !ok || !gt.Time.Equal(want.Time) {
+					t.Fatalf("got ts %#v, want %v at precision us of database archive", got["ts"], want)
+				}
+				got["ts"], c.want["ts"] = nil, nil
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got record %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestConvertLocalTypesStatements(t *testing.T) {
+	d, server := newMockDriver(t, Option{ConvertLocalTypes: true}, func(q mockQuery) mockResponse {
+		return mockRecords(
+			[]string{"table_name", "db_name", "stable_name", "tag_name", "tag_type", "tag_value"},
+			[]interface{}{"d1001", "power", "meters", "groupid", "INT", "2"},
+		)
+	})
+	result, err := d.GetAll(context.Background(), "SHOW TAGS FROM d1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result[0]["tag_value"].Val(); got != "2" {
+		t.Fatalf("got tag value %#v, want the unconverted value", got)
+	}
+	if sqls := server.Sqls(); len(sqls) != 1 {
+		t.Fatalf("got sqls %q, want only the SHOW statement", sqls)
+	}
+}
+
+func TestProjectionAliases(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want []string
+	}{
+		{sql: "SELECT COUNT(*) AS current FROM meters", want: []string{"current"}},
+		{sql: "SELECT COUNT(*) current, AVG(voltage) `Voltage` FROM meters", want: []string{"current", "voltage"}},
+		{sql: "SELECT CAST(current AS BIGINT) AS c, ts FROM meters", want: []string{"c"}},
+		{sql: "SELECT DISTINCT location FROM meters"},
+		{sql: "SELECT current AS current, d1001.voltage AS voltage FROM d1001"},
+		{sql: "SELECT CAST(current AS BIGINT), 'a b' FROM meters"},
+		{sql: "SELECT current + phase FROM meters"},
+		{sql: "SELECT ts, 'x AS y' AS label FROM meters", want: []string{"label"}},
+		{sql: "SELECT SERVER_VERSION() AS version", want: []string{"version"}},
+		{sql: "SHOW TAGS FROM d1001"},
+	}
+	for _, c := range cases {
+		t.Run(c.sql, func(t *testing.T) {
+			want := make(map[string]struct{})
+			for _, alias := range c.want {
+				want[alias] = struct{}{}
+			}
+			if got := projectionAliases(c.sql); !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestQueriedTable(t *testing.T) {
+	cases := []struct {
+		sql    string
+		schema string
+		table  string
+	}{
+		{sql: "SELECT * FROM meters", table: "meters"},
+		{sql: "SELECT * FROM `archive`.`meters` WHERE ts > 0", schema: "archive", table: "meters"},
+		{sql: "SELECT TRIM(LEADING ' ' FROM location) FROM d1001", table: "d1001"},
+		{sql: "SELECT * FROM d1001 WHERE location = 'x FROM y'", table: "d1001"},
+		{sql: "SELECT * FROM (SELECT * FROM d1001)"},
+		{sql: "SELECT SERVER_VERSION()"},
+		{sql: "SHOW DATABASES"},
+	}
+	for _, c := range cases {
+		t.Run(c.sql, func(t *testing.T) {
+			schema, table := queriedTable(c.sql)
+			if schema != c.schema || table != c.table {
+				t.Fatalf("got (%q, %q), want (%q, %q)", schema, table, c.schema, c.table)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package taosql
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// logSlowQuery logs the committed statement as warning if its execution `duration`
+// exceeds Option.SlowQueryThreshold. The arguments are redacted, only their count is logged,
+// and so are the credentials in the sql.
+func (d *Driver) logSlowQuery(ctx context.Context, in gdb.DoCommitInput, duration time.Duration) {
+	threshold := d.option.SlowQueryThreshold
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+	switch in.Type {
+	case gdb.SqlTypeQueryContext, gdb.SqlTypeExecContext,
+		gdb.SqlTypeStmtQueryContext, gdb.SqlTypeStmtExecContext, gdb.SqlTypeStmtQueryRowContext:
+	default:
+		return
+	}
+	d.GetLogger().Warningf(
+		ctx,
+		`[taossql] slow query: group=%s%s type=%s duration=%s threshold=%s args=%d sql=%s`,
+		d.GetGroup(), d.formatLabels(), in.Type, duration, threshold, len(in.Args), redactSql(in.Sql),
+	)
+}
+
+// Labels returns a copy of the labels of Option.Labels, which can be attached to the metrics of the application.
+func (d *Driver) Labels() map[string]string {
+	labels := make(map[string]string, len(d.option.Labels))
+	for k, v := range d.option.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// formatLabels formats the labels of Option.Labels as ` labels=k1=v1,k2=v2` in the order of keys for logging,
+// or returns an empty string if there's no label.
+func (d *Driver) formatLabels() string {
+	if len(d.option.Labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(d.option.Labels))
+	for k := range d.option.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = k + "=" + d.option.Labels[k]
+	}
+	return " labels=" + strings.Join(keys, ",")
+}
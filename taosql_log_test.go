@@ -0,0 +1,178 @@
+package taosql
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// stepClock returns a clock that advances `step` on each call, so that each statement takes `step`.
+func stepClock(step time.Duration) func() time.Time {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+// captureLogs sets a logger writing to the returned buffer to `d`.
+func captureLogs(d *Driver) *bytes.Buffer {
+	var (
+		buffer = bytes.NewBuffer(nil)
+		logger = glog.New()
+	)
+	logger.SetWriter(buffer)
+	logger.SetStdoutPrint(false)
+	d.SetLogger(logger)
+	return buffer
+}
+
+func TestLogSlowQuery(t *testing.T) {
+	cases := []struct {
+		name   string
+		option Option
+		sql    string
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "disabled",
+			option: Option{Clock: stepClock(time.Second)},
+			sql:    "SELECT * FROM meters",
+			absent: []string{"slow query"},
+		},
+		{
+			name:   "under threshold",
+			option: Option{SlowQueryThreshold: 2 * time.Second, Clock: stepClock(time.Second)},
+			sql:    "SELECT * FROM meters",
+			absent: []string{"slow query"},
+		},
+		{
+			name:   "over threshold",
+			option: Option{SlowQueryThreshold: time.Second, Clock: stepClock(time.Second)},
+			sql:    "SELECT * FROM meters WHERE location = ?",
+			want:   []string{"slow query", "duration=1s", "threshold=1s", "args=1", "sql=SELECT * FROM meters WHERE location = ?"},
+			absent: []string{"beijing"},
+		},
+		{
+			name:   "labels",
+			option: Option{SlowQueryThreshold: time.Second, Clock: stepClock(time.Second), Labels: map[string]string{"purpose": "ingest", "app": "collector"}},
+			sql:    "SELECT * FROM meters",
+			want:   []string{"labels=app=collector,purpose=ingest"},
+		},
+		{
+			name:   "redacted",
+			option: Option{SlowQueryThreshold: time.Second, Clock: stepClock(time.Second)},
+			sql:    "CREATE USER u PASS 'secret'",
+			want:   []string{"PASS '***'"},
+			absent: []string{"secret"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, c.option, nil)
+			logs := captureLogs(d)
+			var args []interface{}
+			if gstr.Contains(c.sql, "?") {
+				args = append(args, "beijing")
+			}
+			if _, err := d.Exec(context.Background(), c.sql, args...); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range c.want {
+				if !gstr.Contains(logs.String(), s) {
+					t.Errorf("log %q does not contain %q", logs.String(), s)
+				}
+			}
+			for _, s := range c.absent {
+				if gstr.Contains(logs.String(), s) {
+					t.Errorf("log %q contains %q", logs.String(), s)
+				}
+			}
+		})
+	}
+}
+
+func TestSetGroupOption(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, nil)
+	if d.option.SlowQueryThreshold != 0 {
+		t.Fatalf("got threshold %s, want the option of the driver", d.option.SlowQueryThreshold)
+	}
+	SetGroupOption(d.GetGroup(), Option{SlowQueryThreshold: time.Second})
+	defer groupOptions.Remove(d.GetGroup())
+	// The databases created afterwards, like by Schema or Ctx, are of the group option.
+	db := d.Schema(mockSchema).DB.(*mockDB).Driver
+	if db.option.SlowQueryThreshold != time.Second {
+		t.Fatalf("got threshold %s, want the group option", db.option.SlowQueryThreshold)
+	}
+	ctxDb := d.Ctx(context.Background()).(*mockDB).Driver
+	if ctxDb.option.SlowQueryThreshold != time.Second {
+		t.Fatalf("got threshold %s, want the group option", ctxDb.option.SlowQueryThreshold)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	cases := []struct {
+		name   string
+		option Option
+		group  *Option
+		want   map[string]string
+		log    string
+	}{
+		{name: "no labels", option: Option{SlowQueryThreshold: time.Second, Clock: stepClock(time.Second)}, want: map[string]string{}},
+		{
+			name:   "labels",
+			option: Option{SlowQueryThreshold: time.Second, Clock: stepClock(time.Second), Labels: map[string]string{"purpose": "ingest"}},
+			want:   map[string]string{"purpose": "ingest"},
+			log:    "labels=purpose=ingest",
+		},
+		{
+			name:   "group labels",
+			option: Option{Labels: map[string]string{"purpose": "ingest"}},
+			group: &Option{
+				SlowQueryThreshold: time.Second,
+				Clock:              stepClock(time.Second),
+				Labels:             map[string]string{"purpose": "query", "app": "dashboard"},
+			},
+			want: map[string]string{"purpose": "query", "app": "dashboard"},
+			log:  "labels=app=dashboard,purpose=query",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, c.option, nil)
+			if c.group != nil {
+				SetGroupOption(d.GetGroup(), *c.group)
+				defer groupOptions.Remove(d.GetGroup())
+				d = d.Schema(mockSchema).DB.(*mockDB).Driver
+			}
+			labels := d.Labels()
+			if !reflect.DeepEqual(labels, c.want) {
+				t.Fatalf("got labels %v, want %v", labels, c.want)
+			}
+			// The returned labels are a copy, which does not change the labels of the driver.
+			labels["purpose"] = "changed"
+			if got := d.Labels(); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got labels %v after changing the copy, want %v", got, c.want)
+			}
+			logs := captureLogs(d)
+			if _, err := d.Exec(context.Background(), "SELECT * FROM meters"); err != nil {
+				t.Fatal(err)
+			}
+			if c.log == "" {
+				if gstr.Contains(logs.String(), "labels=") {
+					t.Fatalf("got log %q, want no labels", logs.String())
+				}
+				return
+			}
+			if !gstr.Contains(logs.String(), c.log) {
+				t.Fatalf("got log %q, want %q", logs.String(), c.log)
+			}
+		})
+	}
+}
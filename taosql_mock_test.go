@@ -0,0 +1,306 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+const (
+	mockSqlDriverName = "taosql_mock"
+	mockSchema        = "power"
+)
+
+// mockQuery is a statement received by the mock sql driver.
+type mockQuery struct {
+	Sql    string
+	Args   []interface{}
+	Schema string // Database of the connection that the statement is executed on.
+}
+
+// mockResponse is the scripted response of the mock sql driver to a statement.
+type mockResponse struct {
+	Columns  []string
+	Rows     [][]interface{}
+	Affected int64
+	Err      error
+}
+
+// mockHandler returns the response of statement `q`.
+type mockHandler func(q mockQuery) mockResponse
+
+// mockServer records the statements received by the mock sql driver, and responds them by its handler.
+type mockServer struct {
+	mu      sync.Mutex
+	handler mockHandler
+	queries []mockQuery
+}
+
+// Queries returns the statements received so far.
+func (s *mockServer) Queries() []mockQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mockQuery(nil), s.queries...)
+}
+
+// Sqls returns the sql of the statements received so far.
+func (s *mockServer) Sqls() []string {
+	var sqls []string
+	for _, q := range s.Queries() {
+		sqls = append(sqls, q.Sql)
+	}
+	return sqls
+}
+
+func (s *mockServer) serve(schema, query string, args []driver.NamedValue) mockResponse {
+	q := mockQuery{Sql: query, Schema: schema}
+	for _, arg := range args {
+		q.Args = append(q.Args, arg.Value)
+	}
+	s.mu.Lock()
+	s.queries = append(s.queries, q)
+	handler := s.handler
+	s.mu.Unlock()
+	if handler == nil {
+		return mockResponse{}
+	}
+	return handler(q)
+}
+
+var (
+	mockServers  sync.Map // Servers of the mock sql driver keyed by data source name.
+	mockSequence int64
+)
+
+func init() {
+	sql.Register(mockSqlDriverName, mockSqlDriver{})
+}
+
+// newMockDriver creates and returns a driver of schema "power" with `option`, which is connected to mock server
+// responding by `handler`. It is of its own configuration group, so that the cached information is not shared.
+func newMockDriver(t *testing.T, option Option, handler mockHandler) (*Driver, *mockServer) {
+	t.Helper()
+	d, servers := newMockCluster(t, option, handler)
+	return d, servers[0]
+}
+
+// newMockCluster creates and returns a driver of schema "power" with `option`, which is connected to the mock
+// servers responding by `handlers` respectively, in which the first one is the master node and the others are
+// the slave nodes. It is of its own configuration group, see newMockDriver.
+func newMockCluster(t *testing.T, option Option, handlers ...mockHandler) (*Driver, []*mockServer) {
+	t.Helper()
+	return newMockSchemaCluster(t, mockSchema, option, handlers...)
+}
+
+// newMockSchemaCluster creates and returns a driver of `schema` like newMockCluster, which has no current schema
+// if `schema` is empty.
+func newMockSchemaCluster(t *testing.T, schema string, option Option, handlers ...mockHandler) (*Driver, []*mockServer) {
+	t.Helper()
+	var (
+		seq     = atomic.AddInt64(&mockSequence, 1)
+		name    = fmt.Sprintf(`taosql_mock_%d`, seq)
+		servers = make([]*mockServer, len(handlers))
+	)
+	if err := gdb.Register(name, &mockGdbDriver{option: option}); err != nil {
+		t.Fatal(err)
+	}
+	for i, handler := range handlers {
+		var (
+			link = fmt.Sprintf(`%s_%d`, name, i)
+			role = "master"
+		)
+		if i > 0 {
+			role = "slave"
+		}
+		servers[i] = &mockServer{handler: handler}
+		mockServers.Store(link, servers[i])
+		gdb.AddConfigNode(name, gdb.ConfigNode{Type: name, Link: link, Name: schema, Role: role})
+	}
+	db, err := gdb.NewByGroup(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema == "" {
+		return db.(*mockDB).Driver, servers
+	}
+	return db.Schema(schema).DB.(*mockDB).Driver, servers
+}
+
+// mockGdbDriver is the gdb driver of the mock databases.
+type mockGdbDriver struct {
+	option Option
+}
+
+func (m *mockGdbDriver) New(core *gdb.Core, node *gdb.ConfigNode) (gdb.DB, error) {
+	db, err := New(m.option).New(core, node)
+	if err != nil {
+		return nil, err
+	}
+	return &mockDB{Driver: db.(*Driver)}, nil
+}
+
+// mockDB is the taossql driver connecting to the mock sql driver.
+type mockDB struct {
+	*Driver
+}
+
+// Open opens the connection of data source name `<link>#<schema>` to the mock server of the link.
+func (m *mockDB) Open(config *gdb.ConfigNode) (*sql.DB, error) {
+	return sql.Open(mockSqlDriverName, config.Link+"#"+config.Name)
+}
+
+// mockRecords creates and returns the response of records of `columns` with `rows`.
+func mockRecords(columns []string, rows ...[]interface{}) mockResponse {
+	return mockResponse{Columns: columns, Rows: rows}
+}
+
+type mockSqlDriver struct{}
+
+func (mockSqlDriver) Open(name string) (driver.Conn, error) {
+	link, schema, _ := strings.Cut(name, "#")
+	v, ok := mockServers.Load(link)
+	if !ok {
+		return nil, fmt.Errorf(`mock server "%s" not found`, link)
+	}
+	return &mockConn{server: v.(*mockServer), schema: schema}, nil
+}
+
+type mockConn struct {
+	server *mockServer
+	schema string
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return &mockStmt{conn: c, query: query}, nil
+}
+
+func (c *mockConn) Close() error { return nil }
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+	return nil, errors.New(`transaction is not supported by mock driver`)
+}
+
+func (c *mockConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *mockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	response := c.server.serve(c.schema, query, args)
+	if response.Err != nil {
+		return nil, response.Err
+	}
+	return &mockRows{columns: response.Columns, rows: response.Rows}, nil
+}
+
+func (c *mockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	response := c.server.serve(c.schema, query, args)
+	if response.Err != nil {
+		return nil, response.Err
+	}
+	return driver.RowsAffected(response.Affected), nil
+}
+
+type mockStmt struct {
+	conn  *mockConn
+	query string
+}
+
+func (s *mockStmt) Close() error  { return nil }
+func (s *mockStmt) NumInput() int { return -1 }
+
+func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *mockStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+type mockRows struct {
+	columns []string
+	rows    [][]interface{}
+	index   int
+}
+
+func (r *mockRows) Columns() []string { return r.columns }
+func (r *mockRows) Close() error      { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.rows) {
+		return io.EOF
+	}
+	for i, v := range r.rows[r.index] {
+		dest[i] = v
+	}
+	r.index++
+	return nil
+}
+
+// checkCode checks whether `err` is of error code `code`, or is nil if `code` is nil.
+func checkCode(t *testing.T, err error, code gcode.Code) {
+	t.Helper()
+	if code == nil {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if gerror.Code(err) != code {
+		t.Fatalf("got error %v, want error of code %v", err, code)
+	}
+}
+
+var (
+	// mockDatabases is the response of `SHOW DATABASES` of the mock server, with database "power" of precision ms
+	// and database "archive" of precision us.
+	mockDatabases = mockRecords(
+		[]string{"name", "precision", "update", "keep"},
+		[]interface{}{"power", "ms", int64(0), "3650d,3650d,3650d"},
+		[]interface{}{"archive", "us", int64(1), "365d,365d,365d"},
+	)
+	// mockMetersDesc is the response of `desc` of super table "meters" and its child tables of the mock server.
+	mockMetersDesc = mockRecords(
+		[]string{"field", "type", "length", "note"},
+		[]interface{}{"ts", "TIMESTAMP", int64(8), ""},
+		[]interface{}{"current", "FLOAT", int64(4), ""},
+		[]interface{}{"voltage", "INT", int64(4), ""},
+		[]interface{}{"phase", "FLOAT", int64(4), ""},
+		[]interface{}{"location", "VARCHAR", int64(64), "TAG"},
+		[]interface{}{"groupid", "INT", int64(4), "TAG"},
+	)
+)
+
+// metersHandler returns the handler that responds `SHOW DATABASES` by mockDatabases and `desc` of the tables
+// by mockMetersDesc, and responds the other statements by `next` if it's not nil.
+func metersHandler(next mockHandler) mockHandler {
+	return func(q mockQuery) mockResponse {
+		switch {
+		case q.Sql == `SHOW DATABASES`:
+			return mockDatabases
+		case gstr.HasPrefix(q.Sql, `desc `):
+			return mockMetersDesc
+		case next != nil:
+			return next(q)
+		default:
+			return mockResponse{}
+		}
+	}
+}
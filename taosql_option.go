@@ -0,0 +1,123 @@
+package taosql
+
+import (
+	"time"
+
+	"github.com/gogf/gf/v2/container/gmap"
+)
+
+// Option is the driver specific configuration for taossql, which is passed to function New.
+// Note that the zero value of each attribute means its default behavior.
+//
+// The driver registered by this package for type "taosSql" is of the default option, the option is configured by
+// re-registering the driver with it before the databases are created, like:
+// gdb.Register("taosSql", taosql.New(taosql.Option{SlowQueryThreshold: time.Second})),
+// or per configuration group by function SetGroupOption, which overrides it for the group.
+type Option struct {
+	// JSONTimeLayout is the layout for formatting timestamps in function SelectJSON,
+	// which is ISO8601(RFC3339 with nanoseconds) in default.
+	JSONTimeLayout string
+
+	// SlowQueryThreshold enables slow query logging if it's greater than 0. Any query or exec statement
+	// that takes longer than it is logged as warning with its rewritten sql and duration.
+	// Note that the arguments are redacted from the log, as they might contain sensitive data.
+	SlowQueryThreshold time.Duration
+
+	// ConnectRetryCount is the max retry count for establishing the connection in function Open,
+	// which tolerates the server being not ready yet at service startup. Only connection-establishment
+	// errors are retried, authentication failures are not. It establishes the connection lazily if it's 0.
+	ConnectRetryCount int
+
+	// ConnectRetryInterval is the interval before the first retry, which doubles for each next retry.
+	// It is 1 second in default.
+	ConnectRetryInterval time.Duration
+
+	// BinaryCharset is the charset of the data stored in BINARY(VARCHAR) columns, like: GBK, GB18030,
+	// which is used by function DecodeStrings to decode them to UTF-8 strings. It is UTF-8 in default.
+	BinaryCharset string
+
+	// AdminMode enables the destructive DDL helpers, which return error of code gcode.CodeNotAuthorized
+	// if it's disabled, protecting the services that should never drop objects. It is disabled in default.
+	// The gated operations are: DropDatabase, DropStable, DropTable and SetKeep.
+	AdminMode bool
+
+	// QualifyTableNames enables qualifying the table names of all statements with current schema, like: db.meters,
+	// which avoids operating the tables of another database if the default database of the connection differs from
+	// the intended one. The table names that are already qualified are left as they are. It is disabled in default.
+	// The schema is the one of the database executing the statement, like the one returned by Schema, which is
+	// resolved when the statement is filtered, but not the database switched to by a `USE` statement.
+	QualifyTableNames bool
+
+	// ValidateSchema enables validating the keys of the inserted records against the cached table fields before
+	// sending the statement, which returns an error naming the unknown columns, like typos or removed columns,
+	// instead of the generic error of the server. It is disabled in default to avoid the overhead.
+	// It validates the records of functions Insert, Upsert, InsertAutoCreate, InsertUsing and BatchInsert of the
+	// driver, but not the ones of gdb.Model, which drops the unknown keys silently before DoInsert.
+	ValidateSchema bool
+
+	// ConvertLocalTypes enables converting the values of the query records to the Go types of the columns of the
+	// queried table by Driver.ConvertValueForLocal, like TIMESTAMP to *gtime.Time at the database precision, NCHAR
+	// and BINARY to string, and the unsigned integers to the unsigned Go integers of the same widths, which gdb
+	// reads as int. Only the columns of SELECT statements are converted, but not their aliases.
+	// It is disabled in default, as it retrieves the table fields of each queried table.
+	ConvertLocalTypes bool
+
+	// MaxSubtablesPerBatch is the max number of subtables in each multi-table INSERT statement of functions
+	// InsertAutoCreate and BatchInsert, which splits the insert into multiple statements if exceeded, bounding
+	// the subtables created by a single statement. It is 100 in default.
+	MaxSubtablesPerBatch int
+
+	// MaxRowsPerBatch is the max number of rows in each multi-table INSERT statement of functions
+	// InsertAutoCreate and BatchInsert, which splits the insert into multiple statements if exceeded,
+	// bounding the length of a single statement. It is 1000 in default.
+	MaxRowsPerBatch int
+
+	// SanitizeSubtableNames enables transforming the subtable names of functions InsertAutoCreate and InsertUsing
+	// by SanitizeTableName, like the names derived from the device ids with dots, spaces or unicode, which are
+	// reverted by UnsanitizeTableName. It is disabled in default, so that the explicit names are kept as they are.
+	SanitizeSubtableNames bool
+
+	// Connector is the connector for connecting to the server, which is the native connector in default.
+	// The REST and websocket connectors do not require the native client library, but their underlying
+	// drivers are not provided by the driver-go version of this package, and should be registered by the
+	// application, like by importing the packages of driver-go v3, see ConnectorREST and ConnectorWebSocket.
+	Connector Connector
+
+	// Clock returns the current time wherever the driver needs it, like measuring the statement duration for
+	// slow query logging, which can be replaced by a fixed clock for deterministic tests. It is time.Now in default.
+	Clock func() time.Time
+
+	// MaxAbandonedStatements is the max number of the statements that are abandoned at their context deadlines
+	// and still running in background, each of which holds a goroutine and a connection until the server responds.
+	// The statements exceeding it wait for the server in the calling goroutine past their deadlines instead of
+	// returning promptly, which bounds the goroutines leaked by a stuck server. It is 100 in default.
+	MaxAbandonedStatements int
+
+	// Labels are the key-value pairs describing the purpose of the driver, like: {"purpose": "ingest"},
+	// which are attached to the logs of the driver, and retrieved by Driver.Labels for the metrics of the
+	// application, so that the usage of the configuration groups can be attributed.
+	Labels map[string]string
+}
+
+const (
+	defaultTsColumn             = "ts"
+	defaultJSONTimeLayout       = time.RFC3339Nano
+	defaultConnectRetryInterval = time.Second
+	defaultMaxSubtablesPerBatch = 100
+	defaultMaxRowsPerBatch      = 1000
+
+	defaultMaxAbandonedStatements = 100
+)
+
+var (
+	// groupOptions is the options of the configuration groups set by function SetGroupOption.
+	groupOptions = gmap.NewStrAnyMap(true)
+)
+
+// SetGroupOption sets `option` as the driver specific configuration of configuration group `group`,
+// which overrides the option of function New for the databases of the group, like different Labels or
+// QualifyTableNames for the groups of different purposes. It should be called before the databases of the group
+// are created, like by g.DB(group), as the option of a database is determined at its creation.
+func SetGroupOption(group string, option Option) {
+	groupOptions.Set(group, option)
+}
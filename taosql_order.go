@@ -0,0 +1,38 @@
+package taosql
+
+import (
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// NullsOrder is the placement of null values in the ORDER BY clause, see function OrderBy.
+type NullsOrder string
+
+const (
+	NullsDefault NullsOrder = ""            // Server default, which is first for ASC and last for DESC.
+	NullsFirst   NullsOrder = "NULLS FIRST" // Null values are ordered before the others.
+	NullsLast    NullsOrder = "NULLS LAST"  // Null values are ordered after the others.
+)
+
+// OrderBy returns the `expr ASC|DESC [NULLS FIRST|LAST]` ordering, which controls the placement of null values
+// of the sorted results, and can be passed to Model.Order as gdb.Raw, as the expression might contain commas, eg:
+//
+// order, err := taosql.OrderBy("current", true, taosql.NullsLast)
+// db.Model("meters").Order(gdb.Raw(order)).All()
+func OrderBy(expr string, desc bool, nulls NullsOrder) (string, error) {
+	if err := checkExpr(expr); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid ORDER BY expression`)
+	}
+	order := expr + " ASC"
+	if desc {
+		order = expr + " DESC"
+	}
+	switch nulls {
+	case NullsDefault:
+		return order, nil
+	case NullsFirst, NullsLast:
+		return order + " " + string(nulls), nil
+	default:
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid nulls order "%s"`, nulls)
+	}
+}
@@ -0,0 +1,72 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+func TestOrderBy(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "nulls first",
+			call: func() (string, error) { return OrderBy("current", false, NullsFirst) },
+			want: "current ASC NULLS FIRST",
+		},
+		{
+			name: "nulls last",
+			call: func() (string, error) { return OrderBy("current", true, NullsLast) },
+			want: "current DESC NULLS LAST",
+		},
+		{
+			name: "server default",
+			call: func() (string, error) { return OrderBy("current", true, NullsDefault) },
+			want: "current DESC",
+		},
+		{
+			name: "expression",
+			call: func() (string, error) { return OrderBy("IFNULL(current, 0)", false, NullsLast) },
+			want: "IFNULL(current, 0) ASC NULLS LAST",
+		},
+		{
+			name: "invalid nulls order",
+			call: func() (string, error) { return OrderBy("current", false, NullsOrder("NULLS MIDDLE")) },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "invalid expression",
+			call: func() (string, error) { return OrderBy("current; DROP TABLE meters", false, NullsFirst) },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestOrderByStatement(t *testing.T) {
+	cases := []struct {
+		name  string
+		desc  bool
+		nulls NullsOrder
+		want  string
+	}{
+		{name: "nulls first", nulls: NullsFirst, want: "SELECT * FROM `d1001` ORDER BY current ASC NULLS FIRST"},
+		{name: "nulls last", desc: true, nulls: NullsLast, want: "SELECT * FROM `d1001` ORDER BY current DESC NULLS LAST"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(nil))
+			order, err := OrderBy("current", c.desc, c.nulls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = d.Model("d1001").Ctx(context.Background()).Order(gdb.Raw(order)).All(); err != nil {
+				t.Fatal(err)
+			}
+			sqls := server.Sqls()
+			if got := sqls[len(sqls)-1]; got != c.want {
+				t.Fatalf("got sql %q, want %q", got, c.want)
+			}
+		})
+	}
+}
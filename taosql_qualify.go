@@ -0,0 +1,87 @@
+package taosql
+
+import (
+	"strings"
+
+	"github.com/gogf/gf/v2/text/gregex"
+)
+
+const (
+	// tableNamePattern matches the table names following FROM, JOIN, INTO and USING keywords, which are not
+	// qualified with database name, like: FROM `meters`, INSERT INTO d1001 USING meters.
+	tableNamePattern = "(?i)(\\b(?:FROM|JOIN|INTO|USING)\\s+)(`?)(\\w+)(`?)([^\\w.`]|$)"
+
+	// subqueryPattern matches the beginning of the subquery following an opening parenthesis.
+	subqueryPattern = `^(?i)\s*SELECT\b`
+)
+
+// qualifyTableNames qualifies the table names of `sql` with current schema, see Option.QualifyTableNames.
+//
+// Only the table positions of the statement and its subqueries are qualified, but not the string literals,
+// the quoted identifiers and the parentheses of function calls or column lists, like TRIM(LEADING ' ' FROM col),
+// which takes the innermost parenthesis as the scope of a position, and a parenthesis starting with SELECT as
+// a subquery. The schema is resolved by d.GetSchema when the statement is filtered, which is the schema of the
+// configuration node, or the one of Schema for the database it returns, but not the database switched to by
+// a `USE` statement on the connection. It returns `sql` unchanged if there's no current schema.
+func (d *Driver) qualifyTableNames(sql string) string {
+	schema := d.GetSchema()
+	if schema == "" {
+		return sql
+	}
+	var (
+		buffer  strings.Builder
+		start   int
+		quote   byte
+		escaped bool
+		// scopes are whether the enclosing parentheses are subqueries, from the outermost to the innermost.
+		scopes []bool
+	)
+	// flush writes the segment of `sql` before `end`, which is qualified if it's of a statement scope.
+	flush := func(end int) {
+		segment := sql[start:end]
+		if len(scopes) == 0 || scopes[len(scopes)-1] {
+			segment, _ = gregex.ReplaceStringFuncMatch(tableNamePattern, segment, func(match []string) string {
+				return match[1] + d.QuoteWord(schema) + "." + d.QuoteWord(match[3]) + match[5]
+			})
+		}
+		buffer.WriteString(segment)
+		start = end
+	}
+	for i := 0; i < len(sql); i++ {
+		switch ch := sql[i]; {
+		case escaped:
+			escaped = false
+		case quote == '`':
+			if ch == quote {
+				quote = 0
+			}
+		case quote != 0:
+			if ch == '\\' {
+				escaped = true
+			} else if ch == quote {
+				quote = 0
+				buffer.WriteString(sql[start : i+1])
+				start = i + 1
+			}
+		case ch == '`':
+			quote = ch
+		case ch == '\'' || ch == '"':
+			flush(i)
+			quote = ch
+		case ch == '(':
+			flush(i)
+			scopes = append(scopes, gregex.IsMatchString(subqueryPattern, sql[i+1:]))
+		case ch == ')':
+			flush(i)
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		}
+	}
+	if quote != 0 && quote != '`' {
+		buffer.WriteString(sql[start:])
+	} else {
+		flush(len(sql))
+	}
+	return buffer.String()
+}
@@ -0,0 +1,98 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQualifyTableNames(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "select",
+			sql:  "SELECT * FROM `meters` WHERE ts > 0",
+			want: "SELECT * FROM `power`.`meters` WHERE ts > 0",
+		},
+		{
+			name: "qualified",
+			sql:  "SELECT * FROM `archive`.`meters`",
+			want: "SELECT * FROM `archive`.`meters`",
+		},
+		{
+			name: "insert",
+			sql:  "INSERT INTO `d1001`(`ts`,`current`) VALUES(?,?)",
+			want: "INSERT INTO `power`.`d1001`(`ts`,`current`) VALUES(?,?)",
+		},
+		{
+			name: "insert using",
+			sql:  "INSERT INTO d1001 USING meters (location) TAGS (?) (ts) VALUES (?)",
+			want: "INSERT INTO `power`.`d1001` USING `power`.`meters` (location) TAGS (?) (ts) VALUES (?)",
+		},
+		{
+			name: "subquery",
+			sql:  "SELECT * FROM (SELECT tbname, LAST(current) FROM meters PARTITION BY tbname) ORDER BY 2",
+			want: "SELECT * FROM (SELECT tbname, LAST(current) FROM `power`.`meters` PARTITION BY tbname) ORDER BY 2",
+		},
+		{
+			name: "in subquery",
+			sql:  "SELECT * FROM d1001 WHERE voltage IN (SELECT voltage FROM d1002)",
+			want: "SELECT * FROM `power`.`d1001` WHERE voltage IN (SELECT voltage FROM `power`.`d1002`)",
+		},
+		{
+			name: "function call",
+			sql:  "SELECT TRIM(LEADING ' ' FROM location) FROM meters",
+			want: "SELECT TRIM(LEADING ' ' FROM location) FROM `power`.`meters`",
+		},
+		{
+			name: "nested function call",
+			sql:  "SELECT UPPER(TRIM(BOTH 'x' FROM location)) AS loc FROM meters",
+			want: "SELECT UPPER(TRIM(BOTH 'x' FROM location)) AS loc FROM `power`.`meters`",
+		},
+		{
+			name: "string literal",
+			sql:  "SELECT * FROM meters WHERE location = 'FROM d1001'",
+			want: "SELECT * FROM `power`.`meters` WHERE location = 'FROM d1001'",
+		},
+		{
+			name: "quoted identifier",
+			sql:  "SELECT `a(b` FROM meters",
+			want: "SELECT `a(b` FROM `power`.`meters`",
+		},
+		{
+			name: "join",
+			sql:  "SELECT * FROM d1001 a JOIN d1002 b ON a.ts = b.ts",
+			want: "SELECT * FROM `power`.`d1001` a JOIN `power`.`d1002` b ON a.ts = b.ts",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, Option{}, nil)
+			if got := d.qualifyTableNames(c.sql); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestQualifyTableNamesSchema(t *testing.T) {
+	d, _ := newMockSchemaCluster(t, "", Option{}, nil)
+	if got, want := d.qualifyTableNames("SELECT * FROM meters"), "SELECT * FROM meters"; got != want {
+		t.Fatalf("got %q without schema, want %q", got, want)
+	}
+	d, server := newMockDriver(t, Option{QualifyTableNames: true}, metersHandler(nil))
+	schemaDb := d.Schema("archive").DB.(*mockDB)
+	if _, err := schemaDb.GetAll(context.Background(), "SELECT * FROM meters"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.GetAll(context.Background(), "SELECT * FROM meters"); err != nil {
+		t.Fatal(err)
+	}
+	sqls := server.Sqls()
+	want := []string{"SELECT * FROM `archive`.`meters`", "SELECT * FROM `power`.`meters`"}
+	if len(sqls) < 2 || sqls[len(sqls)-2] != want[0] || sqls[len(sqls)-1] != want[1] {
+		t.Fatalf("got sqls %q, want %q qualified by the schema of each database", sqls, want)
+	}
+}
@@ -0,0 +1,195 @@
+package taosql
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+const (
+	// statementTablePattern matches the first table name of a statement following FROM or INTO keywords,
+	// in which the first group is the database qualifier if the second group is not empty,
+	// like: FROM `archive`.`meters`, INSERT INTO d1001.
+	statementTablePattern = "(?i)\\b(?:FROM|INTO)\\s+`?(\\w+)`?(\\s*\\.\\s*`?\\w+`?)?"
+)
+
+var (
+	// structTagPriority is the priority of struct tags for mapping struct attributes to columns,
+	// which is the same as package gdb.
+	structTagPriority = append([]string{gdb.OrmTagForStruct}, gconv.StructTagPriority...)
+)
+
+// flattenEmbedded merges the attributes of embedded structs of struct `value` into record `data`.
+//
+// The gdb.DataToMapDeep already merges embedded structs without tag, but it converts the embedded
+// structs with tag to nested maps keyed by the tag, which are actually the columns of the same table.
+func flattenEmbedded(data map[string]interface{}, value interface{}) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+	rt := rv.Type()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.Anonymous || !field.IsExported() {
+			continue
+		}
+		fieldValue := rv.Field(i)
+		for fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() {
+			fieldValue = fieldValue.Elem()
+		}
+		if fieldValue.Kind() != reflect.Struct {
+			continue
+		}
+		if key := embeddedTagKey(field); key != "" {
+			nested, ok := data[key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			delete(data, key)
+			for k, v := range nested {
+				data[k] = v
+			}
+		}
+		flattenEmbedded(data, fieldValue.Interface())
+	}
+}
+
+// embeddedTagKey returns the map key of embedded struct attribute `field` by its tag,
+// or an empty string if it has no tag.
+func embeddedTagKey(field reflect.StructField) string {
+	for _, tag := range structTagPriority {
+		if key := field.Tag.Get(tag); key != "" {
+			key = gstr.Trim(gstr.Split(key, ",")[0])
+			if key == "-" {
+				return ""
+			}
+			return key
+		}
+	}
+	return ""
+}
+
+// convertTimeArgs converts the time.Time arguments of `args` of statement `sql` to epoch integers at the precision
+// of the database of the statement, so that the time bounds of predicates are compared at the same precision as
+// the stored timestamps, the same as the inserted timestamps, see timeValue. The database of the statement is the
+// database of its first table if it's qualified, like `archive` of `SELECT * FROM archive.meters`, or else
+// current schema.
+//
+// Note that the gtime.Time arguments are already formatted as strings in seconds by package gdb.
+func (d *Driver) convertTimeArgs(ctx context.Context, sql string, args []interface{}) ([]interface{}, error) {
+	var (
+		precision string
+		newArgs   []interface{}
+	)
+	for i, arg := range args {
+		var t time.Time
+		switch value := arg.(type) {
+		case time.Time:
+			t = value
+		case *time.Time:
+			if value == nil {
+				continue
+			}
+			t = *value
+		default:
+			continue
+		}
+		if newArgs == nil {
+			if schema := d.statementSchema(sql); schema != "" {
+				var err error
+				if precision, err = d.precision(withoutDryRun(withoutClause(ctx)), schema); err != nil {
+					return nil, err
+				}
+			}
+			newArgs = make([]interface{}, len(args))
+			copy(newArgs, args)
+		}
+		newArgs[i] = timeValue(t, precision)
+	}
+	if newArgs == nil {
+		return args, nil
+	}
+	return newArgs, nil
+}
+
+// statementSchema returns the database of the first table of statement `sql`, which is its qualifier if it's
+// qualified, like `archive` of `SELECT * FROM archive.meters`, or else current schema.
+func (d *Driver) statementSchema(sql string) string {
+	match, _ := gregex.MatchString(statementTablePattern, maskStringLiterals(sql))
+	if len(match) > 2 && match[2] != "" {
+		return match[1]
+	}
+	return d.GetSchema()
+}
+
+// timeValue converts `t` to the timestamp value at `precision`, which is the epoch integer at `precision`,
+// or the RFC3339 string with nanoseconds if `precision` is unknown, which the server parses at any precision.
+func timeValue(t time.Time, precision string) interface{} {
+	if precision == "" {
+		return t.Format(time.RFC3339Nano)
+	}
+	return timeToEpoch(t, precision)
+}
+
+// timeToEpoch converts `t` to epoch integer at `precision`, which is ms, us or ns.
+// The sub-precision part of `t` is truncated, like TDengine does for the inserted timestamps.
+func timeToEpoch(t time.Time, precision string) int64 {
+	switch precision {
+	case "ns":
+		return t.UnixNano()
+	case "us":
+		return t.UnixNano() / int64(time.Microsecond)
+	default:
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+}
+
+// checkRecordColumns checks whether all keys of the records of `list` are columns or tags of `table`
+// case-insensitively, and returns an error naming the unknown columns if not.
+func (d *Driver) checkRecordColumns(ctx context.Context, table string, list gdb.List) error {
+	fields, err := d.TableFields(ctx, table)
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]struct{}, len(fields))
+	for name := range fields {
+		columns[gstr.ToLower(name)] = struct{}{}
+	}
+	var (
+		unknown []string
+		checked = make(map[string]struct{})
+	)
+	for _, record := range list {
+		for key := range record {
+			if _, ok := checked[key]; ok {
+				continue
+			}
+			checked[key] = struct{}{}
+			if _, ok := columns[gstr.ToLower(key)]; !ok {
+				unknown = append(unknown, key)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return gerror.NewCodef(
+			gcode.CodeInvalidParameter, `unknown columns %s for table %s`, gstr.Join(unknown, ", "), table,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package taosql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+type RecordBase struct {
+	Ts      int64 `orm:"ts"`
+	Current float64
+}
+
+type RecordTags struct {
+	Location string `orm:"location"`
+	GroupId  int    `json:"groupid"`
+}
+
+type RecordPoint struct {
+	RecordBase
+	*RecordTags `orm:"tags"`
+	Voltage     int `orm:"voltage"`
+}
+
+type recordNested struct {
+	RecordPoint `orm:"point"`
+	Phase       float64 `orm:"phase"`
+}
+
+type recordIgnored struct {
+	RecordTags `orm:"-"`
+	Phase      float64 `orm:"phase"`
+}
+
+func TestConvertDataForRecordEmbedded(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, metersHandler(nil))
+	cases := []struct {
+		name  string
+		value interface{}
+		want  map[string]interface{}
+	}{
+		{
+			name:  "embedded",
+			value: RecordPoint{RecordBase{1, 10.5}, &RecordTags{"beijing", 2}, 220},
+			want: map[string]interface{}{
+				"ts": int64(1), "Current": 10.5, "location": "beijing", "groupid": 2, "voltage": 220,
+			},
+		},
+		{
+			name:  "nested embedded",
+			value: &recordNested{RecordPoint{RecordBase{1, 10.5}, &RecordTags{"beijing", 2}, 220}, 0.3},
+			want: map[string]interface{}{
+				"ts": int64(1), "Current": 10.5, "location": "beijing", "groupid": 2, "voltage": 220, "phase": 0.3,
+			},
+		},
+		{
+			name:  "ignored",
+			value: recordIgnored{RecordTags{"beijing", 2}, 0.3},
+			want:  map[string]interface{}{"phase": 0.3},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := d.ConvertDataForRecord(context.Background(), c.value)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestConvertDataForRecordJSON(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, metersHandler(nil))
+	cases := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{name: "map", value: gdb.Map{"model": "x1", "floor": 3}, want: `{"floor":3,"model":"x1"}`},
+		{name: "slice", value: []interface{}{"a", 1}, want: `["a",1]`},
+		{name: "empty map", value: gdb.Map{}, want: `{}`},
+		{name: "string", value: `{"model":"x1"}`, want: `{"model":"x1"}`},
+		{name: "bytes", value: []byte("raw"), want: []byte("raw")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := d.ConvertDataForRecord(context.Background(), gdb.Map{"info": c.value})
+			if !reflect.DeepEqual(got["info"], c.want) {
+				t.Fatalf("got %#v, want %#v", got["info"], c.want)
+			}
+		})
+	}
+	_, err := d.convertDataForRecord(context.Background(), gdb.Map{"info": gdb.Map{"f": func() {}}})
+	checkCode(t, err, gcode.CodeInvalidParameter)
+}
+
+func TestConvertDataForRecordTime(t *testing.T) {
+	var (
+		ts      = time.Date(2023, 1, 1, 0, 0, 0, 123456789, time.UTC)
+		zero    time.Time
+		nilTime *time.Time
+	)
+	cases := []struct {
+		name   string
+		schema string
+		value  interface{}
+		want   interface{}
+	}{
+		{name: "ms", schema: "power", value: ts, want: int64(1672531200123)},
+		{name: "us", schema: "archive", value: ts, want: int64(1672531200123456)},
+		{name: "pointer", schema: "archive", value: &ts, want: int64(1672531200123456)},
+		{name: "gtime", schema: "power", value: *gtime.NewFromTime(ts), want: int64(1672531200123)},
+		{name: "zero", schema: "power", value: zero, want: nil},
+		{name: "nil pointer", schema: "power", value: nilTime, want: nil},
+		{name: "unknown precision", schema: "missing", value: ts, want: "2023-01-01T00:00:00.123456789Z"},
+		{name: "no schema", value: ts, want: "2023-01-01T00:00:00.123456789Z"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockSchemaCluster(t, c.schema, Option{}, metersHandler(nil))
+			got := d.ConvertDataForRecord(context.Background(), map[string]interface{}{"ts": c.value, "current": 10.5})
+			if want := map[string]interface{}{"ts": c.want, "current": 10.5}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestConvertTimeArgs(t *testing.T) {
+	var (
+		d, server = newMockDriver(t, Option{}, metersHandler(nil))
+		start     = time.Date(2022, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	)
+	cases := []struct {
+		name string
+		sql  string
+		want interface{}
+		err  bool
+	}{
+		{name: "current schema", sql: "SELECT * FROM meters WHERE ts >= ?", want: start.UnixNano() / 1e6},
+		{name: "qualified", sql: "SELECT * FROM archive.meters WHERE ts >= ?", want: start.UnixNano() / 1e3},
+		{name: "quoted qualified", sql: "SELECT * FROM `archive` . `meters` WHERE ts >= ?", want: start.UnixNano() / 1e3},
+		{name: "literal", sql: "SELECT * FROM meters WHERE location <> 'FROM archive.meters' AND ts >= ?", want: start.UnixNano() / 1e6},
+		{name: "no time", sql: "SELECT * FROM history.meters WHERE groupid = ?", want: 2},
+		{name: "unknown database", sql: "SELECT * FROM history.meters WHERE ts >= ?", err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			arg := c.want
+			if _, ok := arg.(int); !ok {
+				arg = start
+			}
+			if _, err := d.GetAll(context.Background(), c.sql, arg); c.err {
+				if err == nil {
+					t.Fatal("got no error of unknown database")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			queries := server.Queries()
+			got := queries[len(queries)-1]
+			if got.Sql != c.sql || len(got.Args) != 1 || !reflect.DeepEqual(got.Args[0], c.want) {
+				t.Fatalf("got %s %#v, want %s %#v", got.Sql, got.Args, c.sql, c.want)
+			}
+		})
+	}
+}
+
+func TestTimeValue(t *testing.T) {
+	value := time.Date(2022, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	cases := []struct {
+		precision string
+		want      interface{}
+	}{
+		{precision: "ms", want: int64(1640995200123)},
+		{precision: "us", want: int64(1640995200123456)},
+		{precision: "ns", want: int64(1640995200123456789)},
+		{precision: "", want: "2022-01-01T00:00:00.123456789Z"},
+	}
+	for _, c := range cases {
+		t.Run(c.precision, func(t *testing.T) {
+			if got := timeValue(value, c.precision); got != c.want {
+				t.Fatalf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	var (
+		typo = []map[string]interface{}{
+			{"ts": int64(1), "curent": 10.5},
+			{"ts": int64(2), "voltag": 220, "curent": 10.5},
+		}
+		unknownError = "unknown columns curent, voltag for table"
+	)
+	cases := []struct {
+		name   string
+		option Option
+		insert func(ctx context.Context, d *Driver) error
+		error  string
+	}{
+		{
+			name: "disabled",
+			insert: func(ctx context.Context, d *Driver) error {
+				_, err := d.Insert(ctx, "d1001", map[string]interface{}{"ts": int64(1), "curent": 10.5})
+				return err
+			},
+		},
+		{
+			name:   "known",
+			option: Option{ValidateSchema: true},
+			insert: func(ctx context.Context, d *Driver) error {
+				_, err := d.Insert(ctx, "d1001", map[string]interface{}{"ts": int64(1), "Current": 10.5})
+				return err
+			},
+		},
+		{
+			name:   "insert",
+			option: Option{ValidateSchema: true},
+			insert: func(ctx context.Context, d *Driver) error {
+				_, err := d.Insert(ctx, "d1001", typo)
+				return err
+			},
+			error: unknownError,
+		},
+		{
+			name:   "upsert",
+			option: Option{ValidateSchema: true},
+			insert: func(ctx context.Context, d *Driver) error {
+				_, err := d.Upsert(ctx, "d1001", typo)
+				return err
+			},
+			error: unknownError,
+		},
+		{
+			name:   "auto create",
+			option: Option{ValidateSchema: true},
+			insert: func(ctx context.Context, d *Driver) error {
+				_, err := d.InsertAutoCreate(ctx, "meters", []SubtableRows{{
+					Subtable: "d1001",
+					Tags:     map[string]interface{}{"location": "beijing", "group": 2},
+					Data:     map[string]interface{}{"ts": int64(1), "current": 10.5},
+				}})
+				return err
+			},
+			error: "unknown columns group for table",
+		},
+		{
+			name:   "batch insert",
+			option: Option{ValidateSchema: true},
+			insert: func(ctx context.Context, d *Driver) error {
+				_, err := d.BatchInsert(ctx, map[string]gdb.List{"d1001": typo})
+				return err
+			},
+			error: unknownError,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, c.option, metersHandler(nil))
+			err := c.insert(context.Background(), d)
+			var inserted bool
+			for _, sql := range server.Sqls() {
+				inserted = inserted || gstr.HasPrefix(sql, "INSERT")
+			}
+			if c.error == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !inserted {
+					t.Fatal("got no records inserted")
+				}
+				return
+			}
+			checkCode(t, err, gcode.CodeInvalidParameter)
+			if !gstr.Contains(err.Error(), c.error) {
+				t.Fatalf("got error %v, want %q", err, c.error)
+			}
+			if inserted {
+				t.Fatal("got records inserted with unknown columns")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package taosql
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// EnumMapping is the mapping of the integer values of a state column to their names.
+type EnumMapping map[int64]string
+
+// MapEnums translates the integer values of the columns of `result` to their names in place, by the mappings
+// of `mappings` keyed by column name, like the state codes to the state names, eg:
+//
+// taosql.MapEnums(result, map[string]taosql.EnumMapping{"status": {0: "offline", 1: "online"}})
+//
+// The NULL values, the values that are not integers and the values that have no names in the mapping are left
+// as they are, and so are the columns that are not in `mappings`.
+func MapEnums(result gdb.Result, mappings map[string]EnumMapping) {
+	for _, record := range result {
+		for column, mapping := range mappings {
+			value, ok := record[column]
+			if !ok || isNullValue(value) {
+				continue
+			}
+			var n int64
+			switch v := value.Val().(type) {
+			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+				n = gconv.Int64(v)
+			default:
+				continue
+			}
+			if name, ok := mapping[n]; ok {
+				record[column] = gvar.New(name)
+			}
+		}
+	}
+}
+
+// windowTimeColumns are the window pseudo columns of timestamps.
+var windowTimeColumns = []string{"_wstart", "_wend"}
+
+// convertWindowColumns converts the window pseudo columns `_wstart` and `_wend` of `result` that are read as epoch
+// integers, like by the connectors without the column types, to *gtime.Time at the precision of current schema,
+// and `_wduration` to int64, so that the window results can be scanned into structs directly.
+// The pseudo column `tbname` that is read as bytes is converted to string as well.
+// The columns that are already read as time values are left as they are.
+func (d *Driver) convertWindowColumns(ctx context.Context, result gdb.Result) error {
+	var precision string
+	for _, record := range result {
+		if err := d.convertWindowRecord(ctx, record, &precision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertWindowRecord converts the window pseudo columns of `record` in place, see convertWindowColumns.
+// The `precision` is the precision of current schema, which is retrieved and stored to it if it's empty.
+func (d *Driver) convertWindowRecord(ctx context.Context, record gdb.Record, precision *string) error {
+	for _, column := range windowTimeColumns {
+		value, ok := record[column]
+		if !ok || isNullValue(value) {
+			continue
+		}
+		switch value.Val().(type) {
+		case int, int32, int64, uint, uint32, uint64, float64:
+		default:
+			continue
+		}
+		if *precision == "" {
+			var err error
+			if *precision, err = d.Precision(ctx); err != nil {
+				return err
+			}
+		}
+		record[column] = gvar.New(gtime.NewFromTime(epochToTime(value.Int64(), *precision)))
+	}
+	if value, ok := record["_wduration"]; ok && !isNullValue(value) {
+		if _, ok = value.Val().(int64); !ok {
+			record["_wduration"] = gvar.New(value.Int64())
+		}
+	}
+	if value, ok := record["tbname"]; ok && !isNullValue(value) {
+		if _, ok = value.Val().(string); !ok {
+			record["tbname"] = gvar.New(value.String())
+		}
+	}
+	return nil
+}
+
+// isWindowColumn checks whether `column` is converted by convertWindowRecord.
+func isWindowColumn(column string) bool {
+	switch column {
+	case "_wstart", "_wend", "_wduration", "tbname":
+		return true
+	default:
+		return false
+	}
+}
+
+// epochToTime converts epoch integer `n` at `precision` to time, which is the reverse of timeToEpoch.
+func epochToTime(n int64, precision string) time.Time {
+	switch precision {
+	case "ns":
+		return time.Unix(0, n)
+	case "us":
+		return time.Unix(0, n*int64(time.Microsecond))
+	default:
+		return time.Unix(0, n*int64(time.Millisecond))
+	}
+}
@@ -0,0 +1,179 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+func TestMapEnums(t *testing.T) {
+	mappings := map[string]EnumMapping{
+		"status": {0: "offline", 1: "online"},
+		"level":  {1: "low", 2: "high"},
+	}
+	cases := []struct {
+		name   string
+		record gdb.Record
+		want   map[string]interface{}
+	}{
+		{
+			name:   "mapped values",
+			record: gdb.Record{"status": gvar.New(int8(1)), "level": gvar.New(uint32(2))},
+			want:   map[string]interface{}{"status": "online", "level": "high"},
+		},
+		{
+			name:   "zero value",
+			record: gdb.Record{"status": gvar.New(int64(0))},
+			want:   map[string]interface{}{"status": "offline"},
+		},
+		{
+			name:   "unmapped value",
+			record: gdb.Record{"status": gvar.New(int32(7))},
+			want:   map[string]interface{}{"status": int32(7)},
+		},
+		{
+			name:   "null value",
+			record: gdb.Record{"status": gvar.New(nil)},
+			want:   map[string]interface{}{"status": nil},
+		},
+		{
+			name:   "non-integer value",
+			record: gdb.Record{"status": gvar.New("1"), "level": gvar.New(1.0)},
+			want:   map[string]interface{}{"status": "1", "level": 1.0},
+		},
+		{
+			name:   "unmapped column",
+			record: gdb.Record{"voltage": gvar.New(int64(1))},
+			want:   map[string]interface{}{"voltage": int64(1)},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			MapEnums(gdb.Result{c.record}, mappings)
+			if len(c.record) != len(c.want) {
+				t.Fatalf("got record %v, want %v", c.record.Map(), c.want)
+			}
+			for column, want := range c.want {
+				if got := c.record[column].Val(); got != want {
+					t.Fatalf("got %s %#v, want %#v", column, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMapEnumsResult(t *testing.T) {
+	result := gdb.Result{
+		{"status": gvar.New(int64(1))},
+		{"status": gvar.New(int64(0))},
+		{"status": gvar.New(int64(2))},
+	}
+	MapEnums(result, map[string]EnumMapping{"status": {0: "offline", 1: "online"}})
+	want := []interface{}{"online", "offline", int64(2)}
+	for i, record := range result {
+		if got := record["status"].Val(); got != want[i] {
+			t.Fatalf("got status %#v of row %d, want %#v", got, i, want[i])
+		}
+	}
+}
+
+func TestConvertWindowColumns(t *testing.T) {
+	var (
+		start = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		end   = start.Add(10 * time.Second)
+	)
+	cases := []struct {
+		name   string
+		schema string
+		record gdb.Record
+		want   map[string]interface{}
+	}{
+		{
+			name:   "ms epoch",
+			schema: "power",
+			record: gdb.Record{
+				"_wstart":    gvar.New(start.UnixNano() / int64(time.Millisecond)),
+				"_wend":      gvar.New(end.UnixNano() / int64(time.Millisecond)),
+				"_wduration": gvar.New(int32(10000)),
+				"tbname":     gvar.New([]byte("d1001")),
+			},
+			want: map[string]interface{}{"_wstart": start, "_wend": end, "_wduration": int64(10000), "tbname": "d1001"},
+		},
+		{
+			name:   "us epoch",
+			schema: "archive",
+			record: gdb.Record{
+				"_wstart":    gvar.New(start.UnixNano() / int64(time.Microsecond)),
+				"_wend":      gvar.New(uint64(end.UnixNano() / int64(time.Microsecond))),
+				"_wduration": gvar.New(float64(10000000)),
+			},
+			want: map[string]interface{}{"_wstart": start, "_wend": end, "_wduration": int64(10000000)},
+		},
+		{
+			name:   "time values",
+			schema: "power",
+			record: gdb.Record{"_wstart": gvar.New(start), "_wduration": gvar.New(int64(10000)), "tbname": gvar.New("d1001")},
+			want:   map[string]interface{}{"_wstart": start, "_wduration": int64(10000), "tbname": "d1001"},
+		},
+		{
+			name:   "null values",
+			schema: "power",
+			record: gdb.Record{"_wstart": gvar.New(nil), "_wend": nil},
+			want:   map[string]interface{}{"_wstart": nil, "_wend": nil},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockSchemaCluster(t, c.schema, Option{}, metersHandler(nil))
+			if err := d.convertWindowColumns(context.Background(), gdb.Result{c.record}); err != nil {
+				t.Fatal(err)
+			}
+			for column, want := range c.want {
+				got := c.record[column].Val()
+				if gt, ok := got.(*gtime.Time); ok {
+					got = gt.Time
+				}
+				if wt, ok := want.(time.Time); ok {
+					if gotTime, ok := got.(time.Time); !ok || !gotTime.Equal(wt) {
+						t.Fatalf("got %s %#v, want %v", column, got, want)
+					}
+					continue
+				}
+				if got != want {
+					t.Fatalf("got %s %#v, want %#v", column, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertWindowColumnsScan(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, _ := newMockDriver(t, Option{}, metersHandler(func(mockQuery) mockResponse {
+		return mockRecords(
+			[]string{"_wstart", "_wend", "_wduration", "avg_current"},
+			[]interface{}{start.UnixNano() / int64(time.Millisecond), start.Add(time.Minute).UnixNano() / int64(time.Millisecond), int64(60000), 10.5},
+		)
+	}))
+	var windows []struct {
+		WStart     *gtime.Time `orm:"_wstart"`
+		WEnd       time.Time   `orm:"_wend"`
+		WDuration  int64       `orm:"_wduration"`
+		AvgCurrent float64     `orm:"avg_current"`
+	}
+	err := d.GetScan(context.Background(), &windows, "SELECT _wstart, _wend, _wduration, AVG(current) AS avg_current FROM d1001 INTERVAL(1m)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 1 {
+		t.Fatalf("got windows %+v, want 1", windows)
+	}
+	w := windows[0]
+	if w.WStart == nil || !w.WStart.Time.Equal(start) || !w.WEnd.Equal(start.Add(time.Minute)) || w.WDuration != 60000 || w.AvgCurrent != 10.5 {
+		t.Fatalf("got window %+v", w)
+	}
+}
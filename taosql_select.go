@@ -0,0 +1,565 @@
+package taosql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+const (
+	ctxKeyForRequireRows gctx.StrKey = `TaossqlRequireRows`
+
+	errMsgNoRows = `no rows retrieved`
+)
+
+// WithRequireRows creates and returns a new context from `ctx`, with which the query helpers return an error
+// of code gcode.CodeNotFound if no rows are retrieved.
+//
+// The query helpers of the driver, like SelectJSON, SelectOrdered, Interp, TopDevices and LastRowPerPartition,
+// return empty but non-nil result with no error on no rows in default.
+func WithRequireRows(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyForRequireRows, true)
+}
+
+// isRequireRows checks whether the query helpers are required to return rows with `ctx`, see WithRequireRows.
+func isRequireRows(ctx context.Context) bool {
+	requireRows, _ := ctx.Value(ctxKeyForRequireRows).(bool)
+	return requireRows
+}
+
+// selectAll queries with given `sql` and `args` by GetAll, and returns empty but non-nil result on no rows,
+// or an error of code gcode.CodeNotFound if it is required to return rows by `ctx`.
+func (d *Driver) selectAll(ctx context.Context, sql string, args ...interface{}) (gdb.Result, error) {
+	result, err := d.GetAll(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		if isRequireRows(ctx) {
+			return nil, gerror.NewCode(gcode.CodeNotFound, errMsgNoRows)
+		}
+		return gdb.Result{}, nil
+	}
+	return result, nil
+}
+
+// SelectJSON queries with given `sql` and `args`, and returns the result records marshaled
+// as a JSON array, in which each record is a JSON object keyed by column name.
+//
+// Timestamps are formatted using Option.JSONTimeLayout, and NULL values are output as JSON null.
+// It returns `[]` if there's no record retrieved, see WithRequireRows.
+func (d *Driver) SelectJSON(ctx context.Context, sql string, args ...interface{}) ([]byte, error) {
+	result, err := d.selectAll(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		layout = d.option.JSONTimeLayout
+		list   = make([]map[string]interface{}, 0, len(result))
+	)
+	if layout == "" {
+		layout = defaultJSONTimeLayout
+	}
+	for _, record := range result {
+		item := make(map[string]interface{}, len(record))
+		for k, v := range record {
+			switch value := v.Val().(type) {
+			case time.Time:
+				item[k] = value.Format(layout)
+			case *gtime.Time:
+				item[k] = value.Layout(layout)
+			default:
+				item[k] = value
+			}
+		}
+		list = append(list, item)
+	}
+	content, err := gjson.Encode(list)
+	if err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInternalError, err, `gjson.Encode failed for query result`)
+	}
+	return content, nil
+}
+
+// InterpInput is the input parameters for function Interp.
+type InterpInput struct {
+	Table    string        // Table or super table to query.
+	Columns  []string      // Columns or expressions to interpolate, each of which is wrapped with INTERP.
+	TsColumn string        // Primary timestamp column for the time range condition, which is `ts` in default.
+	Start    time.Time     // Start of the interpolation points, inclusive.
+	End      time.Time     // End of the interpolation points, inclusive.
+	Every    Interval      // Interval between the interpolation points, like: 1s, 5m.
+	Fill     FillMode      // (Optional) Fill mode for the points that have no data exactly at them.
+	Values   []interface{} // (Optional) Fill values for FillValue mode, one for each column of Columns.
+	Lookback time.Duration // (Optional) Lookback window before Start for the data that fills the first points.
+}
+
+// Interp queries the interpolation values of given columns at every `Every` interval within [Start, End].
+// The returned records contain the interpolation timestamp in column `_irowts`.
+//
+// Note that with FillPrev or FillLinear, a point is filled from the data before it, so the points at the
+// start boundary are NULL if there's no data before them within the queried time range. The `Lookback`
+// extends the queried time range to [Start - Lookback, End], so that the first points can be filled from
+// the prior data within the lookback window. It does not change the output points.
+//
+// With FillValue, the constant values are coerced to the types of the columns, like 0 to 0.0 for DOUBLE columns,
+// and it returns an error of code gcode.CodeInvalidParameter if a value cannot be converted to its column type.
+func (d *Driver) Interp(ctx context.Context, in InterpInput) (gdb.Result, error) {
+	if len(in.Columns) == 0 {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, `at least one column is required for INTERP`)
+	}
+	if in.Every == "" {
+		return nil, gerror.NewCode(gcode.CodeInvalidParameter, `every interval is required for INTERP`)
+	}
+	if in.Lookback < 0 {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `invalid negative lookback "%s"`, in.Lookback)
+	}
+	tsColumn := in.TsColumn
+	if tsColumn == "" {
+		tsColumn = defaultTsColumn
+	}
+	fields := []string{"_irowts"}
+	for _, column := range in.Columns {
+		field, err := Interp(d.QuoteWord(column))
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	clause := NewClause().Range(in.Start, in.End).Every(in.Every)
+	if in.Fill != "" {
+		values := in.Values
+		if in.Fill == FillValue {
+			var err error
+			if values, err = d.coerceFillValues(ctx, in.Table, in.Columns, in.Values); err != nil {
+				return nil, err
+			}
+		}
+		clause.Fill(in.Fill, values...)
+	}
+	clauseStr, err := clause.Build()
+	if err != nil {
+		return nil, err
+	}
+	tsColumn = d.QuoteWord(tsColumn)
+	return d.selectAll(withoutClause(ctx), fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s >= %s AND %s <= %s %s`,
+		gstr.Join(fields, ","), d.QuotePrefixTableName(in.Table),
+		tsColumn, formatTimeLiteral(in.Start.Add(-in.Lookback)),
+		tsColumn, formatTimeLiteral(in.End),
+		clauseStr,
+	))
+}
+
+// SelectChunked queries all the records of `table` within time range [start, end), by splitting it into
+// sub-ranges of `chunk` duration, and calls `fn` with the records of each sub-range, which processes a wide
+// time range with bounded memory and no single long-running query.
+//
+// The sub-ranges are half-open [chunkStart, chunkStart+chunk), so that a record on a boundary is retrieved
+// exactly once, and the last sub-range is truncated at `end`. The sub-ranges are queried in ascending time
+// order, and the records of each sub-range are ordered by the primary timestamp column of `table`.
+// The `fn` is not called for the sub-ranges that have no records. It stops querying if `fn` returns error
+// or `ctx` is done, and returns the error.
+func (d *Driver) SelectChunked(
+	ctx context.Context, table string, start, end time.Time, chunk time.Duration, fn func(result gdb.Result) error,
+) error {
+	if chunk <= 0 {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid chunk duration "%s", it should be positive`, chunk)
+	}
+	if fn == nil {
+		return gerror.NewCode(gcode.CodeInvalidParameter, `callback function should not be nil`)
+	}
+	tsColumn, err := d.primaryTsColumn(ctx, table)
+	if err != nil {
+		return err
+	}
+	tsColumn = d.QuoteWord(tsColumn)
+	query := fmt.Sprintf(
+		`SELECT * FROM %s WHERE %s >= %%s AND %s < %%s ORDER BY %s`,
+		d.QuotePrefixTableName(table), tsColumn, tsColumn, tsColumn,
+	)
+	for chunkStart := start; chunkStart.Before(end); chunkStart = chunkStart.Add(chunk) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunkEnd := chunkStart.Add(chunk)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		result, err := d.GetAll(
+			withoutClause(ctx), fmt.Sprintf(query, formatTimeLiteral(chunkStart), formatTimeLiteral(chunkEnd)),
+		)
+		if err != nil {
+			return err
+		}
+		if len(result) == 0 {
+			continue
+		}
+		if err = fn(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TopDevices queries the top `n` partitions of super table `stable` by tag `tagCol`, ranked by aggregate
+// `aggExpr` in descending order, like the top busiest devices by COUNT(*). It returns the records
+// that contain the tag value in column `tagCol` and the aggregate value in column `agg_value`.
+//
+// TDengine does not allow ordering the partitions of PARTITION BY by the aggregate in the same statement,
+// so the aggregate is computed in a subquery and ranked by the outer query:
+//
+// SELECT * FROM (SELECT tagCol, aggExpr AS agg_value FROM stable PARTITION BY tagCol)
+// ORDER BY agg_value DESC LIMIT n
+func (d *Driver) TopDevices(ctx context.Context, stable, tagCol, aggExpr string, n int) (gdb.Result, error) {
+	if n <= 0 {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `invalid top count %d, it should be positive`, n)
+	}
+	if err := checkExpr(tagCol); err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid tag column for top devices`)
+	}
+	if err := checkExpr(aggExpr); err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid aggregate expression for top devices`)
+	}
+	tagCol = d.QuoteWord(tagCol)
+	return d.selectAll(withoutClause(ctx), fmt.Sprintf(
+		`SELECT * FROM (SELECT %s, %s AS agg_value FROM %s PARTITION BY %s) ORDER BY agg_value DESC LIMIT %d`,
+		tagCol, aggExpr, d.QuotePrefixTableName(stable), tagCol, n,
+	))
+}
+
+// OrderedResult is the query result that preserves the column order of the SELECT statement,
+// which is lost by gdb.Result as its records are maps, like for CSV export and tabular display.
+type OrderedResult struct {
+	Columns []string        // Column names in the order of the SELECT statement.
+	Rows    [][]interface{} // Row values, each of which is in the same order of Columns.
+}
+
+// SelectOrdered queries with given `sql` and `args`, and returns the result in the column order of
+// the statement, which is sourced from sql.Rows.Columns. The values are as they are returned by
+// the underlying driver, like: int64, float64, string, bool, time.Time, and nil for NULL, except that the
+// window pseudo columns are converted the same as DoCommit does, like _wstart to *gtime.Time.
+//
+// It does not return nil result for no records, but an OrderedResult with Columns and empty Rows.
+func (d *Driver) SelectOrdered(ctx context.Context, sql string, args ...interface{}) (*OrderedResult, error) {
+	var (
+		err    error
+		result = &OrderedResult{Rows: make([][]interface{}, 0)}
+	)
+	result.Columns, err = d.queryRows(ctx, sql, args, func(columns []string, values []interface{}) error {
+		result.Rows = append(result.Rows, values)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Rows) == 0 && isRequireRows(ctx) {
+		return nil, gerror.NewCode(gcode.CodeNotFound, errMsgNoRows)
+	}
+	return result, nil
+}
+
+// queryRows queries with given `query` and `args` on the slave link, or on the link of the read preference
+// of `ctx`, and calls `fn` with the values of each row in the column order of the statement, which are not
+// retained by DoCommit as package gdb converts the rows to records. It returns the columns of the statement,
+// and stops iterating the rows if `fn` returns error, in which the `values` are new for each row.
+//
+// The statement is handled the same as DoCommit does: it is skipped in dry run mode, in which case it returns
+// no columns and `fn` is never called, it returns promptly once the deadline of `ctx` or the QueryTimeout of
+// the configuration is exceeded, it's logged if it takes longer than Option.SlowQueryThreshold along with
+// the iteration, the returned error retains the TDengine error code, and the window pseudo columns are converted.
+func (d *Driver) queryRows(
+	ctx context.Context, query string, args []interface{}, fn func(columns []string, values []interface{}) error,
+) (columns []string, err error) {
+	link, err := d.preferredLink(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		if tx := gdb.TXFromCtx(ctx, d.GetGroup()); tx != nil {
+			return nil, gerror.NewCode(gcode.CodeNotSupported, `query of ordered rows is not supported within transaction`)
+		}
+		if link, err = d.SlaveLink(); err != nil {
+			return nil, err
+		}
+	}
+	if timeout := d.GetConfig().QueryTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if query, args, err = d.DoFilter(ctx, link, query, args); err != nil {
+		return nil, err
+	}
+	in := gdb.DoCommitInput{Link: link, Sql: query, Args: args, Type: gdb.SqlTypeQueryContext}
+	if d.dryRun(ctx, in) {
+		return nil, nil
+	}
+	start := d.now()
+	defer func() {
+		d.logSlowQuery(ctx, in, d.now().Sub(start))
+	}()
+	var rows *sql.Rows
+	if err = d.runWithDeadline(ctx, func() (err error) {
+		rows, err = link.QueryContext(ctx, query, args...)
+		return
+	}, func() {
+		if rows != nil {
+			_ = rows.Close()
+		}
+	}); err != nil {
+		return nil, wrapTaosError(gerror.WrapCodef(gcode.CodeDbOperationError, err, `%s`, query))
+	}
+	defer rows.Close()
+	if columns, err = rows.Columns(); err != nil {
+		return nil, gerror.WrapCode(gcode.CodeDbOperationError, err, `retrieve columns of query result failed`)
+	}
+	var (
+		precision     string
+		windowIndexes []int
+		metadataCtx   = withoutDryRun(withoutClause(ctx))
+	)
+	for i, column := range columns {
+		if isWindowColumn(column) {
+			windowIndexes = append(windowIndexes, i)
+		}
+	}
+	for rows.Next() {
+		var (
+			values   = make([]interface{}, len(columns))
+			scanArgs = make([]interface{}, len(values))
+		)
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+		if err = rows.Scan(scanArgs...); err != nil {
+			return nil, gerror.WrapCode(gcode.CodeDbOperationError, err, `scan query result failed`)
+		}
+		if len(windowIndexes) > 0 {
+			record := make(gdb.Record, len(windowIndexes))
+			for _, i := range windowIndexes {
+				record[columns[i]] = gvar.New(values[i])
+			}
+			if err = d.convertWindowRecord(metadataCtx, record, &precision); err != nil {
+				return nil, err
+			}
+			for _, i := range windowIndexes {
+				values[i] = record[columns[i]].Val()
+			}
+		}
+		if err = fn(columns, values); err != nil {
+			return nil, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapTaosError(gerror.WrapCode(gcode.CodeDbOperationError, err, `iterate query result failed`))
+	}
+	return columns, nil
+}
+
+// coerceFillValues coerces the constant fill `values` to the types of `columns` of `table` respectively.
+func (d *Driver) coerceFillValues(
+	ctx context.Context, table string, columns []string, values []interface{},
+) ([]interface{}, error) {
+	if len(values) != len(columns) {
+		return nil, gerror.NewCodef(
+			gcode.CodeInvalidParameter,
+			`fill mode VALUE requires %d values for the columns, but %d given`, len(columns), len(values),
+		)
+	}
+	fields, err := d.TableFields(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	coerced := make([]interface{}, len(values))
+	for i, column := range columns {
+		field, ok := fields[column]
+		if !ok {
+			return nil, gerror.NewCodef(gcode.CodeNotFound, `column "%s" not found in table "%s"`, column, table)
+		}
+		if coerced[i], err = coerceValue(values[i], field.Type); err != nil {
+			return nil, gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid fill value for column "%s"`, column)
+		}
+	}
+	return coerced, nil
+}
+
+// coerceValue converts constant `value` to the Go value of column type `columnType`.
+func coerceValue(value interface{}, columnType string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	typeName := columnTypeName(columnType)
+	switch typeName {
+	case "tinyint", "smallint", "int", "bigint", "tinyint unsigned", "smallint unsigned", "int unsigned",
+		"bigint unsigned", "float", "double":
+		if s, ok := value.(string); ok && !gstr.IsNumeric(s) {
+			return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `value "%s" is not numeric for type %s`, s, columnType)
+		}
+		if _, ok := value.(bool); ok {
+			return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `bool value is not numeric for type %s`, columnType)
+		}
+		switch typeName {
+		case "float", "double":
+			return gconv.Float64(value), nil
+		case "tinyint unsigned", "smallint unsigned", "int unsigned", "bigint unsigned":
+			return gconv.Uint64(value), nil
+		default:
+			return gconv.Int64(value), nil
+		}
+	case "bool":
+		return gconv.Bool(value), nil
+	case "binary", "varchar", "nchar":
+		return gconv.String(value), nil
+	default:
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `constant fill is not supported for type %s`, columnType)
+	}
+}
+
+// LastRowInput is the input parameters for function LastRowPerPartition.
+type LastRowInput struct {
+	Stable      string   // Super table to query.
+	Columns     []string // (Optional) Columns to retrieve the last row values, which are all columns in default.
+	PartitionBy string   // (Optional) Partition expression, which is `tbname` in default, that is per subtable.
+	SLimit      int      // (Optional) Max number of partitions to return, which are all partitions in default.
+}
+
+// LastRowPerPartition queries the last row of each partition of `Stable`, like the latest values of each device,
+// which returns one record for each partition, containing the partition expression and the columns.
+//
+// The query is in shape `SELECT tbname, LAST_ROW(col) AS col FROM stable PARTITION BY tbname [SLIMIT n]`,
+// which is computed per subtable by the server, and served from the last row cache if the database is created
+// with CACHEMODEL 'last_row' or 'both', rather than scanning all the data. The SLIMIT pushes the limit
+// on the number of partitions down to the server. Note that LIMIT instead limits the rows per partition.
+func (d *Driver) LastRowPerPartition(ctx context.Context, in LastRowInput) (gdb.Result, error) {
+	return d.lastRowPerPartition(ctx, in, "")
+}
+
+// LatestValues queries the latest values of `fields` of each subtable of `stable` whose tags match `tagFilter`
+// in one query, like the latest readings of the devices of a dashboard, which returns one record for each matched
+// subtable, containing its `tbname` and the fields. The `fields` are all columns in default.
+//
+// The `tagFilter` is the tag values keyed by the tag names, which are AND-ed as equality predicates, in which
+// the nil values match the null tags. It queries all subtables if `tagFilter` is empty, see LastRowPerPartition.
+func (d *Driver) LatestValues(ctx context.Context, stable string, tagFilter map[string]interface{}, fields ...string) (gdb.Result, error) {
+	tags := make([]string, 0, len(tagFilter))
+	for tag := range tagFilter {
+		if !gregex.IsMatchString(`^\w+$`, tag) {
+			return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `invalid tag name "%s" for tag filter`, tag)
+		}
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	conditions := make([]string, len(tags))
+	for i, tag := range tags {
+		if value := tagFilter[tag]; value == nil {
+			conditions[i] = fmt.Sprintf(`%s IS NULL`, d.QuoteWord(tag))
+		} else {
+			conditions[i] = fmt.Sprintf(`%s=%s`, d.QuoteWord(tag), formatLiteral(value))
+		}
+	}
+	return d.lastRowPerPartition(ctx, LastRowInput{
+		Stable:  stable,
+		Columns: fields,
+	}, gstr.Join(conditions, " AND "))
+}
+
+// lastRowPerPartition queries the last row of each partition of `in.Stable` with optional WHERE `condition`.
+func (d *Driver) lastRowPerPartition(ctx context.Context, in LastRowInput, condition string) (gdb.Result, error) {
+	partitionBy := in.PartitionBy
+	if partitionBy == "" {
+		partitionBy = "tbname"
+	}
+	if err := checkExpr(partitionBy); err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid partition expression for LAST_ROW`)
+	}
+	if in.SLimit < 0 {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `invalid negative SLIMIT %d`, in.SLimit)
+	}
+	fields := []string{partitionBy}
+	if len(in.Columns) == 0 {
+		fields = append(fields, `LAST_ROW(*)`)
+	}
+	for _, column := range in.Columns {
+		field, err := buildFunc("LAST_ROW", d.QuoteWord(column))
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, fmt.Sprintf(`%s AS %s`, field, d.QuoteWord(column)))
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s`, gstr.Join(fields, ","), d.QuotePrefixTableName(in.Stable))
+	if condition != "" {
+		query += ` WHERE ` + condition
+	}
+	query += ` PARTITION BY ` + partitionBy
+	if in.SLimit > 0 {
+		query += fmt.Sprintf(` SLIMIT %d`, in.SLimit)
+	}
+	return d.selectAll(withoutClause(ctx), query)
+}
+
+// TopKPerPartition queries the top `k` rows of each partition of `stable` by `orderBy`, like the 3 highest
+// readings of each device: TopKPerPartition(ctx, "meters", "tbname", "current DESC", 3), which returns the rows
+// of each partition contiguously, containing the partition expression and all columns.
+//
+// The query is in shape `SELECT tbname, * FROM stable PARTITION BY tbname ORDER BY current DESC LIMIT 3`,
+// in which the ORDER BY and LIMIT are applied per partition by the server.
+func (d *Driver) TopKPerPartition(ctx context.Context, stable, partitionCol string, orderBy string, k int) (gdb.Result, error) {
+	if k <= 0 {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `invalid k %d, it should be positive`, k)
+	}
+	if err := checkExpr(partitionCol); err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid partition expression for top k`)
+	}
+	if err := checkExpr(orderBy); err != nil {
+		return nil, gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid order for top k`)
+	}
+	return d.selectAll(withoutClause(ctx), fmt.Sprintf(
+		`SELECT %s, * FROM %s PARTITION BY %s ORDER BY %s LIMIT %d`,
+		partitionCol, d.QuotePrefixTableName(stable), partitionCol, orderBy, k,
+	))
+}
+
+// RecentRows queries the most recent `n` rows of `table` newest first, with optional `fields`,
+// which are all columns in default.
+//
+// The query is in shape `SELECT fields FROM table ORDER BY ts DESC LIMIT n` on the primary timestamp column,
+// which TDengine scans backwards from the latest data block and stops at `n` rows, rather than scanning
+// all the data and sorting.
+func (d *Driver) RecentRows(ctx context.Context, table string, n int, fields ...string) (gdb.Result, error) {
+	if n <= 0 {
+		return nil, gerror.NewCodef(gcode.CodeInvalidParameter, `invalid row count %d, it should be positive`, n)
+	}
+	tsColumn, err := d.primaryTsColumn(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	fieldStr := "*"
+	if len(fields) > 0 {
+		array := make([]string, len(fields))
+		for i, field := range fields {
+			if err = checkExpr(field); err != nil {
+				return nil, gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid field for recent rows`)
+			}
+			array[i] = d.QuoteWord(field)
+		}
+		fieldStr = gstr.Join(array, ",")
+	}
+	return d.selectAll(withoutClause(ctx), fmt.Sprintf(
+		`SELECT %s FROM %s ORDER BY %s DESC LIMIT %d`,
+		fieldStr, d.QuotePrefixTableName(table), d.QuoteWord(tsColumn), n,
+	))
+}
@@ -0,0 +1,691 @@
+package taosql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gstr"
+	taosErrors "github.com/taosdata/driver-go/v2/errors"
+)
+
+func TestSelectJSON(t *testing.T) {
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	cases := []struct {
+		name     string
+		option   Option
+		response mockResponse
+		ctx      context.Context
+		want     string
+		code     gcode.Code
+	}{
+		{
+			name:     "records",
+			response: mockRecords([]string{"ts", "current", "location"}, []interface{}{ts, 10.5, nil}),
+			want:     `[{"current":10.5,"location":null,"ts":"2023-01-02T03:04:05.006Z"}]`,
+		},
+		{
+			name:     "time layout",
+			option:   Option{JSONTimeLayout: "2006-01-02 15:04:05"},
+			response: mockRecords([]string{"ts"}, []interface{}{ts}),
+			want:     `[{"ts":"2023-01-02 03:04:05"}]`,
+		},
+		{
+			name:     "no rows",
+			response: mockRecords([]string{"ts"}),
+			want:     `[]`,
+		},
+		{
+			name:     "require rows",
+			response: mockRecords([]string{"ts"}),
+			ctx:      WithRequireRows(context.Background()),
+			code:     gcode.CodeNotFound,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, c.option, func(mockQuery) mockResponse { return c.response })
+			ctx := c.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			content, err := d.SelectJSON(ctx, "SELECT * FROM meters")
+			checkCode(t, err, c.code)
+			if c.code == nil && string(content) != c.want {
+				t.Fatalf("got %s, want %s", content, c.want)
+			}
+		})
+	}
+}
+
+func TestInterp(t *testing.T) {
+	var (
+		start = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		end   = start.Add(2 * time.Second)
+	)
+	cases := []struct {
+		name      string
+		in        InterpInput
+		response  mockResponse
+		wantSql   string
+		wantFirst interface{}
+		code      gcode.Code
+	}{
+		{
+			name: "no prior data",
+			in: InterpInput{
+				Table: "d1001", Columns: []string{"current"}, Start: start, End: end, Every: "1s", Fill: FillPrev,
+			},
+			response: mockRecords(
+				[]string{"_irowts", "interp(`current`)"},
+				[]interface{}{start, nil}, []interface{}{start.Add(time.Second), 10.5},
+			),
+			wantSql: "SELECT _irowts,INTERP(`current`) FROM `d1001` " +
+				"WHERE `ts` >= '2023-01-01T00:00:00Z' AND `ts` <= '2023-01-01T00:00:02Z' " +
+				"RANGE('2023-01-01T00:00:00Z', '2023-01-01T00:00:02Z') EVERY(1s) FILL(PREV)",
+			wantFirst: nil,
+		},
+		{
+			name: "lookback",
+			in: InterpInput{
+				Table: "d1001", Columns: []string{"current"}, Start: start, End: end, Every: "1s", Fill: FillPrev,
+				Lookback: time.Hour,
+			},
+			response: mockRecords(
+				[]string{"_irowts", "interp(`current`)"},
+				[]interface{}{start, 9.5}, []interface{}{start.Add(time.Second), 10.5},
+			),
+			wantSql: "SELECT _irowts,INTERP(`current`) FROM `d1001` " +
+				"WHERE `ts` >= '2022-12-31T23:00:00Z' AND `ts` <= '2023-01-01T00:00:02Z' " +
+				"RANGE('2023-01-01T00:00:00Z', '2023-01-01T00:00:02Z') EVERY(1s) FILL(PREV)",
+			wantFirst: 9.5,
+		},
+		{
+			name: "negative lookback",
+			in:   InterpInput{Table: "d1001", Columns: []string{"current"}, Every: "1s", Lookback: -time.Second},
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "no columns",
+			in:   InterpInput{Table: "d1001", Every: "1s"},
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "no every",
+			in:   InterpInput{Table: "d1001", Columns: []string{"current"}},
+			code: gcode.CodeInvalidParameter,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse { return c.response })
+			result, err := d.Interp(context.Background(), c.in)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				return
+			}
+			if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != c.wantSql {
+				t.Fatalf("got sqls %q, want %q", sqls, c.wantSql)
+			}
+			if got := result[0]["interp(`current`)"].Val(); got != c.wantFirst {
+				t.Fatalf("got first point %v, want %v", got, c.wantFirst)
+			}
+		})
+	}
+}
+
+func TestSelectChunked(t *testing.T) {
+	var (
+		start = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		desc  = mockRecords(
+			[]string{"field", "type", "length", "note"},
+			[]interface{}{"event_time", "TIMESTAMP", int64(8), ""},
+			[]interface{}{"current", "FLOAT", int64(4), ""},
+		)
+	)
+	cases := []struct {
+		name      string
+		end       time.Time
+		chunk     time.Duration
+		wantSqls  []string
+		wantCalls int
+		code      gcode.Code
+	}{
+		{
+			name:  "truncated last chunk",
+			end:   start.Add(150 * time.Minute),
+			chunk: time.Hour,
+			wantSqls: []string{
+				"SELECT * FROM `d1001` WHERE `event_time` >= '2023-01-01T00:00:00Z' AND `event_time` < '2023-01-01T01:00:00Z' ORDER BY `event_time`",
+				"SELECT * FROM `d1001` WHERE `event_time` >= '2023-01-01T01:00:00Z' AND `event_time` < '2023-01-01T02:00:00Z' ORDER BY `event_time`",
+				"SELECT * FROM `d1001` WHERE `event_time` >= '2023-01-01T02:00:00Z' AND `event_time` < '2023-01-01T02:30:00Z' ORDER BY `event_time`",
+			},
+			// The second chunk has no records.
+			wantCalls: 2,
+		},
+		{
+			name:  "exact boundary",
+			end:   start.Add(time.Hour),
+			chunk: time.Hour,
+			wantSqls: []string{
+				"SELECT * FROM `d1001` WHERE `event_time` >= '2023-01-01T00:00:00Z' AND `event_time` < '2023-01-01T01:00:00Z' ORDER BY `event_time`",
+			},
+			wantCalls: 1,
+		},
+		{
+			name:  "empty range",
+			end:   start,
+			chunk: time.Hour,
+		},
+		{
+			name:  "invalid chunk",
+			end:   start.Add(time.Hour),
+			chunk: 0,
+			code:  gcode.CodeInvalidParameter,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+				switch {
+				case gstr.HasPrefix(q.Sql, "desc "):
+					return desc
+				case gstr.Contains(q.Sql, ">= '2023-01-01T01:00:00Z'"):
+					return mockRecords([]string{"event_time", "current"})
+				default:
+					return mockRecords([]string{"event_time", "current"}, []interface{}{start, 10.5})
+				}
+			})
+			calls := 0
+			err := d.SelectChunked(context.Background(), "d1001", start, c.end, c.chunk, func(result gdb.Result) error {
+				calls++
+				return nil
+			})
+			checkCode(t, err, c.code)
+			var sqls []string
+			for _, sql := range server.Sqls() {
+				if gstr.HasPrefix(sql, "SELECT") {
+					sqls = append(sqls, sql)
+				}
+			}
+			if !reflect.DeepEqual(sqls, c.wantSqls) {
+				t.Fatalf("got sqls %q, want %q", sqls, c.wantSqls)
+			}
+			if calls != c.wantCalls {
+				t.Fatalf("got %d calls, want %d", calls, c.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSelectChunkedCallbackError(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(func(mockQuery) mockResponse {
+		return mockRecords([]string{"ts"}, []interface{}{time.Now()})
+	}))
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := d.SelectChunked(context.Background(), "d1001", start, start.Add(3*time.Hour), time.Hour, func(gdb.Result) error {
+		return gerror.NewCode(gcode.CodeInternalError, "stop")
+	})
+	checkCode(t, err, gcode.CodeInternalError)
+	if n := len(server.Sqls()); n != 2 {
+		t.Fatalf("got %d statements, want desc and the first chunk", n)
+	}
+}
+
+func TestTopDevices(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse {
+		return mockRecords(
+			[]string{"location", "agg_value"},
+			[]interface{}{"beijing", int64(30)}, []interface{}{"shanghai", int64(20)},
+		)
+	})
+	result, err := d.TopDevices(context.Background(), "meters", "location", "COUNT(*)", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT * FROM (SELECT `location`, COUNT(*) AS agg_value FROM `meters` PARTITION BY `location`) " +
+		"ORDER BY agg_value DESC LIMIT 2"
+	if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != want {
+		t.Fatalf("got sqls %q, want %q", sqls, want)
+	}
+	var ranking []string
+	for _, record := range result {
+		ranking = append(ranking, record["location"].String())
+	}
+	if !reflect.DeepEqual(ranking, []string{"beijing", "shanghai"}) {
+		t.Fatalf("got ranking %q", ranking)
+	}
+	for name, call := range map[string]func() (gdb.Result, error){
+		"invalid count": func() (gdb.Result, error) {
+			return d.TopDevices(context.Background(), "meters", "location", "COUNT(*)", 0)
+		},
+		"invalid tag": func() (gdb.Result, error) {
+			return d.TopDevices(context.Background(), "meters", "location)", "COUNT(*)", 2)
+		},
+		"invalid aggregate": func() (gdb.Result, error) {
+			return d.TopDevices(context.Background(), "meters", "location", "COUNT(*); DROP TABLE meters", 2)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := call()
+			checkCode(t, err, gcode.CodeInvalidParameter)
+		})
+	}
+}
+
+func TestSelectOrdered(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+		switch q.Sql {
+		case "SELECT voltage, ts, current FROM d1001":
+			return mockRecords(
+				[]string{"voltage", "ts", "current"},
+				[]interface{}{int64(220), ts, 10.5},
+				[]interface{}{nil, ts.Add(time.Second), 11.5},
+			)
+		case "SELECT _wstart, tbname, COUNT(*) FROM meters PARTITION BY tbname INTERVAL(1m)":
+			return mockRecords(
+				[]string{"_wstart", "tbname", "count(*)"},
+				[]interface{}{ts.UnixNano() / int64(time.Millisecond), []byte("d1001"), int64(3)},
+			)
+		}
+		return mockRecords([]string{"ts", "current"})
+	}))
+	ctx := context.Background()
+
+	result, err := d.SelectOrdered(ctx, "SELECT voltage, ts, current FROM d1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &OrderedResult{
+		Columns: []string{"voltage", "ts", "current"},
+		Rows:    [][]interface{}{{int64(220), ts, 10.5}, {nil, ts.Add(time.Second), 11.5}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("got %#v, want %#v", result, want)
+	}
+
+	// The window pseudo columns are converted the same as DoCommit does.
+	if result, err = d.SelectOrdered(ctx, "SELECT _wstart, tbname, COUNT(*) FROM meters PARTITION BY tbname INTERVAL(1m)"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Columns, []string{"_wstart", "tbname", "count(*)"}) {
+		t.Fatalf("got columns %q", result.Columns)
+	}
+	row := result.Rows[0]
+	if wstart, ok := row[0].(*gtime.Time); !ok || !wstart.Time.Equal(ts) {
+		t.Fatalf("got _wstart %#v, want %s", row[0], ts)
+	}
+	if row[1] != "d1001" || row[2] != int64(3) {
+		t.Fatalf("got row %#v", row)
+	}
+
+	// No rows.
+	if result, err = d.SelectOrdered(ctx, "SELECT ts, current FROM d1002"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Columns, []string{"ts", "current"}) || result.Rows == nil || len(result.Rows) != 0 {
+		t.Fatalf("got %#v, want columns and empty rows", result)
+	}
+	_, err = d.SelectOrdered(WithRequireRows(ctx), "SELECT ts, current FROM d1002")
+	checkCode(t, err, gcode.CodeNotFound)
+
+	// Dry run.
+	recorder := NewDryRun()
+	if result, err = d.SelectOrdered(WithDryRun(ctx, recorder), "SELECT ts, current FROM d1002"); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 0 || len(recorder.Statements()) != 1 {
+		t.Fatalf("got %#v and %d recorded statements in dry run", result, len(recorder.Statements()))
+	}
+	// The statements are the queries except the dry run one, and `SHOW DATABASES` for the precision of _wstart.
+	if n := len(server.Sqls()); n != 5 {
+		t.Fatalf("got %d statements, want 5", n)
+	}
+}
+
+func TestSelectOrderedCommit(t *testing.T) {
+	var (
+		block   = make(chan struct{})
+		taosErr = &taosErrors.TaosError{Code: 0x2662, ErrStr: "Table does not exist"}
+	)
+	defer close(block)
+	d, _ := newMockDriver(t, Option{SlowQueryThreshold: time.Hour, Clock: stepClock(2 * time.Hour)}, func(q mockQuery) mockResponse {
+		switch q.Sql {
+		case "SELECT * FROM missing":
+			return mockResponse{Err: taosErr}
+		case "SELECT * FROM slow":
+			<-block
+		}
+		return mockRecords([]string{"ts"})
+	})
+	logs := captureLogs(d)
+
+	_, err := d.SelectOrdered(context.Background(), "SELECT * FROM missing")
+	if code, ok := TaosCode(err); !ok || code != taosErr.Code {
+		t.Fatalf("got TDengine error code (%x, %v) of %v, want %x", code, ok, err, taosErr.Code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = d.SelectOrdered(ctx, "SELECT * FROM slow")
+	checkCode(t, err, gcode.CodeDbOperationError)
+	if !gstr.Contains(err.Error(), "query timed out") {
+		t.Fatalf("got error %v, want timed out", err)
+	}
+
+	if !gstr.Contains(logs.String(), "slow query") || !gstr.Contains(logs.String(), "sql=SELECT * FROM missing") {
+		t.Fatalf("got logs %q, want the slow query logged", logs.String())
+	}
+}
+
+func TestInterpConstantFill(t *testing.T) {
+	var (
+		start = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		end   = start.Add(2 * time.Second)
+	)
+	cases := []struct {
+		name    string
+		columns []string
+		values  []interface{}
+		want    string
+		code    gcode.Code
+	}{
+		{name: "zero float", columns: []string{"current"}, values: []interface{}{0}, want: "FILL(VALUE, 0)"},
+		{name: "numeric string", columns: []string{"current", "voltage"}, values: []interface{}{"1.5", "220"}, want: "FILL(VALUE, 1.5, 220)"},
+		{name: "truncated int", columns: []string{"voltage"}, values: []interface{}{220.7}, want: "FILL(VALUE, 220)"},
+		{name: "non-numeric", columns: []string{"voltage"}, values: []interface{}{"high"}, code: gcode.CodeInvalidParameter},
+		{name: "bool", columns: []string{"current"}, values: []interface{}{true}, code: gcode.CodeInvalidParameter},
+		{name: "value count", columns: []string{"current", "voltage"}, values: []interface{}{0}, code: gcode.CodeInvalidParameter},
+		{name: "unknown column", columns: []string{"power"}, values: []interface{}{0}, code: gcode.CodeNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(nil))
+			_, err := d.Interp(context.Background(), InterpInput{
+				Table: "d1001", Columns: c.columns, Start: start, End: end, Every: "1s", Fill: FillValue, Values: c.values,
+			})
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				return
+			}
+			sqls := server.Sqls()
+			if got := sqls[len(sqls)-1]; !gstr.HasSuffix(got, "EVERY(1s) "+c.want) {
+				t.Fatalf("got sql %q, want fill %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestLastRowPerPartition(t *testing.T) {
+	cases := []struct {
+		name string
+		in   LastRowInput
+		want string
+		code gcode.Code
+	}{
+		{
+			name: "all columns",
+			in:   LastRowInput{Stable: "meters"},
+			want: "SELECT tbname,LAST_ROW(*) FROM `meters` PARTITION BY tbname",
+		},
+		{
+			name: "columns with slimit",
+			in:   LastRowInput{Stable: "meters", Columns: []string{"current", "voltage"}, SLimit: 2},
+			want: "SELECT tbname,LAST_ROW(`current`) AS `current`,LAST_ROW(`voltage`) AS `voltage` FROM `meters` PARTITION BY tbname SLIMIT 2",
+		},
+		{
+			name: "tag partition",
+			in:   LastRowInput{Stable: "meters", Columns: []string{"current"}, PartitionBy: "location"},
+			want: "SELECT location,LAST_ROW(`current`) AS `current` FROM `meters` PARTITION BY location",
+		},
+		{name: "negative slimit", in: LastRowInput{Stable: "meters", SLimit: -1}, code: gcode.CodeInvalidParameter},
+		{name: "invalid partition", in: LastRowInput{Stable: "meters", PartitionBy: "tbname; DROP TABLE meters"}, code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+				return mockRecords(
+					[]string{"tbname", "current"},
+					[]interface{}{[]byte("d1001"), 10.5},
+					[]interface{}{[]byte("d1002"), 11.5},
+				)
+			})
+			result, err := d.LastRowPerPartition(context.Background(), c.in)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				return
+			}
+			if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != c.want {
+				t.Fatalf("got sqls %q, want %q", sqls, c.want)
+			}
+			tables := make(map[string]struct{})
+			for _, record := range result {
+				tables[record["tbname"].String()] = struct{}{}
+			}
+			if len(result) != 2 || len(tables) != 2 {
+				t.Fatalf("got records %v, want one record for each subtable", result)
+			}
+		})
+	}
+}
+
+func TestLatestValues(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter map[string]interface{}
+		fields []string
+		want   string
+		code   gcode.Code
+	}{
+		{name: "all subtables", want: "SELECT tbname,LAST_ROW(*) FROM `meters` PARTITION BY tbname"},
+		{
+			name:   "tag filter",
+			filter: map[string]interface{}{"location": "California.SanFrancisco", "groupid": 2},
+			fields: []string{"current", "voltage"},
+			want: "SELECT tbname,LAST_ROW(`current`) AS `current`,LAST_ROW(`voltage`) AS `voltage` FROM `meters` " +
+				"WHERE `groupid`=2 AND `location`='California.SanFrancisco' PARTITION BY tbname",
+		},
+		{
+			name:   "null tag",
+			filter: map[string]interface{}{"location": nil},
+			fields: []string{"current"},
+			want:   "SELECT tbname,LAST_ROW(`current`) AS `current` FROM `meters` WHERE `location` IS NULL PARTITION BY tbname",
+		},
+		{name: "invalid tag", filter: map[string]interface{}{"location='x' OR 1": 1}, code: gcode.CodeInvalidParameter},
+		{name: "invalid field", fields: []string{"current; DROP TABLE meters"}, code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+				return mockRecords(
+					[]string{"tbname", "current", "voltage"},
+					[]interface{}{[]byte("d1001"), 10.3, int64(219)},
+					[]interface{}{[]byte("d1002"), 12.6, int64(218)},
+				)
+			})
+			result, err := d.LatestValues(context.Background(), "meters", c.filter, c.fields...)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				if sqls := server.Sqls(); len(sqls) != 0 {
+					t.Fatalf("got sqls %q, want none on invalid input", sqls)
+				}
+				return
+			}
+			if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != c.want {
+				t.Fatalf("got sqls %q, want %q", sqls, c.want)
+			}
+			tables := make(map[string]struct{})
+			for _, record := range result {
+				tables[record["tbname"].String()] = struct{}{}
+			}
+			if len(result) != 2 || len(tables) != 2 {
+				t.Fatalf("got records %v, want one record for each device", result)
+			}
+		})
+	}
+}
+
+func TestTopKPerPartition(t *testing.T) {
+	cases := []struct {
+		name      string
+		partition string
+		orderBy   string
+		k         int
+		want      string
+		code      gcode.Code
+	}{
+		{
+			name:      "per subtable",
+			partition: "tbname",
+			orderBy:   "current DESC",
+			k:         2,
+			want:      "SELECT tbname, * FROM `meters` PARTITION BY tbname ORDER BY current DESC LIMIT 2",
+		},
+		{
+			name:      "per tag",
+			partition: "location",
+			orderBy:   "ts",
+			k:         1,
+			want:      "SELECT location, * FROM `meters` PARTITION BY location ORDER BY ts LIMIT 1",
+		},
+		{name: "zero k", partition: "tbname", orderBy: "current DESC", code: gcode.CodeInvalidParameter},
+		{name: "invalid partition", partition: "tbname; DROP TABLE meters", orderBy: "ts", k: 2, code: gcode.CodeInvalidParameter},
+		{name: "invalid order", partition: "tbname", orderBy: "ts)", k: 2, code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+				// The server returns the rows of each partition contiguously, limited to k per partition.
+				var rows [][]interface{}
+				for _, table := range []string{"d1001", "d1002"} {
+					for i := 0; i < c.k; i++ {
+						rows = append(rows, []interface{}{[]byte(table), 12.5 - float64(i)})
+					}
+				}
+				return mockRecords([]string{"tbname", "current"}, rows...)
+			})
+			result, err := d.TopKPerPartition(context.Background(), "meters", c.partition, c.orderBy, c.k)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				if sqls := server.Sqls(); len(sqls) != 0 {
+					t.Fatalf("got sqls %q, want none on invalid input", sqls)
+				}
+				return
+			}
+			if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != c.want {
+				t.Fatalf("got sqls %q, want %q", sqls, c.want)
+			}
+			counts := make(map[string]int)
+			for _, record := range result {
+				counts[record["tbname"].String()]++
+			}
+			if want := map[string]int{"d1001": c.k, "d1002": c.k}; !reflect.DeepEqual(counts, want) {
+				t.Fatalf("got rows per partition %v, want %v", counts, want)
+			}
+		})
+	}
+}
+
+func TestRequireRows(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	helpers := map[string]func(ctx context.Context, d *Driver) (gdb.Result, error){
+		"interp": func(ctx context.Context, d *Driver) (gdb.Result, error) {
+			return d.Interp(ctx, InterpInput{Table: "d1001", Columns: []string{"current"}, Start: start, End: start, Every: "1s"})
+		},
+		"top devices": func(ctx context.Context, d *Driver) (gdb.Result, error) {
+			return d.TopDevices(ctx, "meters", "location", "COUNT(*)", 3)
+		},
+		"last row per partition": func(ctx context.Context, d *Driver) (gdb.Result, error) {
+			return d.LastRowPerPartition(ctx, LastRowInput{Stable: "meters"})
+		},
+		"top k per partition": func(ctx context.Context, d *Driver) (gdb.Result, error) {
+			return d.TopKPerPartition(ctx, "meters", "tbname", "current DESC", 3)
+		},
+		"recent rows": func(ctx context.Context, d *Driver) (gdb.Result, error) {
+			return d.RecentRows(ctx, "d1001", 3)
+		},
+	}
+	for name, helper := range helpers {
+		t.Run(name, func(t *testing.T) {
+			d, _ := newMockDriver(t, Option{}, metersHandler(func(mockQuery) mockResponse {
+				return mockRecords([]string{"ts"})
+			}))
+			result, err := helper(context.Background(), d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result == nil || len(result) != 0 {
+				t.Fatalf("got result %#v, want empty but non-nil result", result)
+			}
+			result, err = helper(WithRequireRows(context.Background()), d)
+			checkCode(t, err, gcode.CodeNotFound)
+			if result != nil {
+				t.Fatalf("got result %#v, want nil on error", result)
+			}
+		})
+	}
+}
+
+func TestRecentRows(t *testing.T) {
+	latest := time.Date(2023, 1, 1, 0, 0, 10, 0, time.UTC)
+	cases := []struct {
+		name   string
+		n      int
+		fields []string
+		want   string
+		code   gcode.Code
+	}{
+		{name: "all columns", n: 3, want: "SELECT * FROM `d1001` ORDER BY `ts` DESC LIMIT 3"},
+		{
+			name:   "fields",
+			n:      3,
+			fields: []string{"ts", "current"},
+			want:   "SELECT `ts`,`current` FROM `d1001` ORDER BY `ts` DESC LIMIT 3",
+		},
+		{name: "zero count", n: 0, code: gcode.CodeInvalidParameter},
+		{name: "invalid field", n: 3, fields: []string{"current; DROP TABLE meters"}, code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(func(q mockQuery) mockResponse {
+				return mockRecords(
+					[]string{"ts", "current"},
+					[]interface{}{latest, 10.3},
+					[]interface{}{latest.Add(-time.Second), 10.2},
+					[]interface{}{latest.Add(-2 * time.Second), 10.1},
+				)
+			}))
+			result, err := d.RecentRows(context.Background(), "d1001", c.n, c.fields...)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				return
+			}
+			var sqls []string
+			for _, sql := range server.Sqls() {
+				if gstr.HasPrefix(sql, "SELECT") {
+					sqls = append(sqls, sql)
+				}
+			}
+			if len(sqls) != 1 || sqls[0] != c.want {
+				t.Fatalf("got sqls %q, want %q", sqls, c.want)
+			}
+			if len(result) != 3 {
+				t.Fatalf("got %d rows, want 3", len(result))
+			}
+			for i := 1; i < len(result); i++ {
+				if !result[i]["ts"].Time().Before(result[i-1]["ts"].Time()) {
+					t.Fatalf("got rows %v, want newest first", result)
+				}
+			}
+			if !result[0]["ts"].Time().Equal(latest) {
+				t.Fatalf("got first row at %v, want %v", result[0]["ts"], latest)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package taosql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// StreamTrigger is the trigger mode of a stream, which determines when the stream computes the windows.
+type StreamTrigger string
+
+const (
+	StreamTriggerAtOnce      StreamTrigger = "AT_ONCE"      // Compute the windows at once the data is written.
+	StreamTriggerWindowClose StreamTrigger = "WINDOW_CLOSE" // Compute the windows when they are closed.
+	StreamTriggerMaxDelay    StreamTrigger = "MAX_DELAY"    // Compute the windows when they are closed, or after the max delay.
+)
+
+// StreamOptions is the options for function CreateStream, the zero value of each attribute means
+// the default of the server.
+type StreamOptions struct {
+	IfNotExists   bool          // (Optional) Skip creating if the stream already exists.
+	Trigger       StreamTrigger // (Optional) Trigger mode of the stream.
+	MaxDelay      Interval      // (Optional) Max delay of StreamTriggerMaxDelay, which is required by and only by it.
+	Watermark     Interval      // (Optional) Watermark of the windows, that is how long the out-of-order data is waited for.
+	IgnoreExpired *bool         // (Optional) Whether the out-of-order data of the closed windows is ignored.
+}
+
+// CreateStream creates stream `name` of current schema, which computes `selectSQL` continuously and writes
+// the results into super table `intoTable`, like the downsampling of the raw data, by statement
+// `CREATE STREAM [IF NOT EXISTS] name [TRIGGER ...] [WATERMARK ...] [IGNORE EXPIRED ...] INTO intoTable AS selectSQL`.
+//
+// It returns an error of code gcode.CodeInvalidParameter if `selectSQL` is not a SELECT statement,
+// or any option is invalid, before sending the statement.
+func (d *Driver) CreateStream(ctx context.Context, name, intoTable, selectSQL string, opts StreamOptions) error {
+	ddl, err := d.formatCreateStream(name, intoTable, selectSQL, opts)
+	if err != nil {
+		return err
+	}
+	_, err = d.Exec(withoutClause(ctx), ddl)
+	return err
+}
+
+// DropStream drops stream `name` if it exists by statement `DROP STREAM IF EXISTS name`,
+// which stops the computation, but keeps the data already written into its target table.
+func (d *Driver) DropStream(ctx context.Context, name string) error {
+	if !gregex.IsMatchString(`^\w+$`, name) {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `invalid stream name "%s"`, name)
+	}
+	_, err := d.Exec(withoutClause(ctx), fmt.Sprintf(`DROP STREAM IF EXISTS %s`, d.QuoteWord(name)))
+	return err
+}
+
+// formatCreateStream validates and formats the CREATE STREAM statement, see CreateStream.
+func (d *Driver) formatCreateStream(name, intoTable, selectSQL string, opts StreamOptions) (string, error) {
+	if !gregex.IsMatchString(`^\w+$`, name) {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid stream name "%s"`, name)
+	}
+	if !gregex.IsMatchString(`^\w+(\.\w+)?$`, intoTable) {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid target table "%s" of stream "%s"`, intoTable, name)
+	}
+	selectSQL = gstr.Trim(selectSQL)
+	if selectSQL == "" {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `select statement is required for stream "%s"`, name)
+	}
+	if !gregex.IsMatchString(`^(?i)SELECT\s`, selectSQL) {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid select statement "%s" of stream "%s"`, selectSQL, name)
+	}
+	array := []string{`CREATE STREAM`}
+	if opts.IfNotExists {
+		array = append(array, `IF NOT EXISTS`)
+	}
+	array = append(array, d.QuoteWord(name))
+	switch opts.Trigger {
+	case "":
+	case StreamTriggerAtOnce, StreamTriggerWindowClose:
+		array = append(array, `TRIGGER `+string(opts.Trigger))
+	case StreamTriggerMaxDelay:
+		if err := checkDuration(opts.MaxDelay.String()); err != nil {
+			return "", gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid max delay of stream "%s"`, name)
+		}
+		array = append(array, fmt.Sprintf(`TRIGGER %s %s`, opts.Trigger, opts.MaxDelay))
+	default:
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid trigger "%s" of stream "%s"`, opts.Trigger, name)
+	}
+	if opts.MaxDelay != "" && opts.Trigger != StreamTriggerMaxDelay {
+		return "", gerror.NewCodef(
+			gcode.CodeInvalidParameter, `max delay of stream "%s" requires trigger %s`, name, StreamTriggerMaxDelay,
+		)
+	}
+	if opts.Watermark != "" {
+		if err := checkDuration(opts.Watermark.String()); err != nil {
+			return "", gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid watermark of stream "%s"`, name)
+		}
+		array = append(array, `WATERMARK `+opts.Watermark.String())
+	}
+	if opts.IgnoreExpired != nil {
+		if *opts.IgnoreExpired {
+			array = append(array, `IGNORE EXPIRED 1`)
+		} else {
+			array = append(array, `IGNORE EXPIRED 0`)
+		}
+	}
+	array = append(array, `INTO `+d.QuotePrefixTableName(intoTable), `AS `+selectSQL)
+	return gstr.Join(array, " "), nil
+}
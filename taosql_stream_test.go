@@ -0,0 +1,99 @@
+package taosql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+func TestFormatCreateStream(t *testing.T) {
+	var (
+		yes       = true
+		no        = false
+		selectSQL = "SELECT _wstart, AVG(current) FROM meters INTERVAL(1m)"
+	)
+	cases := []struct {
+		name      string
+		stream    string
+		into      string
+		selectSQL string
+		opts      StreamOptions
+		want      string
+		code      gcode.Code
+	}{
+		{
+			name:   "no options",
+			stream: "avg_current", into: "avg_meters", selectSQL: selectSQL,
+			want: "CREATE STREAM `avg_current` INTO `avg_meters` AS " + selectSQL,
+		},
+		{
+			name:   "all options",
+			stream: "avg_current", into: "power.avg_meters", selectSQL: "  " + selectSQL + "\n",
+			opts: StreamOptions{IfNotExists: true, Trigger: StreamTriggerWindowClose, Watermark: "10s", IgnoreExpired: &yes},
+			want: "CREATE STREAM IF NOT EXISTS `avg_current` TRIGGER WINDOW_CLOSE WATERMARK 10s IGNORE EXPIRED 1 " +
+				"INTO `power`.`avg_meters` AS " + selectSQL,
+		},
+		{
+			name:   "at once",
+			stream: "avg_current", into: "avg_meters", selectSQL: selectSQL,
+			opts: StreamOptions{Trigger: StreamTriggerAtOnce, IgnoreExpired: &no},
+			want: "CREATE STREAM `avg_current` TRIGGER AT_ONCE IGNORE EXPIRED 0 INTO `avg_meters` AS " + selectSQL,
+		},
+		{
+			name:   "max delay",
+			stream: "avg_current", into: "avg_meters", selectSQL: selectSQL,
+			opts: StreamOptions{Trigger: StreamTriggerMaxDelay, MaxDelay: "5s"},
+			want: "CREATE STREAM `avg_current` TRIGGER MAX_DELAY 5s INTO `avg_meters` AS " + selectSQL,
+		},
+		{name: "empty select", stream: "s", into: "t", selectSQL: " ", code: gcode.CodeInvalidParameter},
+		{name: "not select", stream: "s", into: "t", selectSQL: "DROP TABLE meters", code: gcode.CodeInvalidParameter},
+		{name: "invalid name", stream: "s-1", into: "t", selectSQL: selectSQL, code: gcode.CodeInvalidParameter},
+		{name: "invalid target", stream: "s", into: "t; DROP", selectSQL: selectSQL, code: gcode.CodeInvalidParameter},
+		{name: "invalid trigger", stream: "s", into: "t", selectSQL: selectSQL, opts: StreamOptions{Trigger: "NEVER"}, code: gcode.CodeInvalidParameter},
+		{
+			name:   "max delay without trigger",
+			stream: "s", into: "t", selectSQL: selectSQL,
+			opts: StreamOptions{MaxDelay: "5s"},
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name:   "missing max delay",
+			stream: "s", into: "t", selectSQL: selectSQL,
+			opts: StreamOptions{Trigger: StreamTriggerMaxDelay},
+			code: gcode.CodeInvalidParameter,
+		},
+		{name: "invalid watermark", stream: "s", into: "t", selectSQL: selectSQL, opts: StreamOptions{Watermark: "soon"}, code: gcode.CodeInvalidParameter},
+	}
+	d, _ := newMockDriver(t, Option{}, nil)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := d.formatCreateStream(c.stream, c.into, c.selectSQL, c.opts)
+			checkCode(t, err, c.code)
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCreateDropStream(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, nil)
+	ctx := context.Background()
+	if err := d.CreateStream(ctx, "avg_current", "avg_meters", "SELECT _wstart, AVG(current) FROM meters INTERVAL(1m)", StreamOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	checkCode(t, d.CreateStream(ctx, "avg_current", "avg_meters", "", StreamOptions{}), gcode.CodeInvalidParameter)
+	if err := d.DropStream(ctx, "avg_current"); err != nil {
+		t.Fatal(err)
+	}
+	checkCode(t, d.DropStream(ctx, "avg_current; DROP TABLE meters"), gcode.CodeInvalidParameter)
+	want := []string{
+		"CREATE STREAM `avg_current` INTO `avg_meters` AS SELECT _wstart, AVG(current) FROM meters INTERVAL(1m)",
+		"DROP STREAM IF EXISTS `avg_current`",
+	}
+	if sqls := server.Sqls(); !reflect.DeepEqual(sqls, want) {
+		t.Fatalf("got sqls %q, want %q", sqls, want)
+	}
+}
@@ -0,0 +1,101 @@
+package taosql
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// SubscriptionInfo is the information of a subscription of a consumer group to a topic on a vgroup,
+// which is retrieved by `SHOW SUBSCRIPTIONS`.
+//
+// It has no lag of the consumer group, as the server reports only the committed offset of the subscription,
+// but not the latest offset of the WAL of the vgroup, which is only returned to the consumers by the topic
+// assignment of the TMQ API. The lag is computed by function Lag with the latest offset of the assignment.
+type SubscriptionInfo struct {
+	Topic         string     // Name of the subscribed topic.
+	ConsumerGroup string     // Name of the consumer group.
+	VgroupId      int        // Id of the vgroup of the subscription.
+	ConsumerId    string     // Id of the consumer assigned to the vgroup, which is empty if not assigned.
+	Offset        string     // Committed offset of the consumer group on the vgroup, like: log:123.
+	Rows          int64      // Rows consumed by the consumer group on the vgroup.
+	Raw           gdb.Record // Raw record of the subscription, for the columns that are not parsed or of different server versions.
+}
+
+// Lag returns the lag of the consumer group on the vgroup, which is the count of the WAL entries from Offset to
+// `endOffset`, the latest offset of the vgroup returned by the topic assignment of the TMQ API. It returns false
+// if Offset is not a WAL offset, like the ones of the snapshot consumption or the groups that never commit.
+func (s SubscriptionInfo) Lag(endOffset int64) (int64, bool) {
+	match, _ := gregex.MatchString(`^log:(-?\d+)$`, gstr.Trim(s.Offset))
+	if len(match) == 0 {
+		return 0, false
+	}
+	lag := endOffset - gconv.Int64(match[1])
+	if lag < 0 {
+		lag = 0
+	}
+	return lag, true
+}
+
+// ConsumerInfo is the information of a consumer, which is retrieved by `SHOW CONSUMERS`.
+type ConsumerInfo struct {
+	ConsumerId    string      // Id of the consumer.
+	ConsumerGroup string      // Name of the consumer group.
+	ClientId      string      // Client id configured by the consumer.
+	Status        string      // Status of the consumer, like: ready, lost, rebalancing.
+	Topics        []string    // Names of the subscribed topics.
+	UpTime        *gtime.Time // Time the consumer connected.
+	SubscribeTime *gtime.Time // Time the consumer subscribed the topics last.
+	RebalanceTime *gtime.Time // Time the vgroups were rebalanced last.
+	Raw           gdb.Record  // Raw record of the consumer, for the columns that are not parsed or of different server versions.
+}
+
+// ShowSubscriptions retrieves and returns the subscriptions of the consumer groups, by `SHOW SUBSCRIPTIONS`,
+// which can be used for monitoring the consumption progress of each vgroup. The Offset and Rows are only
+// reported by the servers of version 3.0.4.0 or later.
+func (d *Driver) ShowSubscriptions(ctx context.Context) ([]SubscriptionInfo, error) {
+	result, err := d.GetAll(withoutClause(ctx), `SHOW SUBSCRIPTIONS`)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions := make([]SubscriptionInfo, 0, len(result))
+	for _, record := range result {
+		subscriptions = append(subscriptions, SubscriptionInfo{
+			Topic:         record["topic_name"].String(),
+			ConsumerGroup: record["consumer_group"].String(),
+			VgroupId:      record["vgroup_id"].Int(),
+			ConsumerId:    record["consumer_id"].String(),
+			Offset:        record["offset"].String(),
+			Rows:          record["rows"].Int64(),
+			Raw:           record,
+		})
+	}
+	return subscriptions, nil
+}
+
+// ShowConsumers retrieves and returns the consumers connected to the cluster, by `SHOW CONSUMERS`.
+func (d *Driver) ShowConsumers(ctx context.Context) ([]ConsumerInfo, error) {
+	result, err := d.GetAll(withoutClause(ctx), `SHOW CONSUMERS`)
+	if err != nil {
+		return nil, err
+	}
+	consumers := make([]ConsumerInfo, 0, len(result))
+	for _, record := range result {
+		consumers = append(consumers, ConsumerInfo{
+			ConsumerId:    record["consumer_id"].String(),
+			ConsumerGroup: record["consumer_group"].String(),
+			ClientId:      record["client_id"].String(),
+			Status:        record["status"].String(),
+			Topics:        gstr.SplitAndTrim(record["topics"].String(), ","),
+			UpTime:        record["up_time"].GTime(),
+			SubscribeTime: record["subscribe_time"].GTime(),
+			RebalanceTime: record["rebalance_time"].GTime(),
+			Raw:           record,
+		})
+	}
+	return consumers, nil
+}
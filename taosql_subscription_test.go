@@ -0,0 +1,95 @@
+package taosql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestShowSubscriptions(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse {
+		return mockRecords(
+			[]string{"topic_name", "consumer_group", "vgroup_id", "consumer_id", "offset", "rows"},
+			[]interface{}{"meters_topic", "group1", int32(2), "0x5a1b2c3d4e5f", "log:120", int64(1000)},
+			[]interface{}{"meters_topic", "group2", int32(3), nil, "earliest", int64(0)},
+		)
+	})
+	subscriptions, err := d.ShowSubscriptions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != "SHOW SUBSCRIPTIONS" {
+		t.Fatalf("got sqls %q", sqls)
+	}
+	want := []SubscriptionInfo{
+		{Topic: "meters_topic", ConsumerGroup: "group1", VgroupId: 2, ConsumerId: "0x5a1b2c3d4e5f", Offset: "log:120", Rows: 1000},
+		{Topic: "meters_topic", ConsumerGroup: "group2", VgroupId: 3, Offset: "earliest"},
+	}
+	if len(subscriptions) != len(want) {
+		t.Fatalf("got %d subscriptions, want %d", len(subscriptions), len(want))
+	}
+	for i, subscription := range subscriptions {
+		if subscription.Raw["vgroup_id"].Int() != want[i].VgroupId {
+			t.Fatalf("got raw record %v of subscription %d", subscription.Raw, i)
+		}
+		subscription.Raw = nil
+		if !reflect.DeepEqual(subscription, want[i]) {
+			t.Fatalf("got subscription %+v, want %+v", subscription, want[i])
+		}
+	}
+}
+
+func TestSubscriptionLag(t *testing.T) {
+	cases := []struct {
+		name      string
+		offset    string
+		endOffset int64
+		want      int64
+		ok        bool
+	}{
+		{name: "behind", offset: "log:120", endOffset: 150, want: 30, ok: true},
+		{name: "caught up", offset: "log:150", endOffset: 150, want: 0, ok: true},
+		{name: "stale end offset", offset: "log:160", endOffset: 150, want: 0, ok: true},
+		{name: "earliest", offset: "earliest", endOffset: 150},
+		{name: "snapshot", offset: "snapshot:1001,1672531200000", endOffset: 150},
+		{name: "empty", offset: "", endOffset: 150},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lag, ok := SubscriptionInfo{Offset: c.offset}.Lag(c.endOffset)
+			if lag != c.want || ok != c.ok {
+				t.Fatalf("got (%d, %t), want (%d, %t)", lag, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestShowConsumers(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, func(mockQuery) mockResponse {
+		return mockRecords(
+			[]string{"consumer_id", "consumer_group", "client_id", "status", "topics", "up_time", "subscribe_time", "rebalance_time"},
+			[]interface{}{
+				"0x5a1b2c3d4e5f", "group1", "client1", "ready", "meters_topic, logs_topic",
+				"2023-01-01 00:00:00", "2023-01-01 00:00:01", "2023-01-01 00:00:02",
+			},
+		)
+	})
+	consumers, err := d.ShowConsumers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consumers) != 1 {
+		t.Fatalf("got %d consumers, want 1", len(consumers))
+	}
+	consumer := consumers[0]
+	if consumer.ConsumerId != "0x5a1b2c3d4e5f" || consumer.ConsumerGroup != "group1" || consumer.ClientId != "client1" ||
+		consumer.Status != "ready" {
+		t.Fatalf("got consumer %+v", consumer)
+	}
+	if want := []string{"meters_topic", "logs_topic"}; !reflect.DeepEqual(consumer.Topics, want) {
+		t.Fatalf("got topics %q, want %q", consumer.Topics, want)
+	}
+	if consumer.UpTime.String() != "2023-01-01 00:00:00" || consumer.RebalanceTime.String() != "2023-01-01 00:00:02" {
+		t.Fatalf("got times %v and %v", consumer.UpTime, consumer.RebalanceTime)
+	}
+}
@@ -0,0 +1,395 @@
+package taosql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// TableKind is the kind of TDengine table.
+type TableKind int
+
+const (
+	TableKindUnknown TableKind = iota // Unknown table kind, returned along with an error.
+	TableKindSuper                    // Super table(STABLE), which defines the schema and tags of its child tables.
+	TableKindChild                    // Child table(subtable), which is created from a super table with tag values.
+	TableKindNormal                   // Normal table, which is neither a super table nor a child table.
+)
+
+// String returns the name of the table kind.
+func (k TableKind) String() string {
+	switch k {
+	case TableKindSuper:
+		return "SUPER_TABLE"
+	case TableKindChild:
+		return "CHILD_TABLE"
+	case TableKindNormal:
+		return "NORMAL_TABLE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// TableType retrieves and returns whether the specified table of current schema is a super,
+// child or normal table, which is sourced from system tables `information_schema.ins_stables`
+// and `information_schema.ins_tables`.
+//
+// The result is cached along with the table fields.
+func (d *Driver) TableType(ctx context.Context, table string, schema ...string) (kind TableKind, err error) {
+	charL, charR := d.GetChars()
+	table = gstr.Trim(table, charL+charR)
+	useSchema := d.GetSchema()
+	if len(schema) > 0 && schema[0] != "" {
+		useSchema = schema[0]
+	}
+	ctx = withoutDryRun(withoutClause(ctx))
+	v := tableFieldsMap.GetOrSetFuncLock(
+		d.tableKey("table_type", table, useSchema),
+		func() interface{} {
+			var (
+				result gdb.Result
+				link   gdb.Link
+			)
+			if link, err = d.SlaveLink(useSchema); err != nil {
+				return nil
+			}
+			result, err = d.DoSelect(ctx, link, fmt.Sprintf(
+				`SELECT stable_name FROM information_schema.ins_stables WHERE db_name=%s AND stable_name=%s`,
+				quoteString(useSchema), quoteString(table),
+			))
+			if err != nil {
+				return nil
+			}
+			if len(result) > 0 {
+				return TableKindSuper
+			}
+			result, err = d.DoSelect(ctx, link, fmt.Sprintf(
+				`SELECT type FROM information_schema.ins_tables WHERE db_name=%s AND table_name=%s`,
+				quoteString(useSchema), quoteString(table),
+			))
+			if err != nil {
+				return nil
+			}
+			if len(result) == 0 {
+				err = gerror.NewCodef(gcode.CodeNotFound, `table "%s" not found in schema "%s"`, table, useSchema)
+				return nil
+			}
+			if gstr.Equal(result[0]["type"].String(), TableKindChild.String()) {
+				return TableKindChild
+			}
+			return TableKindNormal
+		},
+	)
+	if v != nil {
+		kind = v.(TableKind)
+	}
+	return
+}
+
+// TableTagValues retrieves and returns the tag values of child table `subtable` keyed by tag name,
+// which is sourced from `SHOW TAGS FROM subtable`.
+//
+// The tag values are returned as strings by the server, which are converted by their tag types:
+// integer types to int64/uint64, FLOAT/DOUBLE to float64, BOOL to bool, JSON to map[string]interface{},
+// and the others like BINARY, NCHAR and TIMESTAMP are left as strings. NULL tag values are nil.
+func (d *Driver) TableTagValues(ctx context.Context, subtable string) (map[string]interface{}, error) {
+	result, err := d.GetAll(withoutClause(ctx), fmt.Sprintf(`SHOW TAGS FROM %s`, d.QuotePrefixTableName(subtable)))
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, gerror.NewCodef(gcode.CodeNotFound, `no tags found for table "%s"`, subtable)
+	}
+	tags := make(map[string]interface{}, len(result))
+	for _, record := range result {
+		name := record["tag_name"].String()
+		if tags[name], err = parseTagValue(record["tag_type"].String(), record["tag_value"]); err != nil {
+			return nil, gerror.WrapCodef(gcode.CodeInternalError, err, `parse value of tag "%s" failed`, name)
+		}
+	}
+	return tags, nil
+}
+
+// parseTagValue converts tag value `value` in string to the Go value of tag type `tagType`.
+func parseTagValue(tagType string, value *gvar.Var) (interface{}, error) {
+	if value.IsNil() {
+		return nil, nil
+	}
+	switch columnTypeName(tagType) {
+	case "tinyint", "smallint", "int", "bigint":
+		return value.Int64(), nil
+	case "tinyint unsigned", "smallint unsigned", "int unsigned", "bigint unsigned":
+		return value.Uint64(), nil
+	case "float", "double":
+		return value.Float64(), nil
+	case "bool":
+		return value.Bool(), nil
+	case "json":
+		if value.String() == "" || gstr.Equal(value.String(), "null") {
+			return nil, nil
+		}
+		var m map[string]interface{}
+		if err := gjson.DecodeTo(value.Bytes(), &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	default:
+		return value.String(), nil
+	}
+}
+
+// TagKey returns the `CONCAT_WS(separator, tag1, tag2, ...) AS alias` projection, which concatenates the tags
+// of super table `stable` to a composite key, like the device key `location/group_id`, eg:
+//
+// key, err := db.TagKey(ctx, "meters", "device_key", "/", "location", "group_id")
+// db.Model("meters").Fields(key, "AVG(current)").Group("device_key").All()
+//
+// The tags are validated by the table fields of `stable`, and it returns an error of code
+// gcode.CodeInvalidParameter for the columns that are not tags. As CONCAT_WS accepts only strings of the same
+// type, the tags that are not BINARY or NCHAR are cast to VARCHAR, or all the tags that are not NCHAR are cast
+// to NCHAR if there's any NCHAR tag.
+func (d *Driver) TagKey(ctx context.Context, stable, alias, separator string, tags ...string) (string, error) {
+	if len(tags) == 0 {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, `at least one tag is required for tag key`)
+	}
+	if !gregex.IsMatchString(`^\w+$`, alias) {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid alias "%s" for tag key`, alias)
+	}
+	fields, err := d.TableFields(ctx, stable)
+	if err != nil {
+		return "", err
+	}
+	var (
+		types    = make([]string, len(tags))
+		castType = "VARCHAR(64)"
+	)
+	for i, tag := range tags {
+		field, ok := fields[tag]
+		if !ok {
+			return "", gerror.NewCodef(gcode.CodeNotFound, `tag "%s" not found in table "%s"`, tag, stable)
+		}
+		if field.Extra != fieldExtraTag {
+			return "", gerror.NewCodef(gcode.CodeInvalidParameter, `column "%s" of table "%s" is not a tag`, tag, stable)
+		}
+		if types[i] = columnTypeName(field.Type); types[i] == "nchar" {
+			castType = "NCHAR(64)"
+		}
+	}
+	args := []string{quoteString(separator)}
+	for i, tag := range tags {
+		switch {
+		case types[i] == "nchar",
+			castType != "NCHAR(64)" && (types[i] == "binary" || types[i] == "varchar"):
+			args = append(args, d.QuoteWord(tag))
+		default:
+			args = append(args, fmt.Sprintf(`CAST(%s AS %s)`, d.QuoteWord(tag), castType))
+		}
+	}
+	key, err := buildFunc("CONCAT_WS", args...)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`%s AS %s`, key, alias), nil
+}
+
+// SanitizeTableName transforms `name` into a valid TDengine table name, which is deterministic and reversible
+// by UnsanitizeTableName, like for the subtable names derived from the device ids with dots, spaces or unicode.
+//
+// The scheme is: the ASCII lower case letters and digits are kept, except that the leading digit is escaped,
+// as a table name cannot start with a digit; the underscore is escaped as `__`, and any other byte is escaped
+// as `_` followed by its 2 lower case hex digits, like: "dev.01" to "dev_2e01", "3f" to "_33f", "Dev" to "_44ev".
+// The upper case letters are escaped as the table names are case-insensitive and folded to lower case by the
+// server, so that the names listed by the server are reverted to the original names.
+//
+// See Option.SanitizeSubtableNames for sanitizing the subtable names of InsertAutoCreate and InsertUsing.
+func SanitizeTableName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_':
+			b.WriteString("__")
+		case (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9' && i > 0):
+			b.WriteByte(c)
+		default:
+			b.WriteString(fmt.Sprintf(`_%02x`, c))
+		}
+	}
+	return b.String()
+}
+
+// UnsanitizeTableName reverts the table name `name` transformed by SanitizeTableName to the original name.
+func UnsanitizeTableName(name string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '_' {
+			b.WriteByte(name[i])
+			continue
+		}
+		if i+1 < len(name) && name[i+1] == '_' {
+			b.WriteByte('_')
+			i++
+			continue
+		}
+		if i+2 >= len(name) {
+			return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid sanitized table name "%s"`, name)
+		}
+		c, err := strconv.ParseUint(name[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid sanitized table name "%s"`, name)
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// StableStats is the statistics of a super table.
+type StableStats struct {
+	Stable       string      // Super table name.
+	ChildTables  int64       // Number of the child tables.
+	Rows         int64       // Total number of rows of all child tables.
+	LastDataTime *gtime.Time // Timestamp of the latest row, which is nil if there's no rows.
+}
+
+// StableStats retrieves and returns the child table count, the total row count and the latest row timestamp
+// of super table `stable` of current schema, in two queries: the child table count from system table
+// `information_schema.ins_tables`, and the others by `SELECT COUNT(*), LAST(_rowts) FROM stable`.
+// It returns an error of code gcode.CodeMissingParameter if there's no current schema, as the child tables are
+// counted by the database name.
+func (d *Driver) StableStats(ctx context.Context, stable string) (*StableStats, error) {
+	schema := d.GetSchema()
+	if schema == "" {
+		return nil, gerror.NewCode(gcode.CodeMissingParameter, `current schema is required for super table statistics`)
+	}
+	charL, charR := d.GetChars()
+	stable = gstr.Trim(stable, charL+charR)
+	ctx = withoutClause(ctx)
+	tables, err := d.GetValue(ctx, fmt.Sprintf(
+		`SELECT COUNT(*) FROM information_schema.ins_tables WHERE db_name=%s AND stable_name=%s`,
+		quoteString(schema), quoteString(stable),
+	))
+	if err != nil {
+		return nil, err
+	}
+	record, err := d.GetOne(ctx, fmt.Sprintf(
+		`SELECT COUNT(*) AS total_rows, LAST(_rowts) AS last_ts FROM %s`, d.QuotePrefixTableName(stable),
+	))
+	if err != nil {
+		return nil, err
+	}
+	stats := &StableStats{
+		Stable:      stable,
+		ChildTables: tables.Int64(),
+	}
+	if record != nil {
+		stats.Rows = record["total_rows"].Int64()
+		if !record["last_ts"].IsNil() {
+			stats.LastDataTime = record["last_ts"].GTime()
+		}
+	}
+	return stats, nil
+}
+
+// VgroupTables retrieves and returns the names of the child tables of super table `stable` of current schema
+// that are stored in vgroup `vgroupId`, which is sourced from system table `information_schema.ins_tables`.
+//
+// TDengine has no hint or connection property pinning a query to a vgroup, but a query on the returned
+// subtables only scans the given vgroup, which helps investigating the data skew, eg:
+//
+// tables, err := db.(*taosql.Driver).VgroupTables(ctx, "meters", 2)
+// where, err := taosql.WhereTableName(tables...)
+// db.Model("meters").Where(where).Count()
+//
+// It returns an empty slice if the vgroup has no child tables of `stable`.
+func (d *Driver) VgroupTables(ctx context.Context, stable string, vgroupId int) ([]string, error) {
+	charL, charR := d.GetChars()
+	stable = gstr.Trim(stable, charL+charR)
+	array, err := d.GetArray(withoutClause(ctx), fmt.Sprintf(
+		`SELECT table_name FROM information_schema.ins_tables WHERE db_name=%s AND stable_name=%s AND vgroup_id=%d`,
+		quoteString(d.GetSchema()), quoteString(stable), vgroupId,
+	))
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]string, len(array))
+	for i, v := range array {
+		tables[i] = v.String()
+	}
+	return tables, nil
+}
+
+// CreateStable creates super table `name` of current schema with `columns` and `tags` by statement
+// `CREATE STABLE [IF NOT EXISTS] name (col type, ...) TAGS (tag type, ...)`, in which the Name and Type of
+// the fields are used, like: {Name: "ts", Type: "TIMESTAMP"}, {Name: "location", Type: "NCHAR(64)"}.
+//
+// The first column should be of type TIMESTAMP, which is the primary timestamp, at least one tag is required,
+// and the string types BINARY, VARCHAR and NCHAR should be declared with length. It returns an error of code
+// gcode.CodeInvalidParameter if not, before sending the statement.
+func (d *Driver) CreateStable(ctx context.Context, name string, columns []gdb.TableField, tags []gdb.TableField, ifNotExists bool) error {
+	if len(columns) == 0 || !gstr.Equal(gstr.Trim(columns[0].Type), "TIMESTAMP") {
+		return gerror.NewCodef(
+			gcode.CodeInvalidParameter, `the first column of super table "%s" should be of type TIMESTAMP`, name,
+		)
+	}
+	if len(tags) == 0 {
+		return gerror.NewCodef(gcode.CodeInvalidParameter, `at least one tag is required for super table "%s"`, name)
+	}
+	columnDefs, err := d.formatFieldDefs(columns, false)
+	if err != nil {
+		return err
+	}
+	tagDefs, err := d.formatFieldDefs(tags, true)
+	if err != nil {
+		return err
+	}
+	ddl := `CREATE STABLE `
+	if ifNotExists {
+		ddl += `IF NOT EXISTS `
+	}
+	_, err = d.Exec(withoutClause(ctx), fmt.Sprintf(
+		`%s%s (%s) TAGS (%s)`, ddl, d.QuotePrefixTableName(name), columnDefs, tagDefs,
+	))
+	return err
+}
+
+// IsJSONTag checks whether `field` of TableFields is the JSON tag of a super table, which is assigned
+// JSON strings, or maps and slices that are encoded by ConvertDataForRecord.
+func IsJSONTag(field *gdb.TableField) bool {
+	return field != nil && field.Extra == fieldExtraTag && gstr.Equal(field.Type, fieldTypeJSON)
+}
+
+// formatFieldDefs validates and formats the `name type` definitions of `fields`, in which the JSON type is
+// allowed only for tags, that is `isTag` is true.
+func (d *Driver) formatFieldDefs(fields []gdb.TableField, isTag bool) (string, error) {
+	defs := make([]string, len(fields))
+	for i, field := range fields {
+		if !gregex.IsMatchString(`^\w+$`, field.Name) {
+			return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid field name "%s"`, field.Name)
+		}
+		typ := gstr.ToUpper(gstr.Trim(field.Type))
+		if match, _ := gregex.MatchString(stringTypePattern, typ); len(match) > 0 {
+			if gconv.Int(match[2]) <= 0 {
+				return "", gerror.NewCodef(
+					gcode.CodeInvalidParameter, `invalid length of type "%s" of field "%s"`, field.Type, field.Name,
+				)
+			}
+			typ = fmt.Sprintf(`%s(%s)`, match[1], match[2])
+		} else if !gregex.IsMatchString(fixedTypePattern, typ) && !(isTag && typ == fieldTypeJSON) {
+			return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid type "%s" of field "%s"`, field.Type, field.Name)
+		}
+		defs[i] = d.QuoteWord(field.Name) + " " + typ
+	}
+	return gstr.Join(defs, ", "), nil
+}
@@ -0,0 +1,469 @@
+package taosql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// tableTypeHandler responds the system tables with super table "meters", child table "d1001"
+// and normal table "logs".
+func tableTypeHandler(q mockQuery) mockResponse {
+	switch {
+	case gstr.Contains(q.Sql, "ins_stables"):
+		if gstr.Contains(q.Sql, "stable_name='meters'") {
+			return mockRecords([]string{"stable_name"}, []interface{}{"meters"})
+		}
+		return mockRecords([]string{"stable_name"})
+	case gstr.Contains(q.Sql, "table_name='d1001'"):
+		return mockRecords([]string{"type"}, []interface{}{"CHILD_TABLE"})
+	case gstr.Contains(q.Sql, "table_name='logs'"):
+		return mockRecords([]string{"type"}, []interface{}{"NORMAL_TABLE"})
+	default:
+		return mockRecords([]string{"type"})
+	}
+}
+
+func TestTableType(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, tableTypeHandler)
+	cases := []struct {
+		table string
+		want  TableKind
+		code  gcode.Code
+	}{
+		{table: "meters", want: TableKindSuper},
+		{table: "d1001", want: TableKindChild},
+		{table: "`logs`", want: TableKindNormal},
+		{table: "missing", want: TableKindUnknown, code: gcode.CodeNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.table, func(t *testing.T) {
+			kind, err := d.TableType(context.Background(), c.table)
+			checkCode(t, err, c.code)
+			if kind != c.want {
+				t.Fatalf("got %s, want %s", kind, c.want)
+			}
+		})
+	}
+}
+
+func TestTableTypeCache(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, tableTypeHandler)
+	ctx := context.Background()
+	if _, err := d.TableType(ctx, "meters"); err != nil {
+		t.Fatal(err)
+	}
+	// The quoted name shares the cached type of the unquoted name.
+	if _, err := d.TableType(ctx, "`meters`"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.Queries()); n != 1 {
+		t.Fatalf("got %d queries, want 1 for the cached type", n)
+	}
+	d.ClearTableFields("`meters`")
+	if _, err := d.TableType(ctx, "meters"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.Queries()); n != 2 {
+		t.Fatalf("got %d queries, want 2 after the cache is cleared", n)
+	}
+}
+
+func TestTableTagValues(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+		if q.Sql != "SHOW TAGS FROM `d1001`" {
+			return mockRecords([]string{"tag_name", "tag_type", "tag_value"})
+		}
+		return mockRecords(
+			[]string{"table_name", "db_name", "stable_name", "tag_name", "tag_type", "tag_value"},
+			[]interface{}{"d1001", "power", "meters", "location", "VARCHAR(64)", "California.SanFrancisco"},
+			[]interface{}{"d1001", "power", "meters", "groupid", "INT", "2"},
+			[]interface{}{"d1001", "power", "meters", "serial", "BIGINT UNSIGNED", "18446744073709551615"},
+			[]interface{}{"d1001", "power", "meters", "ratio", "DOUBLE", "0.5"},
+			[]interface{}{"d1001", "power", "meters", "online", "BOOL", "true"},
+			[]interface{}{"d1001", "power", "meters", "info", "JSON", `{"model":"x1","floor":3}`},
+			[]interface{}{"d1001", "power", "meters", "empty", "JSON", "null"},
+			[]interface{}{"d1001", "power", "meters", "owner", "NCHAR(16)", nil},
+		)
+	})
+	tags, err := d.TableTagValues(context.Background(), "d1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"location": "California.SanFrancisco",
+		"groupid":  int64(2),
+		"serial":   uint64(18446744073709551615),
+		"ratio":    0.5,
+		"online":   true,
+		"info":     map[string]interface{}{"model": "x1", "floor": float64(3)},
+		"empty":    nil,
+		"owner":    nil,
+	}
+	if !reflect.DeepEqual(tags, want) {
+		t.Fatalf("got %#v, want %#v", tags, want)
+	}
+	_, err = d.TableTagValues(context.Background(), "missing")
+	checkCode(t, err, gcode.CodeNotFound)
+	if n := len(server.Queries()); n != 2 {
+		t.Fatalf("got %d queries, want 2", n)
+	}
+}
+
+func TestParseTagValue(t *testing.T) {
+	if _, err := parseTagValue("JSON", gvar.New("{invalid")); err == nil {
+		t.Fatal("got nil error for the invalid JSON tag value")
+	}
+}
+
+func TestIsJSONTag(t *testing.T) {
+	cases := []struct {
+		name  string
+		field *gdb.TableField
+		want  bool
+	}{
+		{name: "json tag", field: &gdb.TableField{Name: "info", Type: fieldTypeJSON, Extra: fieldExtraTag}, want: true},
+		{name: "lower case", field: &gdb.TableField{Name: "info", Type: "json", Extra: fieldExtraTag}, want: true},
+		{name: "other tag", field: &gdb.TableField{Name: "location", Type: "VARCHAR(64)", Extra: fieldExtraTag}},
+		{name: "column", field: &gdb.TableField{Name: "info", Type: fieldTypeJSON}},
+		{name: "nil", field: nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := IsJSONTag(c.field); got != c.want {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestTagKey(t *testing.T) {
+	desc := func(q mockQuery) mockResponse {
+		switch {
+		case gstr.HasPrefix(q.Sql, "desc "):
+			return mockRecords(
+				[]string{"field", "type", "length", "note"},
+				[]interface{}{"ts", "TIMESTAMP", int64(8), ""},
+				[]interface{}{"current", "FLOAT", int64(4), ""},
+				[]interface{}{"location", "NCHAR", int64(64), "TAG"},
+				[]interface{}{"model", "VARCHAR", int64(32), "TAG"},
+				[]interface{}{"groupid", "INT", int64(4), "TAG"},
+			)
+		case gstr.HasPrefix(q.Sql, "SELECT"):
+			return mockRecords([]string{"device_key"}, []interface{}{[]byte("beijing/2")})
+		}
+		return mockResponse{}
+	}
+	cases := []struct {
+		name  string
+		alias string
+		tags  []string
+		want  string
+		code  gcode.Code
+	}{
+		{
+			name:  "varchar",
+			alias: "device_key",
+			tags:  []string{"model", "groupid"},
+			want:  "CONCAT_WS('/', `model`, CAST(`groupid` AS VARCHAR(64))) AS device_key",
+		},
+		{
+			name:  "nchar",
+			alias: "device_key",
+			tags:  []string{"location", "model", "groupid"},
+			want:  "CONCAT_WS('/', `location`, CAST(`model` AS NCHAR(64)), CAST(`groupid` AS NCHAR(64))) AS device_key",
+		},
+		{name: "no tag", alias: "device_key", code: gcode.CodeInvalidParameter},
+		{name: "invalid alias", alias: "device key", tags: []string{"model"}, code: gcode.CodeInvalidParameter},
+		{name: "not found", alias: "device_key", tags: []string{"site"}, code: gcode.CodeNotFound},
+		{name: "not tag", alias: "device_key", tags: []string{"location", "current"}, code: gcode.CodeInvalidParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				d, server = newMockDriver(t, Option{}, desc)
+				ctx       = context.Background()
+			)
+			key, err := d.TagKey(ctx, "meters", c.alias, "/", c.tags...)
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				return
+			}
+			if key != c.want {
+				t.Fatalf("got %q, want %q", key, c.want)
+			}
+			value, err := d.Model("meters").Ctx(ctx).Fields(key).Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value.String() != "beijing/2" {
+				t.Fatalf("got key %q, want %q", value.String(), "beijing/2")
+			}
+			// The fields are rejoined without the spaces after commas by package gdb.
+			sqls := server.Sqls()
+			if got := sqls[len(sqls)-1]; !gstr.HasPrefix(got, "SELECT "+gstr.Replace(c.want, ", ", ",")+" FROM `meters`") {
+				t.Fatalf("got sql %q, want projection %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeTableName(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{name: "d1001", want: "d1001"},
+		{name: "dev.01", want: "dev_2e01"},
+		{name: "3f", want: "_33f"},
+		{name: "Dev", want: "_44ev"},
+		{name: "dev_01", want: "dev__01"},
+		{name: "dev 01", want: "dev_2001"},
+		{name: "_", want: "__"},
+		{name: "电表", want: "_e7_94_b5_e8_a1_a8"},
+		{name: "a-b/c`d'e", want: "a_2db_2fc_60d_27e"},
+		{name: "", want: ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := SanitizeTableName(c.name)
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+			if !gregex.IsMatchString(`^([a-z_][a-z0-9_]*)?$`, got) {
+				t.Fatalf("got invalid table name %q", got)
+			}
+			// The names listed by the server are in lower case.
+			name, err := UnsanitizeTableName(gstr.ToLower(got))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != c.name {
+				t.Fatalf("got %q reverted, want %q", name, c.name)
+			}
+		})
+	}
+}
+
+func TestUnsanitizeTableNameInvalid(t *testing.T) {
+	for _, name := range []string{"dev_", "dev_2", "dev_zz", "_4"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := UnsanitizeTableName(name)
+			checkCode(t, err, gcode.CodeInvalidParameter)
+		})
+	}
+}
+
+func TestStableStats(t *testing.T) {
+	last := gtime.New("2023-01-01 08:00:00")
+	cases := []struct {
+		name   string
+		schema string
+		tables interface{}
+		rows   []interface{}
+		want   *StableStats
+		code   gcode.Code
+	}{
+		{
+			name:   "with data",
+			schema: mockSchema,
+			tables: int64(3),
+			rows:   []interface{}{int64(1200), last.Time},
+			want:   &StableStats{Stable: "meters", ChildTables: 3, Rows: 1200, LastDataTime: last},
+		},
+		{
+			name:   "no rows",
+			schema: mockSchema,
+			tables: int64(2),
+			rows:   []interface{}{int64(0), nil},
+			want:   &StableStats{Stable: "meters", ChildTables: 2},
+		},
+		{name: "no schema", code: gcode.CodeMissingParameter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, servers := newMockSchemaCluster(t, c.schema, Option{}, func(q mockQuery) mockResponse {
+				if gstr.Contains(q.Sql, "ins_tables") {
+					if !gstr.Contains(q.Sql, "db_name='power' AND stable_name='meters'") {
+						t.Errorf("got sql %q, want filtered by database and super table", q.Sql)
+					}
+					return mockRecords([]string{"count(*)"}, []interface{}{c.tables})
+				}
+				return mockRecords([]string{"total_rows", "last_ts"}, c.rows)
+			})
+			stats, err := d.StableStats(context.Background(), "`meters`")
+			checkCode(t, err, c.code)
+			if c.code != nil {
+				if sqls := servers[0].Sqls(); len(sqls) != 0 {
+					t.Fatalf("got statements %q sent, want none", sqls)
+				}
+				return
+			}
+			if stats.Stable != c.want.Stable || stats.ChildTables != c.want.ChildTables || stats.Rows != c.want.Rows {
+				t.Fatalf("got %+v, want %+v", stats, c.want)
+			}
+			if (stats.LastDataTime == nil) != (c.want.LastDataTime == nil) ||
+				(stats.LastDataTime != nil && !stats.LastDataTime.Equal(c.want.LastDataTime)) {
+				t.Fatalf("got last data time %v, want %v", stats.LastDataTime, c.want.LastDataTime)
+			}
+		})
+	}
+}
+
+func TestVgroupTables(t *testing.T) {
+	cases := []struct {
+		name     string
+		stable   string
+		vgroupId int
+		response mockResponse
+		wantSql  string
+		want     []string
+	}{
+		{
+			name:     "tables",
+			stable:   "meters",
+			vgroupId: 2,
+			response: mockRecords([]string{"table_name"}, []interface{}{"d1001"}, []interface{}{"d1003"}),
+			wantSql:  "SELECT table_name FROM information_schema.ins_tables WHERE db_name='power' AND stable_name='meters' AND vgroup_id=2",
+			want:     []string{"d1001", "d1003"},
+		},
+		{
+			name:     "quoted stable",
+			stable:   "`meters`",
+			vgroupId: 3,
+			response: mockRecords([]string{"table_name"}, []interface{}{"d1002"}),
+			wantSql:  "SELECT table_name FROM information_schema.ins_tables WHERE db_name='power' AND stable_name='meters' AND vgroup_id=3",
+			want:     []string{"d1002"},
+		},
+		{
+			name:     "empty vgroup",
+			stable:   "meters",
+			vgroupId: 4,
+			response: mockRecords([]string{"table_name"}),
+			wantSql:  "SELECT table_name FROM information_schema.ins_tables WHERE db_name='power' AND stable_name='meters' AND vgroup_id=4",
+			want:     []string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(mockQuery) mockResponse { return c.response })
+			tables, err := d.VgroupTables(context.Background(), c.stable, c.vgroupId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != c.wantSql {
+				t.Fatalf("got sqls %q, want %q", sqls, c.wantSql)
+			}
+			if !reflect.DeepEqual(tables, c.want) {
+				t.Fatalf("got tables %q, want %q", tables, c.want)
+			}
+		})
+	}
+}
+
+func TestCreateStable(t *testing.T) {
+	var (
+		columns = []gdb.TableField{
+			{Name: "ts", Type: "timestamp"},
+			{Name: "current", Type: "FLOAT"},
+			{Name: "message", Type: "binary (128)"},
+		}
+		tags = []gdb.TableField{
+			{Name: "location", Type: "NCHAR(64)"},
+			{Name: "groupid", Type: "INT"},
+		}
+	)
+	cases := []struct {
+		name        string
+		stable      string
+		columns     []gdb.TableField
+		tags        []gdb.TableField
+		ifNotExists bool
+		want        string
+		code        gcode.Code
+	}{
+		{
+			name:    "create",
+			stable:  "meters",
+			columns: columns,
+			tags:    tags,
+			want:    "CREATE STABLE `meters` (`ts` TIMESTAMP, `current` FLOAT, `message` BINARY(128)) TAGS (`location` NCHAR(64), `groupid` INT)",
+		},
+		{
+			name:        "if not exists",
+			stable:      "archive.meters",
+			columns:     columns[:1],
+			tags:        []gdb.TableField{{Name: "info", Type: "json"}},
+			ifNotExists: true,
+			want:        "CREATE STABLE IF NOT EXISTS `archive`.`meters` (`ts` TIMESTAMP) TAGS (`info` JSON)",
+		},
+		{name: "no columns", stable: "meters", tags: tags, code: gcode.CodeInvalidParameter},
+		{name: "first column not timestamp", stable: "meters", columns: columns[1:], tags: tags, code: gcode.CodeInvalidParameter},
+		{name: "no tags", stable: "meters", columns: columns, code: gcode.CodeInvalidParameter},
+		{
+			name:    "json column",
+			stable:  "meters",
+			columns: append(columns[:1:1], gdb.TableField{Name: "info", Type: "JSON"}),
+			tags:    tags,
+			code:    gcode.CodeInvalidParameter,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, nil)
+			err := d.CreateStable(context.Background(), c.stable, c.columns, c.tags, c.ifNotExists)
+			checkCode(t, err, c.code)
+			var want []string
+			if c.code == nil {
+				want = []string{c.want}
+			}
+			if sqls := server.Sqls(); !reflect.DeepEqual(sqls, want) {
+				t.Fatalf("got sqls %q, want %q", sqls, want)
+			}
+		})
+	}
+}
+
+func TestFormatFieldDefs(t *testing.T) {
+	cases := []struct {
+		name   string
+		fields []gdb.TableField
+		isTag  bool
+		want   string
+		code   gcode.Code
+	}{
+		{
+			name:   "fixed types",
+			fields: []gdb.TableField{{Name: "ts", Type: " timestamp "}, {Name: "n", Type: "bigint unsigned"}, {Name: "ok", Type: "BOOL"}},
+			want:   "`ts` TIMESTAMP, `n` BIGINT UNSIGNED, `ok` BOOL",
+		},
+		{
+			name:   "string lengths",
+			fields: []gdb.TableField{{Name: "a", Type: "varchar( 16 )"}, {Name: "b", Type: "NCHAR(64)"}},
+			want:   "`a` VARCHAR(16), `b` NCHAR(64)",
+		},
+		{name: "json tag", fields: []gdb.TableField{{Name: "info", Type: "json"}}, isTag: true, want: "`info` JSON"},
+		{name: "json column", fields: []gdb.TableField{{Name: "info", Type: "json"}}, code: gcode.CodeInvalidParameter},
+		{name: "string without length", fields: []gdb.TableField{{Name: "a", Type: "NCHAR"}}, code: gcode.CodeInvalidParameter},
+		{name: "zero length", fields: []gdb.TableField{{Name: "a", Type: "BINARY(0)"}}, code: gcode.CodeInvalidParameter},
+		{name: "unknown type", fields: []gdb.TableField{{Name: "a", Type: "TEXT"}}, code: gcode.CodeInvalidParameter},
+		{name: "invalid name", fields: []gdb.TableField{{Name: "a b", Type: "INT"}}, code: gcode.CodeInvalidParameter},
+	}
+	d, _ := newMockDriver(t, Option{}, nil)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := d.formatFieldDefs(c.fields, c.isTag)
+			checkCode(t, err, c.code)
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
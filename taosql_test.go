@@ -0,0 +1,470 @@
+package taosql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+func TestDoFilterPlaceholders(t *testing.T) {
+	cases := []struct {
+		name     string
+		sql      string
+		args     []interface{}
+		wantSql  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "where placeholders",
+			sql:      "SELECT * FROM `d1001` WHERE a=? AND b=?",
+			args:     []interface{}{1, "x"},
+			wantSql:  "SELECT * FROM `d1001` WHERE a=? AND b=?",
+			wantArgs: []interface{}{1, "x"},
+		},
+		{
+			name:     "insert placeholders",
+			sql:      "INSERT INTO `d1001`(`current`,`voltage`) VALUES(?,?)",
+			args:     []interface{}{10.3, 219},
+			wantSql:  "INSERT INTO `d1001`(`current`,`voltage`) VALUES(?,?)",
+			wantArgs: []interface{}{10.3, 219},
+		},
+		{
+			name:     "mysql style limit",
+			sql:      "SELECT * FROM `d1001` WHERE a=? LIMIT 10,20",
+			args:     []interface{}{1},
+			wantSql:  "SELECT * FROM `d1001` WHERE a=? LIMIT 20 OFFSET 10",
+			wantArgs: []interface{}{1},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, Option{}, metersHandler(nil))
+			sql, args, err := d.DoFilter(context.Background(), nil, c.sql, c.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != c.wantSql {
+				t.Fatalf("got sql %q, want %q", sql, c.wantSql)
+			}
+			if !reflect.DeepEqual(args, c.wantArgs) {
+				t.Fatalf("got args %v, want %v", args, c.wantArgs)
+			}
+		})
+	}
+}
+
+func TestDoFilterModelPlaceholders(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	if _, err := d.Model("d1001").Where("current>? AND voltage=?", 10.5, 220).All(); err != nil {
+		t.Fatal(err)
+	}
+	queries := server.Queries()
+	q := queries[len(queries)-1]
+	if want := "SELECT * FROM `d1001` WHERE current>? AND voltage=?"; q.Sql != want {
+		t.Fatalf("got sql %q, want %q", q.Sql, want)
+	}
+	if want := []interface{}{10.5, 220}; !reflect.DeepEqual(q.Args, want) {
+		t.Fatalf("got args %#v, want %#v", q.Args, want)
+	}
+}
+
+func TestGetChars(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	if charL, charR := d.GetChars(); charL != "`" || charR != "`" {
+		t.Fatalf("got chars %q and %q, want backticks", charL, charR)
+	}
+	if _, err := d.Model("d1001").Fields("group").All(); err != nil {
+		t.Fatal(err)
+	}
+	sqls := server.Sqls()
+	if got := sqls[len(sqls)-1]; !gstr.Contains(got, "`group`") || gstr.Contains(got, `"group"`) {
+		t.Fatalf("got sql %q, want field quoted by backticks", got)
+	}
+}
+
+func TestTableFieldsQuotedTable(t *testing.T) {
+	for _, table := range []string{"d1001", "`d1001`"} {
+		t.Run(table, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, metersHandler(nil))
+			fields, err := d.TableFields(context.Background(), table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sqls := server.Sqls(); len(sqls) != 1 || sqls[0] != "desc d1001" {
+				t.Fatalf("got sqls %q, want %q", sqls, "desc d1001")
+			}
+			if len(fields) != len(mockMetersDesc.Rows) {
+				t.Fatalf("got %d fields, want %d", len(fields), len(mockMetersDesc.Rows))
+			}
+		})
+	}
+}
+
+func TestTables(t *testing.T) {
+	cases := []struct {
+		name     string
+		schema   []string
+		wantSqls []string
+	}{
+		{name: "current schema", wantSqls: []string{"SHOW STABLES", "SHOW TABLES"}},
+		{name: "schema", schema: []string{"archive"}, wantSqls: []string{"SHOW `archive`.STABLES", "SHOW `archive`.TABLES"}},
+		{
+			name:     "like pattern",
+			schema:   []string{"power", "d10%"},
+			wantSqls: []string{"SHOW `power`.STABLES LIKE 'd10%'", "SHOW `power`.TABLES LIKE 'd10%'"},
+		},
+		{
+			// The quote and escape chars are escaped for the string literal, so that the LIKE escape \_ is kept.
+			name:     "escaped pattern",
+			schema:   []string{"power", "d1001's\\_%"},
+			wantSqls: []string{"SHOW `power`.STABLES LIKE 'd1001\\'s\\\\_%'", "SHOW `power`.TABLES LIKE 'd1001\\'s\\\\_%'"},
+		},
+		{name: "empty pattern", schema: []string{"power", ""}, wantSqls: []string{"SHOW `power`.STABLES", "SHOW `power`.TABLES"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, server := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+				if gstr.Contains(q.Sql, "STABLES") {
+					return mockRecords([]string{"stable_name", "db_name"}, []interface{}{"meters", "power"})
+				}
+				return mockRecords(
+					[]string{"table_name", "db_name", "stable_name"},
+					[]interface{}{"d1001", "power", "meters"}, []interface{}{"d1002", "power", "meters"},
+				)
+			})
+			tables, err := d.Tables(context.Background(), c.schema...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sqls := server.Sqls(); !reflect.DeepEqual(sqls, c.wantSqls) {
+				t.Fatalf("got sqls %q, want %q", sqls, c.wantSqls)
+			}
+			if want := []string{"meters", "d1001", "d1002"}; !reflect.DeepEqual(tables, want) {
+				t.Fatalf("got tables %q, want %q", tables, want)
+			}
+		})
+	}
+}
+
+// newConfigDriver creates and returns a driver of configuration `node` with `option`, which is not connected.
+func newConfigDriver(t *testing.T, option Option, node gdb.ConfigNode) *Driver {
+	t.Helper()
+	name := fmt.Sprintf(`taosql_config_%d`, atomic.AddInt64(&mockSequence, 1))
+	if err := gdb.Register(name, &mockGdbDriver{option: option}); err != nil {
+		t.Fatal(err)
+	}
+	node.Type = name
+	gdb.AddConfigNode(name, node)
+	db, err := gdb.NewByGroup(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db.(*mockDB).Driver
+}
+
+func TestFilteredLink(t *testing.T) {
+	cases := []struct {
+		name   string
+		option Option
+		node   gdb.ConfigNode
+		want   string
+	}{
+		{
+			name: "discrete fields",
+			node: gdb.ConfigNode{User: "root", Pass: "taosdata", Host: "127.0.0.1", Port: "6030", Name: "power"},
+			want: "root:xxx@tcp(127.0.0.1:6030)/power",
+		},
+		{
+			name: "discrete fields with timezone",
+			node: gdb.ConfigNode{
+				User: "root", Pass: "taosdata", Host: "127.0.0.1", Port: "6030", Name: "power", Timezone: "Asia/Shanghai",
+			},
+			want: "root:xxx@tcp(127.0.0.1:6030)/power?loc=Asia%2FShanghai",
+		},
+		{
+			name:   "discrete fields of websocket",
+			option: Option{Connector: ConnectorWebSocket},
+			node:   gdb.ConfigNode{User: "root", Pass: "taosdata", Host: "127.0.0.1", Port: "6041", Name: "power"},
+			want:   "root:xxx@ws(127.0.0.1:6041)/power",
+		},
+		{name: "no link", node: gdb.ConfigNode{Name: "power"}, want: ""},
+		{
+			name: "dsn link",
+			node: gdb.ConfigNode{Link: "root:taosdata@tcp(127.0.0.1:6030)/power"},
+			want: "root:xxx@tcp(127.0.0.1:6030)/power",
+		},
+		{
+			name: "dsn link of rest",
+			node: gdb.ConfigNode{Link: "root:taos:data@http(127.0.0.1:6041)/power"},
+			want: "root:xxx@http(127.0.0.1:6041)/power",
+		},
+		{
+			name: "key value link",
+			node: gdb.ConfigNode{Link: "user=root password=taosdata host=127.0.0.1 port=6030 dbname=power"},
+			want: "user=root password=xxx host=127.0.0.1 port=6030 dbname=power",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := newConfigDriver(t, c.option, c.node)
+			got := d.FilteredLink()
+			if got != c.want {
+				t.Fatalf("got link %q, want %q", got, c.want)
+			}
+			if gstr.Contains(got, "taosdata") {
+				t.Fatalf("got link %q with the password", got)
+			}
+		})
+	}
+}
+
+func TestTableFields(t *testing.T) {
+	cases := []struct {
+		name string
+		desc mockResponse
+		want map[string]gdb.TableField
+	}{
+		{
+			name: "super table",
+			desc: mockRecords(
+				[]string{"field", "type", "length", "note"},
+				[]interface{}{"ts", "TIMESTAMP", int64(8), ""},
+				[]interface{}{"current", "FLOAT", int64(4), ""},
+				[]interface{}{"name", "NCHAR", int64(32), ""},
+				[]interface{}{"location", "VARCHAR", int64(64), "TAG"},
+				[]interface{}{"info", "json", int64(4095), "TAG"},
+			),
+			want: map[string]gdb.TableField{
+				"ts":       {Index: 0, Name: "ts", Type: "TIMESTAMP", Key: fieldKeyPrimary},
+				"current":  {Index: 1, Name: "current", Type: "FLOAT"},
+				"name":     {Index: 2, Name: "name", Type: "NCHAR(32)"},
+				"location": {Index: 3, Name: "location", Type: "VARCHAR(64)", Extra: fieldExtraTag},
+				"info":     {Index: 4, Name: "info", Type: fieldTypeJSON, Extra: fieldExtraTag},
+			},
+		},
+		{
+			name: "capitalized columns of 2.x",
+			desc: mockRecords(
+				[]string{"Field", "Type", "Length", "Note"},
+				[]interface{}{"created", "TIMESTAMP", int64(8), ""},
+				[]interface{}{"message", "BINARY", int64(128), ""},
+				[]interface{}{"level", "BINARY(16)", int64(16), "tag"},
+			),
+			want: map[string]gdb.TableField{
+				"created": {Index: 0, Name: "created", Type: "TIMESTAMP", Key: fieldKeyPrimary},
+				"message": {Index: 1, Name: "message", Type: "BINARY(128)"},
+				"level":   {Index: 2, Name: "level", Type: "BINARY(16)", Extra: fieldExtraTag},
+			},
+		},
+		{
+			name: "lengths of variable-width types only",
+			desc: mockRecords(
+				[]string{"field", "type", "length", "note"},
+				[]interface{}{"ts", "TIMESTAMP", int64(8), ""},
+				[]interface{}{"voltage", "INT", int64(4), ""},
+				[]interface{}{"flag", "BOOL", int64(1), ""},
+				[]interface{}{"code", "varchar", int64(20), ""},
+				[]interface{}{"name", "nchar", int64(0), ""},
+			),
+			want: map[string]gdb.TableField{
+				"ts":      {Index: 0, Name: "ts", Type: "TIMESTAMP", Key: fieldKeyPrimary},
+				"voltage": {Index: 1, Name: "voltage", Type: "INT"},
+				"flag":    {Index: 2, Name: "flag", Type: "BOOL"},
+				"code":    {Index: 3, Name: "code", Type: "varchar(20)"},
+				"name":    {Index: 4, Name: "name", Type: "nchar"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, Option{}, func(mockQuery) mockResponse { return c.desc })
+			fields, err := d.TableFields(context.Background(), "meters")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]gdb.TableField, len(fields))
+			for name, field := range fields {
+				got[name] = *field
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got fields %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRecordValue(t *testing.T) {
+	cases := []struct {
+		name   string
+		record gdb.Record
+		want   interface{}
+	}{
+		{name: "lower case", record: gdb.Record{"field": gvar.New("ts")}, want: "ts"},
+		{name: "capitalized", record: gdb.Record{"Field": gvar.New("ts")}, want: "ts"},
+		{name: "upper case", record: gdb.Record{"FIELD": gvar.New("ts")}, want: "ts"},
+		{name: "exact match first", record: gdb.Record{"Field": gvar.New("Ts"), "field": gvar.New("ts")}, want: "ts"},
+		{name: "missing", record: gdb.Record{"type": gvar.New("INT")}, want: nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := recordValue(c.record, "field").Val(); got != c.want {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestClearTableFields(t *testing.T) {
+	descs := make(map[string]int)
+	d, _ := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+		descs[q.Sql]++
+		return mockMetersDesc
+	})
+	ctx := context.Background()
+	tables := []struct{ table, schema string }{{"meters", ""}, {"d1001", ""}, {"meters", "archive"}}
+	load := func() {
+		t.Helper()
+		for _, table := range tables {
+			if _, err := d.TableFields(ctx, table.table, table.schema); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	cases := []struct {
+		name  string
+		clear func()
+		want  map[string]int
+	}{
+		{name: "cached", clear: func() {}, want: map[string]int{"desc meters": 2, "desc d1001": 1}},
+		{
+			name:  "clear quoted table",
+			clear: func() { d.ClearTableFields("`meters`") },
+			want:  map[string]int{"desc meters": 3, "desc d1001": 1},
+		},
+		{
+			name:  "clear table of schema",
+			clear: func() { d.ClearTableFields("meters", "archive") },
+			want:  map[string]int{"desc meters": 4, "desc d1001": 1},
+		},
+		{
+			name:  "clear all",
+			clear: d.ClearAllTableFields,
+			want:  map[string]int{"desc meters": 6, "desc d1001": 2},
+		},
+	}
+	load()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.clear()
+			load()
+			if !reflect.DeepEqual(descs, c.want) {
+				t.Fatalf("got desc statements %v, want %v", descs, c.want)
+			}
+		})
+	}
+}
+
+func TestCacheGroupIsolation(t *testing.T) {
+	clusters := []struct {
+		precision string
+		version   string
+		column    string
+	}{
+		{precision: "ms", version: "3.0.4.0", column: "current"},
+		{precision: "us", version: "3.1.0.0", column: "voltage"},
+	}
+	var (
+		drivers = make([]*Driver, len(clusters))
+		servers = make([]*mockServer, len(clusters))
+	)
+	for i, cluster := range clusters {
+		cluster := cluster
+		drivers[i], servers[i] = newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+			switch {
+			case q.Sql == "SHOW DATABASES":
+				return mockRecords([]string{"name", "precision"}, []interface{}{mockSchema, cluster.precision})
+			case q.Sql == "SELECT SERVER_VERSION()":
+				return mockRecords([]string{"server_version()"}, []interface{}{cluster.version})
+			case gstr.HasPrefix(q.Sql, "desc "):
+				return mockRecords(
+					[]string{"field", "type", "length", "note"},
+					[]interface{}{"ts", "TIMESTAMP", int64(8), ""},
+					[]interface{}{cluster.column, "FLOAT", int64(4), ""},
+				)
+			}
+			return mockResponse{}
+		})
+	}
+	ctx := context.Background()
+	// Each lookup is repeated, so that the second one is served by the cache of the group.
+	for round := 0; round < 2; round++ {
+		for i, cluster := range clusters {
+			d := drivers[i]
+			if precision, err := d.Precision(ctx); err != nil || precision != cluster.precision {
+				t.Fatalf("got precision %q and error %v of cluster %d, want %q", precision, err, i, cluster.precision)
+			}
+			if version, err := d.ServerVersion(ctx); err != nil || version != cluster.version {
+				t.Fatalf("got version %q and error %v of cluster %d, want %q", version, err, i, cluster.version)
+			}
+			fields, err := d.TableFields(ctx, "meters")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := fields[cluster.column]; !ok || len(fields) != 2 {
+				t.Fatalf("got fields %v of cluster %d, want column %q", fields, i, cluster.column)
+			}
+		}
+	}
+	for i, server := range servers {
+		if sqls := server.Sqls(); len(sqls) != 3 {
+			t.Fatalf("got sqls %q of cluster %d, want each information retrieved once", sqls, i)
+		}
+	}
+}
+
+func TestNow(t *testing.T) {
+	fixed := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		option Option
+		want   time.Time
+	}{
+		{name: "fixed clock", option: Option{Clock: func() time.Time { return fixed }}, want: fixed},
+		{name: "default clock"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, _ := newMockDriver(t, c.option, nil)
+			before := time.Now()
+			got := d.now()
+			if !c.want.IsZero() {
+				if !got.Equal(c.want) {
+					t.Fatalf("got now %v, want %v", got, c.want)
+				}
+				return
+			}
+			if got.Before(before) || got.After(time.Now()) {
+				t.Fatalf("got now %v, want the current time", got)
+			}
+		})
+	}
+}
+
+func TestNowFixedClockDuration(t *testing.T) {
+	fixed := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, _ := newMockDriver(t, Option{SlowQueryThreshold: time.Nanosecond, Clock: func() time.Time { return fixed }}, nil)
+	logs := captureLogs(d)
+	if _, err := d.Exec(context.Background(), "SELECT * FROM meters"); err != nil {
+		t.Fatal(err)
+	}
+	if gstr.Contains(logs.String(), "slow query") {
+		t.Fatalf("got log %q, want no slow query of zero duration by fixed clock", logs.String())
+	}
+}
@@ -0,0 +1,100 @@
+package taosql
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// commitWithDeadline commits `in` by Core.DoCommit within the deadline of `ctx`, see runWithDeadline.
+//
+// Only the query and exec statements are abandoned, as the results of the others should be closed by the caller.
+func (d *Driver) commitWithDeadline(ctx context.Context, in gdb.DoCommitInput) (out gdb.DoCommitOutput, err error) {
+	switch in.Type {
+	case gdb.SqlTypeQueryContext, gdb.SqlTypeExecContext, gdb.SqlTypeStmtExecContext:
+	default:
+		if err = ctx.Err(); err != nil {
+			return out, wrapContextError(err)
+		}
+		return d.Core.DoCommit(ctx, in)
+	}
+	var result gdb.DoCommitOutput
+	if err = d.runWithDeadline(ctx, func() (err error) {
+		result, err = d.Core.DoCommit(ctx, in)
+		return
+	}, nil); err != nil {
+		return out, err
+	}
+	return result, nil
+}
+
+// abandonedStatements is the number of the statements abandoned at their deadlines by runWithDeadline
+// that are still running in background, which is shared by all drivers of the process.
+var abandonedStatements int64
+
+// runWithDeadline runs `run` and returns its error, or returns promptly with a timeout error once `ctx` is done
+// if it has a deadline, as the native connector blocks in the client library regardless of the context.
+// The abandoned `run` keeps running to its end in background, after which `discard` is called if it's not nil,
+// which releases the results of `run`, like closing the rows.
+//
+// Each abandoned statement holds its goroutine and connection until the server responds, which is never
+// reclaimed earlier, as the native client library cannot be interrupted. So once Option.MaxAbandonedStatements
+// statements are abandoned and still running, the later statements run in the calling goroutine instead,
+// which wait for the server past their deadlines, bounding the goroutines leaked by a stuck server.
+func (d *Driver) runWithDeadline(ctx context.Context, run func() error, discard func()) error {
+	if err := ctx.Err(); err != nil {
+		return wrapContextError(err)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return run()
+	}
+	// finish reports the error of `run` that fails for the context as the context error.
+	finish := func(err error) error {
+		if err != nil && ctx.Err() != nil {
+			err = wrapContextError(ctx.Err())
+		}
+		return err
+	}
+	maxAbandoned := d.option.MaxAbandonedStatements
+	if maxAbandoned <= 0 {
+		maxAbandoned = defaultMaxAbandonedStatements
+	}
+	if atomic.LoadInt64(&abandonedStatements) >= int64(maxAbandoned) {
+		return finish(run())
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- run()
+	}()
+	select {
+	case err := <-done:
+		return finish(err)
+	case <-ctx.Done():
+		atomic.AddInt64(&abandonedStatements, 1)
+		go func() {
+			<-done
+			if discard != nil {
+				discard()
+			}
+			atomic.AddInt64(&abandonedStatements, -1)
+		}()
+		return wrapContextError(ctx.Err())
+	}
+}
+
+// wrapContextError wraps context error `err` as error of code gcode.CodeDbOperationError,
+// with message telling whether the statement timed out or was canceled.
+func wrapContextError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return gerror.WrapCode(gcode.CodeDbOperationError, err, `query timed out`)
+	case errors.Is(err, context.Canceled):
+		return gerror.WrapCode(gcode.CodeDbOperationError, err, `query canceled`)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,170 @@
+package taosql
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// waitAbandoned waits for the number of the abandoned statements to be `want`, and fails if it's not in time.
+func waitAbandoned(t *testing.T, want int64) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		got := atomic.LoadInt64(&abandonedStatements)
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d abandoned statements, want %d", got, want)
+		}
+	}
+}
+
+func TestRunWithDeadline(t *testing.T) {
+	errRun := errors.New("run failed")
+	cases := []struct {
+		name    string
+		timeout time.Duration // No deadline if 0, or expired already if negative.
+		run     func(ctx context.Context) error
+		want    string
+		ran     bool
+	}{
+		{name: "no deadline", run: func(context.Context) error { return errRun }, want: "run failed", ran: true},
+		{name: "within deadline", timeout: time.Second, run: func(context.Context) error { return nil }, ran: true},
+		{name: "error within deadline", timeout: time.Second, run: func(context.Context) error { return errRun }, want: "run failed", ran: true},
+		{name: "expired", timeout: -time.Second, run: func(context.Context) error { return nil }, want: "query timed out"},
+		{
+			name:    "failed for deadline",
+			timeout: 10 * time.Millisecond,
+			run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			want: "query timed out",
+			ran:  true,
+		},
+	}
+	d, _ := newMockDriver(t, Option{}, nil)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.timeout != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.timeout)
+				defer cancel()
+			}
+			var ran bool
+			err := d.runWithDeadline(ctx, func() error {
+				ran = true
+				return c.run(ctx)
+			}, nil)
+			if c.want == "" {
+				checkCode(t, err, nil)
+			} else if err == nil || !gstr.Contains(err.Error(), c.want) {
+				t.Fatalf("got error %v, want %q", err, c.want)
+			}
+			if ran != c.ran {
+				t.Fatalf("got ran %v, want %v", ran, c.ran)
+			}
+		})
+	}
+}
+
+func TestRunWithDeadlineAbandoned(t *testing.T) {
+	// The statements abandoned by the other tests end once their tests finish.
+	waitAbandoned(t, 0)
+	d, _ := newMockDriver(t, Option{}, nil)
+	var (
+		block     = make(chan struct{})
+		discarded = make(chan struct{})
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := d.runWithDeadline(ctx, func() error {
+		<-block
+		return nil
+	}, func() { close(discarded) })
+	checkCode(t, err, gcode.CodeDbOperationError)
+	if !gstr.Contains(err.Error(), "query timed out") {
+		t.Fatalf("got error %v, want timed out", err)
+	}
+	waitAbandoned(t, 1)
+	select {
+	case <-discarded:
+		t.Fatal("discarded before the abandoned statement ends")
+	default:
+	}
+	close(block)
+	<-discarded
+	waitAbandoned(t, 0)
+}
+
+func TestRunWithDeadlineCap(t *testing.T) {
+	waitAbandoned(t, 0)
+	d, _ := newMockDriver(t, Option{MaxAbandonedStatements: 1}, nil)
+	block := make(chan struct{})
+	run := func() error {
+		<-block
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	checkCode(t, d.runWithDeadline(ctx, run, nil), gcode.CodeDbOperationError)
+	waitAbandoned(t, 1)
+
+	// The cap is reached, so the next statement waits in the calling goroutine past its deadline.
+	const release = 50 * time.Millisecond
+	time.AfterFunc(release, func() { close(block) })
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel2()
+	start := time.Now()
+	err := d.runWithDeadline(ctx2, run, nil)
+	if elapsed := time.Since(start); elapsed < release-10*time.Millisecond {
+		t.Fatalf("returned after %s, want waiting for the statement in the calling goroutine", elapsed)
+	}
+	// The statement succeeded past the deadline, which is reported as is.
+	checkCode(t, err, nil)
+	waitAbandoned(t, 0)
+}
+
+func TestDoCommitDeadline(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	d, server := newMockDriver(t, Option{}, func(q mockQuery) mockResponse {
+		<-block
+		return mockResponse{}
+	})
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	canceled, cancelCanceled := context.WithCancel(context.Background())
+	cancelCanceled()
+	cases := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{name: "expired query", call: func() error { _, err := d.GetAll(expired, "SELECT * FROM meters"); return err }, want: "query timed out"},
+		{name: "canceled exec", call: func() error { _, err := d.Exec(canceled, "DELETE FROM meters"); return err }, want: "query canceled"},
+		{name: "blocking exec", call: func() error { _, err := d.Exec(short, "INSERT INTO d1001 VALUES (NOW, 10.5)"); return err }, want: "query timed out"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.call()
+			checkCode(t, err, gcode.CodeDbOperationError)
+			if !gstr.Contains(err.Error(), c.want) {
+				t.Fatalf("got error %v, want %q", err, c.want)
+			}
+		})
+	}
+	// Only the blocking exec reaches the server, the expired and canceled ones are not sent.
+	if sqls := server.Sqls(); len(sqls) != 1 {
+		t.Fatalf("got sqls %q, want only the blocking exec", sqls)
+	}
+}
@@ -0,0 +1,255 @@
+package taosql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/text/gregex"
+	"github.com/gogf/gf/v2/text/gstr"
+)
+
+// WhereTableName returns the `TBNAME IN ('name1', 'name2', ...)` predicate, which selects the data of given
+// subtables from a super table. It is much faster than OR-ing tag predicates, as TDengine only scans
+// the given subtables, eg:
+//
+// where, err := taosql.WhereTableName("d1001", "d1002")
+// db.Model("meters").Where(where).All()
+//
+// The names are quoted as string literals, as the TBNAME pseudo column is compared with strings.
+func WhereTableName(names ...string) (string, error) {
+	if len(names) == 0 {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, `at least one table name is required for TBNAME IN`)
+	}
+	array := make([]string, len(names))
+	for i, name := range names {
+		if gstr.Trim(name) == "" {
+			return "", gerror.NewCode(gcode.CodeInvalidParameter, `table name should not be empty for TBNAME IN`)
+		}
+		array[i] = quoteString(name)
+	}
+	return fmt.Sprintf(`TBNAME IN (%s)`, gstr.Join(array, ", ")), nil
+}
+
+// JSONPath returns the `tag->'key'` expression, which accesses the value of `key` in JSON tag `tag`,
+// and can be used in both predicates and projections, like: Where(path + " > 10").
+// The `path` elements are the keys for string and the array indexes for int.
+//
+// TDengine supports only the first level keys of JSON tags, as the JSON tag values are flattened to
+// the first level key-value pairs, and the arrays and nested objects are not indexable. So it returns an error
+// of code gcode.CodeNotSupported for the array indexes or nested keys, instead of an expression that
+// fails at the server.
+func JSONPath(tag string, path ...interface{}) (string, error) {
+	if err := checkExpr(tag); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid JSON tag`)
+	}
+	if len(path) == 0 {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, `at least one key is required for JSON path`)
+	}
+	key, ok := path[0].(string)
+	if !ok {
+		return "", gerror.NewCodef(
+			gcode.CodeNotSupported, `JSON path element %v is not supported, only the first level keys are indexable`, path[0],
+		)
+	}
+	if key == "" {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, `JSON key should not be empty`)
+	}
+	if len(path) > 1 {
+		return "", gerror.NewCodef(
+			gcode.CodeNotSupported, `JSON path %v is not supported, only the first level keys are indexable`, path,
+		)
+	}
+	return fmt.Sprintf(`%s->%s`, tag, quoteString(key)), nil
+}
+
+// GroupByJSON returns the `tag->'key1', tag->'key2', ...` expressions, which group the rows by the values of
+// given keys of JSON tag `tag`, and can be passed to gdb.Model.Group or Clause.PartitionBy, eg:
+//
+// group, err := taosql.GroupByJSON("info", "region")
+// db.Model("meters").Fields(group, "AVG(current)").Group(group).All()
+//
+// The keys should be word chars, as package gdb splits the GROUP BY expressions by commas, spaces and dots for
+// quoting the column names.
+func GroupByJSON(tag string, keys ...string) (string, error) {
+	if len(keys) == 0 {
+		return "", gerror.NewCode(gcode.CodeInvalidParameter, `at least one key is required for GROUP BY JSON tag`)
+	}
+	paths := make([]string, len(keys))
+	for i, key := range keys {
+		if !gregex.IsMatchString(`^\w+$`, key) {
+			return "", gerror.NewCodef(gcode.CodeInvalidParameter, `invalid JSON key "%s" for GROUP BY`, key)
+		}
+		path, err := JSONPath(tag, key)
+		if err != nil {
+			return "", err
+		}
+		paths[i] = path
+	}
+	return gstr.Join(paths, ", "), nil
+}
+
+// WhereMatch returns the `column MATCH 'pattern'` predicate, which selects the rows whose string column or tag
+// `column` matches POSIX regular expression `pattern`, like: WhereMatch("location", "^California\\.").
+// The `column` should be of BINARY or NCHAR type, which can be validated by Driver.CheckStringColumn.
+func WhereMatch(column, pattern string) (string, error) {
+	return buildMatch(column, "MATCH", pattern)
+}
+
+// WhereNotMatch returns the `column NMATCH 'pattern'` predicate, which selects the rows whose string column or
+// tag `column` does not match POSIX regular expression `pattern`, see WhereMatch.
+func WhereNotMatch(column, pattern string) (string, error) {
+	return buildMatch(column, "NMATCH", pattern)
+}
+
+// buildMatch validates and renders the regular expression predicate `column operator 'pattern'`.
+func buildMatch(column, operator, pattern string) (string, error) {
+	if err := checkExpr(column); err != nil {
+		return "", gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid column for %s`, operator)
+	}
+	if pattern == "" {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `pattern should not be empty for %s`, operator)
+	}
+	if _, err := regexp.CompilePOSIX(pattern); err != nil {
+		return "", gerror.WrapCodef(gcode.CodeInvalidParameter, err, `invalid pattern "%s" for %s`, pattern, operator)
+	}
+	return fmt.Sprintf(`%s %s %s`, column, operator, quoteString(pattern)), nil
+}
+
+// CheckStringColumn checks whether `column` is a column or tag of BINARY(VARCHAR) or NCHAR type of `table`,
+// by its table fields, which is required by the string predicates like WhereMatch.
+func (d *Driver) CheckStringColumn(ctx context.Context, table, column string) error {
+	fields, err := d.TableFields(ctx, table)
+	if err != nil {
+		return err
+	}
+	field, ok := fields[column]
+	if !ok {
+		return gerror.NewCodef(gcode.CodeNotFound, `column "%s" not found in table "%s"`, column, table)
+	}
+	switch columnTypeName(field.Type) {
+	case "binary", "varchar", "nchar":
+		return nil
+	default:
+		return gerror.NewCodef(
+			gcode.CodeInvalidParameter, `column "%s" of type %s is not a string column`, column, field.Type,
+		)
+	}
+}
+
+// WhereIn returns the `column IN (subquery)` predicate, which selects the rows whose `column` is in the result
+// of `subquery`, like the devices derived from another query, eg:
+//
+// where, err := taosql.WhereIn("tbname", "SELECT tbname FROM alerts WHERE level > ?", 3)
+// db.Model("meters").Where(where).All()
+//
+// The `args` are inlined into `subquery`. The `subquery` should be a SELECT statement of a single column,
+// as TDengine supports only the uncorrelated subquery of a single column in WHERE. It returns an error of code
+// gcode.CodeNotSupported for the other forms, like multiple columns.
+func WhereIn(column, subquery string, args ...interface{}) (string, error) {
+	if err := checkExpr(column); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid column for IN subquery`)
+	}
+	subquery = gstr.Trim(subquery)
+	if err := checkExpr(subquery); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid subquery for IN`)
+	}
+	if !gregex.IsMatchString(`^(?i)SELECT\s`, subquery) {
+		return "", gerror.NewCodef(gcode.CodeNotSupported, `subquery "%s" for IN should be a SELECT statement`, subquery)
+	}
+	from := topLevelIndex(subquery, " FROM ")
+	if from < 0 {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `subquery "%s" for IN has no FROM`, subquery)
+	}
+	projection := subquery[len("SELECT "):from]
+	if gstr.Trim(projection) == "*" || topLevelIndex(projection, ",") >= 0 {
+		return "", gerror.NewCodef(
+			gcode.CodeNotSupported, `subquery "%s" for IN should select a single column`, subquery,
+		)
+	}
+	subquery, err := inlineArgs(subquery, args)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`%s IN (%s)`, column, subquery), nil
+}
+
+// WhereTimeWithin returns the `tsColumn > NOW - duration` predicate, which selects the rows within the latest
+// `duration` for the live window queries, like: WhereTimeWithin("ts", time.Hour) for `ts > NOW - 1h`.
+// The current time is evaluated by the server, so that it does not depend on the clock of the client.
+func WhereTimeWithin(tsColumn string, duration time.Duration) (string, error) {
+	if err := checkExpr(tsColumn); err != nil {
+		return "", gerror.WrapCode(gcode.CodeInvalidParameter, err, `invalid timestamp column`)
+	}
+	if duration <= 0 {
+		return "", gerror.NewCodef(gcode.CodeInvalidParameter, `duration should be positive, but got %s`, duration)
+	}
+	return fmt.Sprintf(`%s > NOW - %s`, tsColumn, formatDuration(duration)), nil
+}
+
+// inlineArgs replaces the `?` placeholders of `sql` that are not quoted with the literals of `args` in order,
+// in which the strings are quoted, and the time.Time are quoted as timestamp literals with nanoseconds.
+func inlineArgs(sql string, args []interface{}) (string, error) {
+	var (
+		b       strings.Builder
+		index   int
+		quote   byte
+		escaped bool
+	)
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if ch == '\\' {
+				escaped = true
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '?':
+			if index >= len(args) {
+				return "", gerror.NewCodef(gcode.CodeInvalidParameter, `missing arguments for placeholders of "%s"`, sql)
+			}
+			b.WriteString(formatLiteral(args[index]))
+			index++
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	if index != len(args) {
+		return "", gerror.NewCodef(
+			gcode.CodeInvalidParameter, `%d arguments given for %d placeholders of "%s"`, len(args), index, sql,
+		)
+	}
+	return b.String(), nil
+}
+
+// formatLiteral formats `v` as SQL literal.
+func formatLiteral(v interface{}) string {
+	switch value := v.(type) {
+	case time.Time:
+		return formatTimeLiteral(value)
+	case *time.Time:
+		if value == nil {
+			return "NULL"
+		}
+		return formatTimeLiteral(*value)
+	case *gtime.Time:
+		if value == nil {
+			return "NULL"
+		}
+		return formatTimeLiteral(value.Time)
+	case []byte:
+		return quoteString(string(value))
+	default:
+		return formatFillValue(v)
+	}
+}
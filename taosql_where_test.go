@@ -0,0 +1,287 @@
+package taosql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gcode"
+)
+
+func TestWhereTableName(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "single",
+			call: func() (string, error) { return WhereTableName("d1001") },
+			want: "TBNAME IN ('d1001')",
+		},
+		{
+			name: "multiple",
+			call: func() (string, error) { return WhereTableName("d1001", "d1002", "d1003") },
+			want: "TBNAME IN ('d1001', 'd1002', 'd1003')",
+		},
+		{
+			name: "quoted",
+			call: func() (string, error) { return WhereTableName("it's") },
+			want: `TBNAME IN ('it\'s')`,
+		},
+		{
+			name: "no names",
+			call: func() (string, error) { return WhereTableName() },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "empty name",
+			call: func() (string, error) { return WhereTableName("d1001", " ") },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestJSONPath(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "key",
+			call: func() (string, error) { return JSONPath("info", "region") },
+			want: "info->'region'",
+		},
+		{
+			name: "quoted key",
+			call: func() (string, error) { return JSONPath("info", "it's") },
+			want: `info->'it\'s'`,
+		},
+		{
+			name: "array index",
+			call: func() (string, error) { return JSONPath("info", 0) },
+			code: gcode.CodeNotSupported,
+		},
+		{
+			name: "array element",
+			call: func() (string, error) { return JSONPath("info", "arr", 0) },
+			code: gcode.CodeNotSupported,
+		},
+		{
+			name: "nested key",
+			call: func() (string, error) { return JSONPath("info", "site", "region") },
+			code: gcode.CodeNotSupported,
+		},
+		{
+			name: "no key",
+			call: func() (string, error) { return JSONPath("info") },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "empty key",
+			call: func() (string, error) { return JSONPath("info", "") },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "invalid tag",
+			call: func() (string, error) { return JSONPath("info; DROP TABLE t", "region") },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestJSONPathStatement(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, nil)
+	path, err := JSONPath("info", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.Model("meters").Ctx(context.Background()).Fields(path).Where(path+" = ?", "north").All(); err != nil {
+		t.Fatal(err)
+	}
+	sqls := server.Sqls()
+	if want := "SELECT info->'region' FROM `meters` WHERE info->'region' = ?"; sqls[len(sqls)-1] != want {
+		t.Fatalf("got sql %q, want %q", sqls[len(sqls)-1], want)
+	}
+}
+
+func TestWhereMatch(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "match",
+			call: func() (string, error) { return WhereMatch("location", `^California\.`) },
+			want: `location MATCH '^California\\.'`,
+		},
+		{
+			name: "not match",
+			call: func() (string, error) { return WhereNotMatch("tbname", "^d10[0-9]+$") },
+			want: "tbname NMATCH '^d10[0-9]+$'",
+		},
+		{
+			name: "quoted pattern",
+			call: func() (string, error) { return WhereMatch("location", "it's") },
+			want: `location MATCH 'it\'s'`,
+		},
+		{
+			name: "empty pattern",
+			call: func() (string, error) { return WhereMatch("location", "") },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "invalid pattern",
+			call: func() (string, error) { return WhereMatch("location", "(beijing") },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "invalid column",
+			call: func() (string, error) { return WhereNotMatch("location; DROP TABLE t", "a") },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestCheckStringColumn(t *testing.T) {
+	d, _ := newMockDriver(t, Option{}, metersHandler(nil))
+	cases := []struct {
+		column string
+		code   gcode.Code
+	}{
+		{column: "location"},
+		{column: "voltage", code: gcode.CodeInvalidParameter},
+		{column: "site", code: gcode.CodeNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.column, func(t *testing.T) {
+			checkCode(t, d.CheckStringColumn(context.Background(), "meters", c.column), c.code)
+		})
+	}
+}
+
+func TestWhereIn(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "subquery",
+			call: func() (string, error) { return WhereIn("tbname", "SELECT tbname FROM alerts WHERE level > ?", 3) },
+			want: "tbname IN (SELECT tbname FROM alerts WHERE level > 3)",
+		},
+		{
+			name: "quoted argument",
+			call: func() (string, error) {
+				return WhereIn("location", "SELECT location FROM sites WHERE name = ? AND note <> '?'", "it's")
+			},
+			want: `location IN (SELECT location FROM sites WHERE name = 'it\'s' AND note <> '?')`,
+		},
+		{
+			name: "function projection",
+			call: func() (string, error) { return WhereIn("groupid", "SELECT DISTINCT(groupid) FROM meters") },
+			want: "groupid IN (SELECT DISTINCT(groupid) FROM meters)",
+		},
+		{
+			name: "multiple columns",
+			call: func() (string, error) { return WhereIn("tbname", "SELECT tbname, level FROM alerts") },
+			code: gcode.CodeNotSupported,
+		},
+		{
+			name: "all columns",
+			call: func() (string, error) { return WhereIn("tbname", "SELECT * FROM alerts") },
+			code: gcode.CodeNotSupported,
+		},
+		{
+			name: "not select",
+			call: func() (string, error) { return WhereIn("tbname", "SHOW TABLES") },
+			code: gcode.CodeNotSupported,
+		},
+		{
+			name: "no from",
+			call: func() (string, error) { return WhereIn("tbname", "SELECT 1") },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "missing arguments",
+			call: func() (string, error) { return WhereIn("tbname", "SELECT tbname FROM alerts WHERE level > ?") },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "extra arguments",
+			call: func() (string, error) { return WhereIn("tbname", "SELECT tbname FROM alerts", 3) },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "statement separator",
+			call: func() (string, error) { return WhereIn("tbname", "SELECT tbname FROM alerts; DROP TABLE meters") },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestWhereTimeWithin(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "hours",
+			call: func() (string, error) { return WhereTimeWithin("ts", time.Hour) },
+			want: "ts > NOW - 1h",
+		},
+		{
+			name: "minutes",
+			call: func() (string, error) { return WhereTimeWithin("ts", 90*time.Minute) },
+			want: "ts > NOW - 90m",
+		},
+		{
+			name: "days",
+			call: func() (string, error) { return WhereTimeWithin("ts", 48*time.Hour) },
+			want: "ts > NOW - 2d",
+		},
+		{
+			name: "milliseconds",
+			call: func() (string, error) { return WhereTimeWithin("ts", 1500*time.Millisecond) },
+			want: "ts > NOW - 1500a",
+		},
+		{
+			name: "quoted column",
+			call: func() (string, error) { return WhereTimeWithin("`ts`", time.Second) },
+			want: "`ts` > NOW - 1s",
+		},
+		{
+			name: "zero duration",
+			call: func() (string, error) { return WhereTimeWithin("ts", 0) },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "negative duration",
+			call: func() (string, error) { return WhereTimeWithin("ts", -time.Hour) },
+			code: gcode.CodeInvalidParameter,
+		},
+		{
+			name: "invalid column",
+			call: func() (string, error) { return WhereTimeWithin("ts; DROP TABLE meters", time.Hour) },
+			code: gcode.CodeInvalidParameter,
+		},
+	})
+}
+
+func TestGroupByJSON(t *testing.T) {
+	runFuncCases(t, []funcCase{
+		{
+			name: "single key",
+			call: func() (string, error) { return GroupByJSON("info", "region") },
+			want: "info->'region'",
+		},
+		{
+			name: "multiple keys",
+			call: func() (string, error) { return GroupByJSON("info", "region", "zone_id") },
+			want: "info->'region', info->'zone_id'",
+		},
+		{name: "no keys", call: func() (string, error) { return GroupByJSON("info") }, code: gcode.CodeInvalidParameter},
+		{name: "quoted key", call: func() (string, error) { return GroupByJSON("info", "re'gion") }, code: gcode.CodeInvalidParameter},
+		{name: "dotted key", call: func() (string, error) { return GroupByJSON("info", "a.b") }, code: gcode.CodeInvalidParameter},
+		{name: "invalid tag", call: func() (string, error) { return GroupByJSON("info)", "region") }, code: gcode.CodeInvalidParameter},
+	})
+}
+
+func TestGroupByJSONStatement(t *testing.T) {
+	d, server := newMockDriver(t, Option{}, metersHandler(nil))
+	group, err := GroupByJSON("info", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.Model("meters").Fields(group, "AVG(current)").Group(group).All(); err != nil {
+		t.Fatal(err)
+	}
+	sqls := server.Sqls()
+	if want := "SELECT info->'region',AVG(current) FROM `meters` GROUP BY info->'region'"; sqls[len(sqls)-1] != want {
+		t.Fatalf("got sql %q, want %q", sqls[len(sqls)-1], want)
+	}
+}